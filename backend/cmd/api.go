@@ -2,11 +2,24 @@ package main
 
 import (
 	"backend/database/services"
+	"backend/pkg/actuator"
+	"backend/pkg/alarm"
+	"backend/pkg/auth"
+	"backend/pkg/extract"
+	"backend/pkg/incident"
+	"backend/pkg/notify"
+	"backend/pkg/reporting"
+	"backend/pkg/rules"
+	"backend/pkg/siren"
+	"backend/pkg/trouble"
 	"backend/pkg/utils"
 	"encoding/json"
+	"errors"
 	"log"
+	"net"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 // PaginatedResponse represents a paginated API response
@@ -30,6 +43,30 @@ func getSensorReadings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	page, pageSize := parsePagination(r)
+
+	// Get paginated data from service
+	readings, totalCount, err := services.SensorReading.GetPaginated(page, pageSize)
+	if err != nil {
+		log.Printf("Error fetching sensor readings: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Internal server error"})
+		return
+	}
+
+	// Create response
+	response := newPaginatedResponse(readings, page, pageSize, totalCount)
+
+	// Send response
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Error encoding response: %v", err)
+	}
+}
+
+// parsePagination reads the page and page_size query parameters, falling
+// back to defaults for missing or invalid values
+func parsePagination(r *http.Request) (int, int) {
 	// Parse query parameters
 	pageStr := r.URL.Query().Get("page")
 	pageSizeStr := r.URL.Query().Get("page_size")
@@ -57,41 +94,167 @@ func getSensorReadings(w http.ResponseWriter, r *http.Request) {
 		pageSize = 1000
 	}
 
-	// Get paginated data from service
-	readings, totalCount, err := services.SensorReading.GetPaginated(page, pageSize)
-	if err != nil {
-		log.Printf("Error fetching sensor readings: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Internal server error"})
-		return
-	}
+	return page, pageSize
+}
 
-	// Calculate total pages
-	totalPages := int((totalCount + int64(pageSize) - 1) / int64(pageSize))
+// parseTimeRange reads the optional since and until query parameters as RFC 3339 times
+func parseTimeRange(r *http.Request) (since, until time.Time, err error) {
+	query := r.URL.Query()
+	if v := query.Get("since"); v != "" {
+		if since, err = time.Parse(time.RFC3339, v); err != nil {
+			return since, until, errors.New("invalid since, expected RFC 3339 time")
+		}
+	}
+	if v := query.Get("until"); v != "" {
+		if until, err = time.Parse(time.RFC3339, v); err != nil {
+			return since, until, errors.New("invalid until, expected RFC 3339 time")
+		}
+	}
+	return since, until, nil
+}
 
-	// Create response
-	response := PaginatedResponse{
-		Data:       readings,
+// newPaginatedResponse wraps one page of data with the pagination metadata
+func newPaginatedResponse(data interface{}, page, pageSize int, totalCount int64) PaginatedResponse {
+	return PaginatedResponse{
+		Data:       data,
 		Page:       page,
 		PageSize:   pageSize,
 		TotalCount: totalCount,
-		TotalPages: totalPages,
+		TotalPages: int((totalCount + int64(pageSize) - 1) / int64(pageSize)),
 	}
+}
 
-	// Send response
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
 		log.Printf("Error encoding response: %v", err)
 	}
 }
 
-// StartAPIServer starts the HTTP API server
-func StartAPIServer() {
+// writeError writes a JSON error response with the given status code
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// parseID parses a numeric path parameter
+func parseID(r *http.Request, name string) (uint, error) {
+	id, err := strconv.ParseUint(r.PathValue(name), 10, 0)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
+
+// clientIP returns the address a request came from, without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// APIDeps holds the subsystems the API handlers operate on
+type APIDeps struct {
+	System     *alarm.System
+	Ingestor   *Ingestor
+	Extractors *extract.Registry
+	Tokens     *auth.TokenManager
+	Troubles   *trouble.Manager
+	Incidents  *incident.Manager
+	Rules      *rules.Engine
+	Actuators  *actuator.Manager
+	Sirens     *siren.Controller
+	Notifier   *notify.Dispatcher
+	Reporter   *reporting.Reporter // nil unless SIA reporting is enabled
+}
+
+// StartAPIServer starts the HTTP API server. Every endpoint except login and
+// the health check requires a session token.
+func StartAPIServer(deps APIDeps) {
 	port := utils.GetEnv("API_PORT", "8081")
+	system, ingestor, extractors := deps.System, deps.Ingestor, deps.Extractors
+	lockout := auth.NewLockout(
+		utils.GetEnvInt("KEYPAD_MAX_ATTEMPTS", 5),
+		utils.GetEnvDuration("KEYPAD_LOCKOUT", 5*time.Minute),
+	)
+
+	pinChanges := auth.NewRateLimit(
+		utils.GetEnvInt("PIN_CHANGE_LIMIT", 5),
+		utils.GetEnvDuration("PIN_CHANGE_WINDOW", time.Hour),
+	)
 
 	mux := http.NewServeMux()
 	// Register routes
-	mux.HandleFunc("/api/sensor-readings", getSensorReadings)
+	mux.HandleFunc("/api/auth/login", login(deps.Tokens))
+	mux.HandleFunc("/api/auth/me", getCurrentUser)
+	mux.HandleFunc("/api/auth/password", changePassword)
+	mux.HandleFunc("/api/auth/pin", changePin(lockout, pinChanges))
+	mux.HandleFunc("/api/keypad/arm", keypadAction(system, lockout, false))
+	mux.HandleFunc("/api/keypad/disarm", keypadAction(system, lockout, true))
+	mux.HandleFunc("/api/users", authorize(auth.PermUsersManage, auth.PermUsersManage, usersHandler))
+	mux.HandleFunc("/api/users/{id}", authorize(auth.PermUsersManage, auth.PermUsersManage, userHandler))
+	mux.HandleFunc("/api/users/{id}/pin", authorize(auth.PermUsersManage, auth.PermUsersManage, userPinHandler))
+	mux.HandleFunc("/api/sensor-readings", authorize(auth.PermHistoryRead, auth.PermHistoryRead, getSensorReadings))
+	mux.HandleFunc("/api/alarm/state", authorize(auth.PermAlarmRead, auth.PermAlarmRead, getAlarmState(system)))
+	mux.HandleFunc("/api/alarm/arm", authorize(auth.PermAlarmArm, auth.PermAlarmArm, armAlarm(system)))
+	mux.HandleFunc("/api/alarm/disarm", authorize(auth.PermAlarmArm, auth.PermAlarmArm, disarmAlarm(system)))
+	mux.HandleFunc("/api/alarm/siren", authorize(auth.PermAlarmRead, auth.PermAlarmRead, getSirenStatus(system, deps.Sirens)))
+	mux.HandleFunc("/api/alarm/siren/silence", authorize(auth.PermAlarmArm, auth.PermAlarmArm, silenceSirens(system, deps.Sirens, deps.Incidents)))
+	mux.HandleFunc("/api/partitions", authorize(auth.PermAlarmRead, auth.PermConfigWrite, partitionsHandler(system)))
+	mux.HandleFunc("/api/partitions/{id}", authorize(auth.PermAlarmRead, auth.PermConfigWrite, partitionHandler(system)))
+	mux.HandleFunc("/api/partitions/{id}/state", authorize(auth.PermAlarmRead, auth.PermAlarmRead, getAlarmState(system)))
+	mux.HandleFunc("/api/partitions/{id}/arm", authorize(auth.PermAlarmArm, auth.PermAlarmArm, armAlarm(system)))
+	mux.HandleFunc("/api/partitions/{id}/disarm", authorize(auth.PermAlarmArm, auth.PermAlarmArm, disarmAlarm(system)))
+	mux.HandleFunc("/api/partitions/{id}/siren", authorize(auth.PermAlarmRead, auth.PermAlarmRead, getSirenStatus(system, deps.Sirens)))
+	mux.HandleFunc("/api/partitions/{id}/siren/silence", authorize(auth.PermAlarmArm, auth.PermAlarmArm, silenceSirens(system, deps.Sirens, deps.Incidents)))
+	mux.HandleFunc("/api/zones", authorize(auth.PermSensorsRead, auth.PermConfigWrite, zonesHandler(system)))
+	mux.HandleFunc("/api/zones/{id}", authorize(auth.PermSensorsRead, auth.PermConfigWrite, zoneHandler(system)))
+	mux.HandleFunc("/api/sensors", authorize(auth.PermSensorsRead, auth.PermConfigWrite, sensorsHandler))
+	mux.HandleFunc("/api/sensors/{sensor_id}", authorize(auth.PermSensorsRead, auth.PermConfigWrite, sensorHandler))
+	mux.HandleFunc("/api/sensors/{sensor_id}/readings", authorize(auth.PermHistoryRead, auth.PermHistoryRead, getSensorReadingsBySensor))
+	mux.HandleFunc("/api/sensors/{sensor_id}/approve", authorize(auth.PermConfigWrite, auth.PermConfigWrite, sensorStatusAction("approve")))
+	mux.HandleFunc("/api/sensors/{sensor_id}/reject", authorize(auth.PermConfigWrite, auth.PermConfigWrite, sensorStatusAction("reject")))
+	mux.HandleFunc("/api/sensors/{sensor_id}/block", authorize(auth.PermConfigWrite, auth.PermConfigWrite, sensorStatusAction("block")))
+	mux.HandleFunc("/api/sensors/{sensor_id}/delays", authorize(auth.PermConfigWrite, auth.PermConfigWrite, updateSensorDelays))
+	mux.HandleFunc("/api/sensors/{sensor_id}/zone", authorize(auth.PermConfigWrite, auth.PermConfigWrite, updateSensorZone))
+	mux.HandleFunc("/api/sensors/{sensor_id}/metrics", authorize(auth.PermHistoryRead, auth.PermHistoryRead, getSensorMetrics))
+	mux.HandleFunc("/api/sensors/{sensor_id}/series", authorize(auth.PermHistoryRead, auth.PermHistoryRead, getSensorSeries))
+	mux.HandleFunc("/api/value-extractions", authorize(auth.PermSensorsRead, auth.PermConfigWrite, valueExtractionsHandler(extractors)))
+	mux.HandleFunc("/api/value-extractions/{id}", authorize(auth.PermSensorsRead, auth.PermConfigWrite, valueExtractionHandler(extractors)))
+	mux.HandleFunc("/api/actuators", authorize(auth.PermSensorsRead, auth.PermConfigWrite, actuatorsHandler))
+	mux.HandleFunc("/api/actuators/{actuator_id}", authorize(auth.PermSensorsRead, auth.PermConfigWrite, actuatorHandler))
+	mux.HandleFunc("/api/actuators/{actuator_id}/commands", authorize(auth.PermSensorsRead, auth.PermAlarmArm, actuatorCommandsHandler(deps.Actuators)))
+	mux.HandleFunc("/api/actuators/{actuator_id}/commands/{command_id}", authorize(auth.PermSensorsRead, auth.PermSensorsRead, getActuatorCommand))
+	mux.HandleFunc("/api/rules", authorize(auth.PermSensorsRead, auth.PermConfigWrite, rulesHandler(system, deps.Rules)))
+	mux.HandleFunc("/api/rules/{id}", authorize(auth.PermSensorsRead, auth.PermConfigWrite, ruleHandler(system, deps.Rules)))
+	mux.HandleFunc("/api/troubles", authorize(auth.PermSensorsRead, auth.PermSensorsRead, getTroubles))
+	mux.HandleFunc("/api/troubles/{id}/acknowledge", authorize(auth.PermAlarmArm, auth.PermAlarmArm, acknowledgeTrouble(deps.Troubles)))
+	mux.HandleFunc("/api/incidents", authorize(auth.PermAlarmRead, auth.PermAlarmRead, getIncidents))
+	mux.HandleFunc("/api/incidents/{id}", authorize(auth.PermAlarmRead, auth.PermAlarmRead, getIncident))
+	mux.HandleFunc("/api/incidents/{id}/acknowledge", authorize(auth.PermAlarmArm, auth.PermAlarmArm, incidentAction(deps.Incidents, "acknowledge")))
+	mux.HandleFunc("/api/incidents/{id}/resolve", authorize(auth.PermAlarmArm, auth.PermAlarmArm, incidentAction(deps.Incidents, "resolve")))
+	mux.HandleFunc("/api/incidents/{id}/false-alarm", authorize(auth.PermAlarmArm, auth.PermAlarmArm, incidentAction(deps.Incidents, "false_alarm")))
+	mux.HandleFunc("/api/incidents/{id}/assignee", authorize(auth.PermAlarmArm, auth.PermAlarmArm, assignIncident(deps.Incidents)))
+	mux.HandleFunc("/api/incidents/{id}/notes", authorize(auth.PermAlarmArm, auth.PermAlarmArm, addIncidentNote(deps.Incidents)))
+	mux.HandleFunc("/api/escalation-policies", authorize(auth.PermAlarmRead, auth.PermConfigWrite, escalationPoliciesHandler(system, deps.Notifier)))
+	mux.HandleFunc("/api/escalation-policies/{id}", authorize(auth.PermAlarmRead, auth.PermConfigWrite, escalationPolicyHandler(system, deps.Notifier)))
+	mux.HandleFunc("/api/notifications/channels", authorize(auth.PermAlarmRead, auth.PermAlarmRead, getNotificationChannels(deps.Notifier)))
+	mux.HandleFunc("/api/notifications/preferences", authorize(auth.PermAlarmRead, auth.PermAlarmRead, notificationPreferencesHandler(deps.Notifier)))
+	mux.HandleFunc("/api/notifications/preferences/{id}", authorize(auth.PermAlarmRead, auth.PermAlarmRead, notificationPreferenceHandler(deps.Notifier)))
+	mux.HandleFunc("/api/notifications/test", authorize(auth.PermAlarmRead, auth.PermAlarmRead, sendTestNotification(deps.Notifier)))
+	mux.HandleFunc("/api/notifications/deliveries", authorize(auth.PermSystemManage, auth.PermSystemManage, getNotificationDeliveries))
+	mux.HandleFunc("/api/notifications/templates", authorize(auth.PermSensorsRead, auth.PermConfigWrite, getNotificationTemplates))
+	mux.HandleFunc("/api/notifications/templates/{event}", authorize(auth.PermConfigWrite, auth.PermConfigWrite, notificationTemplateHandler))
+	mux.HandleFunc("/api/alarm-reports", authorize(auth.PermSystemManage, auth.PermSystemManage, getAlarmReports))
+	mux.HandleFunc("/api/alarm-reports/test", authorize(auth.PermSystemManage, auth.PermSystemManage, sendTestReport(deps.Reporter)))
+	mux.HandleFunc("/api/audit", authorize(auth.PermAuditRead, auth.PermAuditRead, getAuditLog))
+	mux.HandleFunc("/api/audit/verify", authorize(auth.PermAuditRead, auth.PermAuditRead, verifyAuditLog))
+	mux.HandleFunc("/api/dead-letters", authorize(auth.PermSystemManage, auth.PermSystemManage, getDeadLetters))
+	mux.HandleFunc("/api/dead-letters/{id}", authorize(auth.PermSystemManage, auth.PermSystemManage, deadLetterHandler))
+	mux.HandleFunc("/api/dead-letters/reprocess", authorize(auth.PermSystemManage, auth.PermSystemManage, reprocessDeadLetters(ingestor)))
 
 	// Health check endpoint
 	mux.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {
@@ -102,12 +265,78 @@ func StartAPIServer() {
 
 	log.Printf("API server starting on port %s", port)
 	log.Println("Available endpoints:")
+	log.Println("  POST /api/auth/login {\"username\": \"admin\", \"password\": \"...\"}")
+	log.Println("  GET /api/auth/me")
+	log.Println("  POST /api/auth/password {\"current_password\": \"...\", \"new_password\": \"...\"}")
+	log.Println("  PUT /api/auth/pin {\"password\": \"...\", \"pin\": \"1234\", \"duress_pin\": \"9876\"}")
+	log.Println("  POST /api/keypad/arm {\"pin\": \"1234\", \"mode\": \"armed_away\", \"partition_id\": 1}")
+	log.Println("  POST /api/keypad/disarm {\"pin\": \"1234\"}")
+	log.Println("  GET|POST /api/users")
+	log.Println("  GET|PUT|DELETE /api/users/{id}")
+	log.Println("  PUT /api/users/{id}/pin {\"pin\": \"1234\", \"duress_pin\": \"9876\"}")
 	log.Println("  GET /api/sensor-readings?page=1&page_size=100")
+	log.Println("  GET /api/alarm/state")
+	log.Println("  POST /api/alarm/arm {\"mode\": \"armed_home|armed_away\", \"force\": false}")
+	log.Println("  POST /api/alarm/disarm")
+	log.Println("  GET /api/alarm/siren")
+	log.Println("  POST /api/alarm/siren/silence")
+	log.Println("  GET|POST /api/partitions")
+	log.Println("  GET|PUT|DELETE /api/partitions/{id}")
+	log.Println("  GET /api/partitions/{id}/state")
+	log.Println("  POST /api/partitions/{id}/arm")
+	log.Println("  POST /api/partitions/{id}/disarm")
+	log.Println("  GET /api/partitions/{id}/siren")
+	log.Println("  POST /api/partitions/{id}/siren/silence")
+	log.Println("  GET|POST /api/zones?partition_id=1")
+	log.Println("  GET|PUT|DELETE /api/zones/{id}")
+	log.Println("  GET|POST /api/sensors?type=&status=pending&location=&zone_id=&online=false&page=1&page_size=100")
+	log.Println("  GET|PUT|DELETE /api/sensors/{sensor_id}")
+	log.Println("  GET /api/sensors/{sensor_id}/readings?page=1&page_size=100")
+	log.Println("  POST /api/sensors/{sensor_id}/approve|reject|block")
+	log.Println("  PUT /api/sensors/{sensor_id}/delays {\"entry_delay\": 30, \"exit_delay\": 60}")
+	log.Println("  PUT /api/sensors/{sensor_id}/zone {\"zone_id\": 1}")
+	log.Println("  GET /api/sensors/{sensor_id}/metrics")
+	log.Println("  GET /api/sensors/{sensor_id}/series?metric=temperature&since=&until=&limit=1000")
+	log.Println("  GET|POST /api/value-extractions")
+	log.Println("  GET|PUT|DELETE /api/value-extractions/{id}")
+	log.Println("  GET|POST /api/actuators")
+	log.Println("  GET|PUT|DELETE /api/actuators/{actuator_id}")
+	log.Println("  GET /api/actuators/{actuator_id}/commands?status=timed_out&page=1&page_size=100")
+	log.Println("  POST /api/actuators/{actuator_id}/commands {\"state\": \"on\"}")
+	log.Println("  GET /api/actuators/{actuator_id}/commands/{command_id}")
+	log.Println("  GET|POST /api/rules")
+	log.Println("  GET|PUT|DELETE /api/rules/{id}")
+	log.Println("  GET /api/troubles?active=true&sensor_id=&type=tamper&since=&until=&page=1&page_size=100")
+	log.Println("  POST /api/troubles/{id}/acknowledge")
+	log.Println("  GET /api/incidents?state=open&partition_id=&assignee=&since=&until=&page=1&page_size=100")
+	log.Println("  GET /api/incidents/{id}")
+	log.Println("  POST /api/incidents/{id}/acknowledge|resolve|false-alarm {\"note\": \"...\"}")
+	log.Println("  PUT /api/incidents/{id}/assignee {\"assignee\": \"alice\"}")
+	log.Println("  POST /api/incidents/{id}/notes {\"note\": \"...\"}")
+	log.Println("  GET|POST /api/escalation-policies {\"name\": \"Night\", \"steps\": [{\"target\": \"user\", \"user_id\": 2}, {\"delay\": 300, \"target\": \"contact\", \"name\": \"Neighbour\", \"channel\": \"ntfy\", \"address\": \"...\"}]}")
+	log.Println("  GET|PUT|DELETE /api/escalation-policies/{id}")
+	log.Println("  GET /api/notifications/channels")
+	log.Println("  GET|POST /api/notifications/preferences {\"channel\": \"ntfy\", \"address\": \"my-topic\", \"events\": [\"alarm\"]}")
+	log.Println("  GET|PUT|DELETE /api/notifications/preferences/{id}")
+	log.Println("  POST /api/notifications/test {\"preference_id\": 1}")
+	log.Println("  GET /api/notifications/deliveries?user_id=&event=alarm&channel=&status=failed&since=&until=&page=1&page_size=100")
+	log.Println("  GET /api/notifications/templates")
+	log.Println("  PUT|DELETE /api/notifications/templates/{event} {\"title\": \"...\", \"body\": \"...\"}")
+	log.Println("  GET /api/alarm-reports?status=pending&code=&since=&until=&page=1&page_size=100")
+	log.Println("  POST /api/alarm-reports/test")
+	log.Println("  GET /api/audit?actor=&action=arm&resource=alarm&since=&until=&page=1&page_size=100")
+	log.Println("  GET /api/audit/verify")
+	log.Println("  GET /api/dead-letters?reason=invalid_payload&topic=sensor/&status=pending&since=&until=")
+	log.Println("  GET|DELETE /api/dead-letters/{id}")
+	log.Println("  POST /api/dead-letters/reprocess {\"ids\": [1, 2]}")
 	log.Println("  GET /api/health")
 
+	// Keypads authenticate with a PIN instead of a session token
+	handler := deps.Tokens.Middleware(mux, "/api/health", "/api/auth/login", "/api/keypad/arm", "/api/keypad/disarm")
+
 	// Start server in goroutine
 	go func() {
-		if err := http.ListenAndServe(":"+port, mux); err != nil {
+		if err := http.ListenAndServe(":"+port, handler); err != nil {
 			log.Printf("API server error: %v", err)
 		}
 	}()
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/actuator"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ActuatorRequest is the request body for creating and updating actuators.
+// The actuator_id is taken from the path on updates.
+type ActuatorRequest struct {
+	ActuatorID  string `json:"actuator_id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Location    string `json:"location"`
+	ZoneID      *uint  `json:"zone_id"`
+	QoS         *byte  `json:"qos"` // Defaults to 1
+}
+
+func (req ActuatorRequest) apply(a *models.Actuator) {
+	a.Name = req.Name
+	a.Type = req.Type
+	a.Description = req.Description
+	a.Location = req.Location
+	a.ZoneID = req.ZoneID
+	a.QoS = 1
+	if req.QoS != nil {
+		a.QoS = *req.QoS
+	}
+}
+
+// actuatorsHandler handles GET and POST /api/actuators
+func actuatorsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		filter := services.ActuatorFilter{
+			Type:     query.Get("type"),
+			Location: query.Get("location"),
+		}
+		if z := query.Get("zone_id"); z != "" {
+			zoneID, err := strconv.ParseUint(z, 10, 0)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "Invalid zone ID")
+				return
+			}
+			filter.ZoneID = uint(zoneID)
+		}
+
+		page, pageSize := parsePagination(r)
+		actuators, totalCount, err := services.Actuator.GetPaginated(filter, page, pageSize)
+		if err != nil {
+			log.Printf("Error fetching actuators: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		writeJSON(w, http.StatusOK, newPaginatedResponse(actuators, page, pageSize, totalCount))
+
+	case http.MethodPost:
+		req, ok := decodeActuatorRequest(w, r)
+		if !ok {
+			return
+		}
+		// Actuator IDs end up in MQTT topics just like sensor IDs
+		if !sensorIDPattern.MatchString(req.ActuatorID) {
+			writeError(w, http.StatusBadRequest, "actuator_id must be 1-50 letters, digits, '_' or '-'")
+			return
+		}
+
+		a := models.Actuator{ActuatorID: req.ActuatorID}
+		req.apply(&a)
+		if err := services.Actuator.Create(&a); err != nil {
+			if errors.Is(err, services.ErrDuplicate) {
+				writeError(w, http.StatusConflict, "An actuator with this actuator_id already exists")
+				return
+			}
+			log.Printf("Error creating actuator: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		audit(r, "create", "actuator", a.ActuatorID, nil, a)
+		writeJSON(w, http.StatusCreated, a)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// actuatorHandler handles GET, PUT and DELETE /api/actuators/{actuator_id}
+func actuatorHandler(w http.ResponseWriter, r *http.Request) {
+	actuatorID := r.PathValue("actuator_id")
+
+	existing, err := services.Actuator.GetByActuatorID(actuatorID)
+	if err != nil {
+		log.Printf("Error fetching actuator: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if existing == nil {
+		writeError(w, http.StatusNotFound, "Actuator not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, existing)
+
+	case http.MethodPut:
+		req, ok := decodeActuatorRequest(w, r)
+		if !ok {
+			return
+		}
+		if req.ActuatorID != "" && req.ActuatorID != actuatorID {
+			writeError(w, http.StatusBadRequest, "actuator_id can't be changed")
+			return
+		}
+
+		before := *existing
+		req.apply(existing)
+		if err := services.Actuator.Update(existing); err != nil {
+			log.Printf("Error updating actuator: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		audit(r, "update", "actuator", actuatorID, before, existing)
+		writeJSON(w, http.StatusOK, existing)
+
+	case http.MethodDelete:
+		if err := services.Actuator.Delete(actuatorID); err != nil {
+			writeServiceError(w, err, "Actuator")
+			return
+		}
+		audit(r, "delete", "actuator", actuatorID, existing, nil)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// actuatorCommandsHandler handles GET and POST /api/actuators/{actuator_id}/commands.
+// The body of a POST is the command published to the device, e.g. {"state": "on"}.
+func actuatorCommandsHandler(actuators *actuator.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actuatorID := r.PathValue("actuator_id")
+
+		switch r.Method {
+		case http.MethodGet:
+			page, pageSize := parsePagination(r)
+			filter := services.ActuatorCommandFilter{ActuatorID: actuatorID, Status: r.URL.Query().Get("status")}
+			commands, totalCount, err := services.ActuatorCommand.GetPaginated(filter, page, pageSize)
+			if err != nil {
+				log.Printf("Error fetching commands: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			writeJSON(w, http.StatusOK, newPaginatedResponse(commands, page, pageSize, totalCount))
+
+		case http.MethodPost:
+			target, err := services.Actuator.GetByActuatorID(actuatorID)
+			if err != nil {
+				log.Printf("Error fetching actuator: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			if target == nil {
+				writeError(w, http.StatusNotFound, "Actuator not found")
+				return
+			}
+
+			var command map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&command); err != nil || len(command) == 0 {
+				writeError(w, http.StatusBadRequest, "Request body must be a non-empty JSON object")
+				return
+			}
+
+			sent, err := actuators.Send(target, command, actor(r))
+			if err != nil {
+				log.Printf("Error sending command: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			audit(r, "command", "actuator", actuatorID, nil, sent)
+			writeJSON(w, http.StatusAccepted, sent)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// getActuatorCommand handles GET /api/actuators/{actuator_id}/commands/{command_id}
+func getActuatorCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	command, err := services.ActuatorCommand.GetByCommandID(r.PathValue("command_id"))
+	if err != nil {
+		log.Printf("Error fetching command: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if command == nil || command.ActuatorID != r.PathValue("actuator_id") {
+		writeError(w, http.StatusNotFound, "Command not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, command)
+}
+
+func decodeActuatorRequest(w http.ResponseWriter, r *http.Request) (ActuatorRequest, bool) {
+	var req ActuatorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return req, false
+	}
+
+	req.ActuatorID = strings.TrimSpace(req.ActuatorID)
+	req.Name = strings.TrimSpace(req.Name)
+	req.Type = strings.ToLower(strings.TrimSpace(req.Type))
+	if req.Name == "" || req.Type == "" {
+		writeError(w, http.StatusBadRequest, "name and type are required")
+		return req, false
+	}
+	if req.QoS != nil && *req.QoS > 2 {
+		writeError(w, http.StatusBadRequest, "qos must be 0, 1 or 2")
+		return req, false
+	}
+
+	if req.ZoneID != nil {
+		zone, err := services.Zone.Get(*req.ZoneID)
+		if err != nil {
+			log.Printf("Error fetching zone: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return req, false
+		}
+		if zone == nil {
+			writeError(w, http.StatusBadRequest, "Zone not found")
+			return req, false
+		}
+	}
+
+	return req, true
+}
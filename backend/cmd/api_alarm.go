@@ -0,0 +1,176 @@
+package main
+
+import (
+	"backend/database/services"
+	"backend/pkg/alarm"
+	"backend/pkg/incident"
+	"backend/pkg/siren"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// ArmRequest is the request body for the arm endpoints
+type ArmRequest struct {
+	Mode  string `json:"mode"`
+	Force bool   `json:"force"` // Arm even though troubles are active
+}
+
+// panelForRequest returns the panel of the {id} partition in the request
+// path, or the default partition's panel for the /api/alarm/ routes
+func panelForRequest(system *alarm.System, w http.ResponseWriter, r *http.Request) (*alarm.Panel, bool) {
+	partitionID := system.DefaultPartitionID()
+	if r.PathValue("id") != "" {
+		id, err := parseID(r, "id")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid partition ID")
+			return nil, false
+		}
+		partitionID = id
+	}
+
+	panel, err := system.Panel(partitionID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Partition not found")
+		return nil, false
+	}
+	return panel, true
+}
+
+// getAlarmState handles GET /api/alarm/state and GET /api/partitions/{id}/state
+func getAlarmState(system *alarm.System) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		panel, ok := panelForRequest(system, w, r)
+		if !ok {
+			return
+		}
+
+		writeJSON(w, http.StatusOK, panel.Status())
+	}
+}
+
+// armAlarm handles POST /api/alarm/arm and POST /api/partitions/{id}/arm
+func armAlarm(system *alarm.System) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		panel, ok := panelForRequest(system, w, r)
+		if !ok {
+			return
+		}
+
+		var req ArmRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		before := panel.Status()
+		if err := panel.Arm(alarm.State(req.Mode), actor(r), req.Force); err != nil {
+			writeAlarmError(w, err)
+			return
+		}
+		audit(r, "arm", "alarm", fmt.Sprint(panel.PartitionID()), before, panel.Status())
+
+		writeJSON(w, http.StatusOK, panel.Status())
+	}
+}
+
+// disarmAlarm handles POST /api/alarm/disarm and POST /api/partitions/{id}/disarm
+func disarmAlarm(system *alarm.System) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		panel, ok := panelForRequest(system, w, r)
+		if !ok {
+			return
+		}
+
+		before := panel.Status()
+		if err := panel.Disarm(actor(r)); err != nil {
+			writeAlarmError(w, err)
+			return
+		}
+		audit(r, "disarm", "alarm", fmt.Sprint(panel.PartitionID()), before, panel.Status())
+
+		writeJSON(w, http.StatusOK, panel.Status())
+	}
+}
+
+// writeAlarmError maps panel errors to HTTP status codes
+func writeAlarmError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, alarm.ErrInvalidMode):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, alarm.ErrInvalidTransition), errors.Is(err, alarm.ErrArmBlocked):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("Error changing alarm state: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// getSirenStatus handles GET /api/alarm/siren and GET /api/partitions/{id}/siren
+func getSirenStatus(system *alarm.System, sirens *siren.Controller) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		panel, ok := panelForRequest(system, w, r)
+		if !ok {
+			return
+		}
+
+		writeJSON(w, http.StatusOK, sirens.Status(panel.PartitionID()))
+	}
+}
+
+// silenceSirens handles POST /api/alarm/siren/silence and POST
+// /api/partitions/{id}/siren/silence. The alarm stays triggered and the
+// incident open, the silence is added to the incident's timeline.
+func silenceSirens(system *alarm.System, sirens *siren.Controller, incidents *incident.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		panel, ok := panelForRequest(system, w, r)
+		if !ok {
+			return
+		}
+
+		status, err := sirens.Silence(panel.PartitionID(), actor(r))
+		if errors.Is(err, siren.ErrNotSounding) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		audit(r, "silence", "siren", fmt.Sprint(panel.PartitionID()), nil, status)
+
+		open, err := services.Incident.GetOpen(panel.PartitionID())
+		if err != nil {
+			log.Printf("Error fetching open incident: %v", err)
+		} else if open != nil {
+			if _, err := incidents.AddNote(open.ID, actor(r), "Sirens silenced"); err != nil {
+				log.Printf("Error adding silence to incident %d: %v", open.ID, err)
+			}
+		}
+
+		writeJSON(w, http.StatusOK, status)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"backend/database/services"
+	"backend/pkg/reporting"
+	"log"
+	"net/http"
+)
+
+// getAlarmReports handles GET /api/alarm-reports
+func getAlarmReports(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	page, pageSize := parsePagination(r)
+	since, until, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	filter := services.AlarmReportFilter{
+		Status: query.Get("status"),
+		Code:   query.Get("code"),
+		Since:  since,
+		Until:  until,
+	}
+	reports, totalCount, err := services.AlarmReport.GetPaginated(filter, page, pageSize)
+	if err != nil {
+		log.Printf("Error fetching alarm reports: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	writeJSON(w, http.StatusOK, newPaginatedResponse(reports, page, pageSize, totalCount))
+}
+
+// sendTestReport handles POST /api/alarm-reports/test. The test report is
+// queued like any other, its outcome shows up in GET /api/alarm-reports.
+func sendTestReport(reporter *reporting.Reporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		if reporter == nil {
+			writeError(w, http.StatusServiceUnavailable, "SIA reporting is not enabled")
+			return
+		}
+
+		reporter.Test(actor(r))
+		audit(r, "test", "alarm_report", "", nil, nil)
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "queued"})
+	}
+}
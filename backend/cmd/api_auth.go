@@ -0,0 +1,224 @@
+package main
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/auth"
+	"backend/pkg/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// dummyHash is compared against when a login names an unknown user, so
+// response times don't reveal which usernames exist
+var dummyHash, _ = auth.HashPassword("not-a-real-password")
+
+// LoginRequest is the request body for POST /api/auth/login
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginResponse is returned by a successful login
+type LoginResponse struct {
+	Token     string       `json:"token"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	User      *models.User `json:"user"`
+}
+
+// ChangePasswordRequest is the request body for POST /api/auth/password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// login handles POST /api/auth/login
+func login(tokens *auth.TokenManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		var req LoginRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		user, err := services.User.GetByUsername(req.Username)
+		if err != nil {
+			log.Printf("Error fetching user: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		hash := dummyHash
+		if user != nil {
+			hash = user.Password
+		}
+		if !auth.CheckPassword(hash, req.Password) || user == nil {
+			log.Printf("Failed login for %q from %s", req.Username, r.RemoteAddr)
+			auditAs(req.Username, clientIP(r), "login_failed", "auth", "", nil, nil)
+			writeError(w, http.StatusUnauthorized, "Invalid username or password")
+			return
+		}
+
+		if !auth.InAccessWindow(user, time.Now()) {
+			log.Printf("Login for %q outside of access window", req.Username)
+			auditAs(req.Username, clientIP(r), "login_failed", "auth", fmt.Sprint(user.ID), nil, map[string]string{"reason": "outside access window"})
+			writeError(w, http.StatusForbidden, "Access is not allowed at this time")
+			return
+		}
+
+		token, expires, err := tokens.Issue(user.ID, user.Username)
+		if err != nil {
+			log.Printf("Error issuing token: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		log.Printf("User %s logged in from %s", user.Username, r.RemoteAddr)
+		auditAs(user.Username, clientIP(r), "login", "auth", fmt.Sprint(user.ID), nil, nil)
+		writeJSON(w, http.StatusOK, LoginResponse{Token: token, ExpiresAt: expires, User: user})
+	}
+}
+
+// getCurrentUser handles GET /api/auth/me
+func getCurrentUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+// changePassword handles POST /api/auth/password
+func changePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, ok := currentUser(w, r)
+	if !ok {
+		return
+	}
+	if !auth.CheckPassword(user.Password, req.CurrentPassword) {
+		writeError(w, http.StatusForbidden, "Current password is incorrect")
+		return
+	}
+
+	hash, err := auth.HashPassword(req.NewPassword)
+	if errors.Is(err, auth.ErrPasswordTooShort) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	if err := services.User.UpdatePassword(user.ID, hash); err != nil {
+		writeServiceError(w, err, "User")
+		return
+	}
+	audit(r, "password_change", "user", fmt.Sprint(user.ID), nil, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// currentUser loads the authenticated user of a request
+func currentUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	claims := auth.FromContext(r.Context())
+	if claims == nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	user, err := services.User.Get(claims.UserID)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return nil, false
+	}
+	if user == nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+	return user, true
+}
+
+// authorize wraps a handler so it only runs for users whose role grants the
+// permission. GET requests need read, every other method needs write.
+func authorize(read, write auth.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		perm := write
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			perm = read
+		}
+
+		user, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		switch err := auth.Authorize(user, perm); {
+		case errors.Is(err, auth.ErrOutsideWindow):
+			writeError(w, http.StatusForbidden, "Access is not allowed at this time")
+		case err != nil:
+			writeError(w, http.StatusForbidden, "Forbidden")
+		default:
+			next(w, r)
+		}
+	}
+}
+
+// actor names who made a request, for logging state changes
+func actor(r *http.Request) string {
+	if claims := auth.FromContext(r.Context()); claims != nil {
+		return claims.Username
+	}
+	return r.RemoteAddr
+}
+
+// bootstrapAdmin creates the first user from ADMIN_USERNAME and
+// ADMIN_PASSWORD when there are no users yet
+func bootstrapAdmin() error {
+	count, err := services.User.Count()
+	if err != nil || count > 0 {
+		return err
+	}
+
+	username := utils.GetEnv("ADMIN_USERNAME", "admin")
+	password := utils.GetEnv("ADMIN_PASSWORD", "")
+	if password == "" {
+		log.Println("Warning: no users exist and ADMIN_PASSWORD is not set, nobody can log in")
+		return nil
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := services.User.Create(&models.User{Username: username, Password: hash, Role: string(auth.RoleAdmin)}); err != nil {
+		return err
+	}
+	log.Printf("Created initial user %s", username)
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"backend/database/services"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// maxReprocessBatch limits how many dead letters can be re-processed in one request
+const maxReprocessBatch = 500
+
+// ReprocessRequest is the request body for POST /api/dead-letters/reprocess
+type ReprocessRequest struct {
+	IDs []uint `json:"ids"`
+}
+
+// ReprocessResult is the outcome of re-processing a single dead letter
+type ReprocessResult struct {
+	ID      uint   `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// getDeadLetters handles GET /api/dead-letters with pagination and filters
+func getDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := services.DeadLetterFilter{
+		Topic:  query.Get("topic"),
+		Reason: query.Get("reason"),
+		Status: query.Get("status"),
+	}
+	since, until, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Since, filter.Until = since, until
+
+	page, pageSize := parsePagination(r)
+	letters, totalCount, err := services.DeadLetter.GetPaginated(filter, page, pageSize)
+	if err != nil {
+		log.Printf("Error fetching dead letters: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newPaginatedResponse(letters, page, pageSize, totalCount))
+}
+
+// deadLetterHandler handles GET and DELETE /api/dead-letters/{id}
+func deadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid dead letter ID")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		letter, err := services.DeadLetter.Get(id)
+		if err != nil {
+			log.Printf("Error fetching dead letter: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		if letter == nil {
+			writeError(w, http.StatusNotFound, "Dead letter not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, letter)
+
+	case http.MethodDelete:
+		if err := services.DeadLetter.Delete(id); err != nil {
+			writeServiceError(w, err, "Dead letter")
+			return
+		}
+		audit(r, "delete", "dead_letter", fmt.Sprint(id), nil, nil)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// reprocessDeadLetters handles POST /api/dead-letters/reprocess
+func reprocessDeadLetters(ingestor *Ingestor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		var req ReprocessRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if len(req.IDs) == 0 || len(req.IDs) > maxReprocessBatch {
+			writeError(w, http.StatusBadRequest, "Between 1 and 500 ids are required")
+			return
+		}
+
+		results := make([]ReprocessResult, 0, len(req.IDs))
+		for _, id := range req.IDs {
+			result := ReprocessResult{ID: id}
+
+			letter, err := services.DeadLetter.Get(id)
+			switch {
+			case err != nil:
+				log.Printf("Error fetching dead letter: %v", err)
+				result.Error = "Internal server error"
+			case letter == nil:
+				result.Error = "Dead letter not found"
+			default:
+				if err := ingestor.Reprocess(letter); err != nil {
+					result.Error = err.Error()
+				} else {
+					result.Success = true
+				}
+				audit(r, "reprocess", "dead_letter", fmt.Sprint(id), nil, result)
+			}
+
+			results = append(results, result)
+		}
+
+		writeJSON(w, http.StatusOK, results)
+	}
+}
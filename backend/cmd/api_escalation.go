@@ -0,0 +1,227 @@
+package main
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/alarm"
+	"backend/pkg/notify"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// EscalationPolicyRequest is the request body for creating and updating
+// escalation policies. Updates replace all steps.
+type EscalationPolicyRequest struct {
+	Name        string                  `json:"name"`
+	PartitionID *uint                   `json:"partition_id"` // Omit for partitions without their own policy
+	Enabled     *bool                   `json:"enabled"`      // Defaults to true
+	Steps       []EscalationStepRequest `json:"steps"`
+}
+
+// EscalationStepRequest is one step of an EscalationPolicyRequest, either a
+// user_id or a name, channel and address
+type EscalationStepRequest struct {
+	Delay   int    `json:"delay"` // Seconds after the previous step
+	Target  string `json:"target"`
+	UserID  *uint  `json:"user_id"`
+	Name    string `json:"name"`
+	Channel string `json:"channel"`
+	Address string `json:"address"`
+}
+
+func (req EscalationPolicyRequest) apply(policy *models.EscalationPolicy) {
+	policy.Name = req.Name
+	policy.PartitionID = req.PartitionID
+	policy.Enabled = req.Enabled == nil || *req.Enabled
+	policy.Steps = make([]models.EscalationStep, len(req.Steps))
+	for i, step := range req.Steps {
+		policy.Steps[i] = models.EscalationStep{
+			Position: i,
+			Delay:    step.Delay,
+			Target:   step.Target,
+			UserID:   step.UserID,
+			Name:     step.Name,
+			Channel:  step.Channel,
+			Address:  step.Address,
+		}
+	}
+}
+
+// escalationPoliciesHandler handles GET and POST /api/escalation-policies
+func escalationPoliciesHandler(system *alarm.System, notifier *notify.Dispatcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			policies, err := services.Escalation.List()
+			if err != nil {
+				log.Printf("Error fetching escalation policies: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			writeJSON(w, http.StatusOK, policies)
+
+		case http.MethodPost:
+			req, ok := decodeEscalationPolicyRequest(w, r, system, notifier)
+			if !ok {
+				return
+			}
+
+			var policy models.EscalationPolicy
+			req.apply(&policy)
+			if err := services.Escalation.Create(&policy); err != nil {
+				writeEscalationError(w, err)
+				return
+			}
+			audit(r, "create", "escalation_policy", fmt.Sprint(policy.ID), nil, policy)
+			writeJSON(w, http.StatusCreated, policy)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// escalationPolicyHandler handles GET, PUT and DELETE /api/escalation-policies/{id}
+func escalationPolicyHandler(system *alarm.System, notifier *notify.Dispatcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseID(r, "id")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid escalation policy ID")
+			return
+		}
+
+		existing, err := services.Escalation.Get(id)
+		if err != nil {
+			log.Printf("Error fetching escalation policy: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		if existing == nil {
+			writeError(w, http.StatusNotFound, "Escalation policy not found")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, existing)
+
+		case http.MethodPut:
+			req, ok := decodeEscalationPolicyRequest(w, r, system, notifier)
+			if !ok {
+				return
+			}
+
+			before := *existing
+			req.apply(existing)
+			if err := services.Escalation.Update(existing); err != nil {
+				writeEscalationError(w, err)
+				return
+			}
+			audit(r, "update", "escalation_policy", fmt.Sprint(id), before, existing)
+			writeJSON(w, http.StatusOK, existing)
+
+		case http.MethodDelete:
+			if err := services.Escalation.Delete(id); err != nil {
+				writeServiceError(w, err, "Escalation policy")
+				return
+			}
+			audit(r, "delete", "escalation_policy", fmt.Sprint(id), existing, nil)
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+func decodeEscalationPolicyRequest(w http.ResponseWriter, r *http.Request, system *alarm.System, notifier *notify.Dispatcher) (EscalationPolicyRequest, bool) {
+	var req EscalationPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return req, false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return req, false
+	}
+	if len(req.Steps) == 0 {
+		writeError(w, http.StatusBadRequest, "At least one step is required")
+		return req, false
+	}
+	if req.PartitionID != nil {
+		if _, err := system.Panel(*req.PartitionID); errors.Is(err, alarm.ErrUnknownPartition) {
+			writeError(w, http.StatusBadRequest, "Unknown partition")
+			return req, false
+		}
+	}
+
+	for i := range req.Steps {
+		step := &req.Steps[i]
+		prefix := fmt.Sprintf("steps[%d]: ", i)
+		if step.Delay < 0 {
+			writeError(w, http.StatusBadRequest, prefix+"delay can't be negative")
+			return req, false
+		}
+
+		step.Target = strings.ToLower(strings.TrimSpace(step.Target))
+		step.Name = strings.TrimSpace(step.Name)
+		step.Channel = strings.ToLower(strings.TrimSpace(step.Channel))
+		step.Address = strings.TrimSpace(step.Address)
+		switch step.Target {
+		case models.EscalationTargetUser:
+			if step.UserID == nil {
+				writeError(w, http.StatusBadRequest, prefix+"user_id is required")
+				return req, false
+			}
+			user, err := services.User.Get(*step.UserID)
+			if err != nil {
+				log.Printf("Error fetching user: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return req, false
+			}
+			if user == nil {
+				writeError(w, http.StatusBadRequest, prefix+"User not found")
+				return req, false
+			}
+			step.Name = user.Username
+			step.Channel, step.Address = "", ""
+
+		case models.EscalationTargetContact:
+			if step.Name == "" {
+				writeError(w, http.StatusBadRequest, prefix+"name is required")
+				return req, false
+			}
+			channel, ok := notifier.Channel(step.Channel)
+			if !ok {
+				writeError(w, http.StatusBadRequest, prefix+"channel must be one of: "+strings.Join(notifier.Channels(), ", "))
+				return req, false
+			}
+			if err := channel.Validate(step.Address); err != nil {
+				writeError(w, http.StatusBadRequest, prefix+err.Error())
+				return req, false
+			}
+			step.UserID = nil
+
+		default:
+			writeError(w, http.StatusBadRequest, prefix+"target must be user or contact")
+			return req, false
+		}
+	}
+
+	return req, true
+}
+
+func writeEscalationError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrDuplicate) {
+		writeError(w, http.StatusConflict, "An escalation policy with this name already exists")
+		return
+	}
+	log.Printf("Error saving escalation policy: %v", err)
+	writeError(w, http.StatusInternalServerError, "Internal server error")
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/incident"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// IncidentNoteRequest is the body of the incident state changes and notes
+type IncidentNoteRequest struct {
+	Note string `json:"note"`
+}
+
+// AssigneeRequest is the body of PUT /api/incidents/{id}/assignee
+type AssigneeRequest struct {
+	Assignee string `json:"assignee"` // Username, empty to unassign
+}
+
+// IncidentResponse is an incident together with its timeline
+type IncidentResponse struct {
+	*models.Incident
+	Events []models.IncidentEvent `json:"events"`
+}
+
+// getIncidents handles GET /api/incidents
+func getIncidents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	page, pageSize := parsePagination(r)
+	since, until, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	filter := services.IncidentFilter{
+		State:    query.Get("state"),
+		Assignee: query.Get("assignee"),
+		Since:    since,
+		Until:    until,
+	}
+	if p := query.Get("partition_id"); p != "" {
+		partitionID, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid partition_id filter")
+			return
+		}
+		filter.PartitionID = uint(partitionID)
+	}
+
+	incidents, totalCount, err := services.Incident.GetPaginated(filter, page, pageSize)
+	if err != nil {
+		log.Printf("Error fetching incidents: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newPaginatedResponse(incidents, page, pageSize, totalCount))
+}
+
+// getIncident handles GET /api/incidents/{id}
+func getIncident(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := parseID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid incident ID")
+		return
+	}
+
+	found, err := services.Incident.Get(id)
+	if err != nil {
+		log.Printf("Error fetching incident: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if found == nil {
+		writeError(w, http.StatusNotFound, "Incident not found")
+		return
+	}
+
+	events, err := services.Incident.ListEvents(id)
+	if err != nil {
+		log.Printf("Error fetching incident events: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, IncidentResponse{Incident: found, Events: events})
+}
+
+// incidentAction handles POST /api/incidents/{id}/acknowledge, /resolve and
+// /false-alarm. The note is optional.
+func incidentAction(incidents *incident.Manager, action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		id, err := parseID(r, "id")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid incident ID")
+			return
+		}
+
+		var req IncidentNoteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		req.Note = strings.TrimSpace(req.Note)
+
+		var updated *models.Incident
+		switch action {
+		case "acknowledge":
+			updated, err = incidents.Acknowledge(id, actor(r))
+			if err == nil && req.Note != "" {
+				_, err = incidents.AddNote(id, actor(r), req.Note)
+			}
+		case "resolve":
+			updated, err = incidents.Resolve(id, actor(r), false, req.Note)
+		case "false_alarm":
+			updated, err = incidents.Resolve(id, actor(r), true, req.Note)
+		}
+		if err != nil {
+			writeIncidentError(w, err)
+			return
+		}
+
+		audit(r, action, "incident", fmt.Sprint(id), nil, updated)
+		writeJSON(w, http.StatusOK, updated)
+	}
+}
+
+// assignIncident handles PUT /api/incidents/{id}/assignee
+func assignIncident(incidents *incident.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		id, err := parseID(r, "id")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid incident ID")
+			return
+		}
+
+		var req AssigneeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		req.Assignee = strings.TrimSpace(req.Assignee)
+
+		if req.Assignee != "" {
+			user, err := services.User.GetByUsername(req.Assignee)
+			if err != nil {
+				log.Printf("Error fetching user: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			if user == nil {
+				writeError(w, http.StatusBadRequest, "Unknown assignee")
+				return
+			}
+		}
+
+		updated, err := incidents.Assign(id, req.Assignee, actor(r))
+		if err != nil {
+			writeIncidentError(w, err)
+			return
+		}
+
+		audit(r, "assign", "incident", fmt.Sprint(id), nil, updated)
+		writeJSON(w, http.StatusOK, updated)
+	}
+}
+
+// addIncidentNote handles POST /api/incidents/{id}/notes
+func addIncidentNote(incidents *incident.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		id, err := parseID(r, "id")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid incident ID")
+			return
+		}
+
+		var req IncidentNoteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		req.Note = strings.TrimSpace(req.Note)
+		if req.Note == "" {
+			writeError(w, http.StatusBadRequest, "Note is required")
+			return
+		}
+
+		entry, err := incidents.AddNote(id, actor(r), req.Note)
+		if err != nil {
+			writeIncidentError(w, err)
+			return
+		}
+
+		audit(r, "note", "incident", fmt.Sprint(id), nil, entry)
+		writeJSON(w, http.StatusCreated, entry)
+	}
+}
+
+// writeIncidentError maps incident errors to HTTP status codes
+func writeIncidentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, incident.ErrClosed), errors.Is(err, incident.ErrAlreadyAcknowledged):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeServiceError(w, err, "Incident")
+	}
+}
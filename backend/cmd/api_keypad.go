@@ -0,0 +1,279 @@
+package main
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/alarm"
+	"backend/pkg/auth"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// KeypadRequest is the request body for the keypad arm and disarm endpoints.
+// The PIN identifies the user, PartitionID defaults to the default partition.
+type KeypadRequest struct {
+	Pin         string `json:"pin"`
+	Mode        string `json:"mode"`
+	Force       bool   `json:"force"`
+	PartitionID *uint  `json:"partition_id"`
+}
+
+// PinRequest is the request body for setting a user's PINs. An empty pin
+// removes both PINs, an empty duress_pin only removes the duress PIN.
+// Password is only needed when users change their own PINs.
+type PinRequest struct {
+	Password  string `json:"password"`
+	Pin       string `json:"pin"`
+	DuressPin string `json:"duress_pin"`
+}
+
+// keypadAction handles POST /api/keypad/arm and POST /api/keypad/disarm.
+// Keypads don't log in, the PIN decides who is acting. Too many wrong PINs
+// from the same address lock the keypad out for a while.
+func keypadAction(system *alarm.System, lockout *auth.Lockout, disarm bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		keypad := clientIP(r)
+		if until := lockout.LockedUntil(keypad); !until.IsZero() {
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(until).Seconds())+1))
+			writeError(w, http.StatusTooManyRequests, "Too many wrong PINs, keypad is locked")
+			return
+		}
+
+		var req KeypadRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		user, duress, err := matchPin(req.Pin)
+		if err != nil {
+			log.Printf("Error checking PIN: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		if user == nil {
+			log.Printf("Wrong PIN entered on keypad %s", keypad)
+			auditAs("", keypad, "pin_failed", "keypad", "", nil, nil)
+			if lockout.Fail(keypad) {
+				log.Printf("Keypad %s locked out after too many wrong PINs", keypad)
+				auditAs("", keypad, "lockout", "keypad", "", nil, nil)
+			}
+			writeError(w, http.StatusUnauthorized, "Invalid PIN")
+			return
+		}
+		lockout.Reset(keypad)
+
+		partitionID := system.DefaultPartitionID()
+		if req.PartitionID != nil {
+			partitionID = *req.PartitionID
+		}
+		panel, err := system.Panel(partitionID)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "Partition not found")
+			return
+		}
+
+		action := "arm"
+		if disarm {
+			action = "disarm"
+		}
+		// Raise the silent alarm before anything can fail, the response must
+		// look exactly like the one for the normal PIN
+		if duress {
+			panel.RaiseDuress(user.Username, action)
+			auditAs(user.Username, keypad, "duress", "alarm", fmt.Sprint(partitionID), nil, map[string]string{"action": action})
+		}
+
+		switch err := auth.Authorize(user, auth.PermAlarmArm); {
+		case errors.Is(err, auth.ErrOutsideWindow):
+			writeError(w, http.StatusForbidden, "Access is not allowed at this time")
+			return
+		case err != nil:
+			writeError(w, http.StatusForbidden, "Forbidden")
+			return
+		}
+
+		before := panel.Status()
+		if disarm {
+			err = panel.Disarm(user.Username)
+		} else {
+			err = panel.Arm(alarm.State(req.Mode), user.Username, req.Force)
+		}
+		if err != nil {
+			writeAlarmError(w, err)
+			return
+		}
+		auditAs(user.Username, keypad, action, "alarm", fmt.Sprint(partitionID), before, panel.Status())
+
+		writeJSON(w, http.StatusOK, panel.Status())
+	}
+}
+
+// matchPin finds the user a PIN belongs to and whether it is their duress PIN
+func matchPin(pin string) (*models.User, bool, error) {
+	if auth.ValidatePin(pin) != nil {
+		return nil, false, nil
+	}
+
+	users, err := services.User.ListWithPin()
+	if err != nil {
+		return nil, false, err
+	}
+	for i := range users {
+		if auth.CheckPin(users[i].PinHash, pin) {
+			return &users[i], false, nil
+		}
+		if auth.CheckPin(users[i].DuressPinHash, pin) {
+			return &users[i], true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// changePin handles PUT /api/auth/pin. Wrong passwords count against the
+// lockout of the user. Whether a PIN change works tells whether somebody
+// else has that PIN, so PIN changes are rate limited too, successful or not,
+// to keep PINs from being guessed this way.
+func changePin(lockout *auth.Lockout, limit *auth.RateLimit) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		var req PinRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		user, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		key := fmt.Sprintf("pin-change:%d", user.ID)
+		if until := lockout.LockedUntil(key); !until.IsZero() {
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(until).Seconds())+1))
+			writeError(w, http.StatusTooManyRequests, "Too many wrong passwords, try again later")
+			return
+		}
+		if !auth.CheckPassword(user.Password, req.Password) {
+			if lockout.Fail(key) {
+				log.Printf("PIN changes of user %s locked after too many wrong passwords", user.Username)
+				audit(r, "lockout", "user", fmt.Sprint(user.ID), nil, nil)
+			}
+			writeError(w, http.StatusForbidden, "Password is incorrect")
+			return
+		}
+		lockout.Reset(key)
+
+		if ok, next := limit.Allow(key); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(next).Seconds())+1))
+			writeError(w, http.StatusTooManyRequests, "Too many PIN changes, try again later")
+			return
+		}
+
+		if setPins(w, user, req) {
+			audit(r, "pin_change", "user", fmt.Sprint(user.ID), nil, nil)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+// userPinHandler handles PUT /api/users/{id}/pin
+func userPinHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := parseID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req PinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := services.User.Get(id)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if user == nil {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if setPins(w, user, req) {
+		audit(r, "pin_change", "user", fmt.Sprint(user.ID), nil, nil)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// setPins validates, hashes and stores the PINs of a user. PINs have to be
+// unique across all users because the keypad identifies users by PIN alone.
+// A PIN taken by somebody else is refused without saying so.
+func setPins(w http.ResponseWriter, user *models.User, req PinRequest) bool {
+	if req.Pin == "" && req.DuressPin != "" {
+		writeError(w, http.StatusBadRequest, "A duress_pin needs a pin")
+		return false
+	}
+	if req.Pin != "" && req.Pin == req.DuressPin {
+		writeError(w, http.StatusBadRequest, "duress_pin must differ from pin")
+		return false
+	}
+
+	var hashes [2]string
+	for i, pin := range []string{req.Pin, req.DuressPin} {
+		if pin == "" {
+			continue
+		}
+		if err := auth.ValidatePin(pin); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return false
+		}
+
+		owner, _, err := matchPin(pin)
+		if err != nil {
+			log.Printf("Error checking PIN: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return false
+		}
+		if owner != nil && owner.ID != user.ID {
+			writeError(w, http.StatusBadRequest, "PIN can't be used, choose a different one")
+			return false
+		}
+
+		hash, err := auth.HashPin(pin)
+		if err != nil {
+			log.Printf("Error hashing PIN: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return false
+		}
+		hashes[i] = hash
+	}
+
+	if err := services.User.UpdatePins(user.ID, hashes[0], hashes[1]); err != nil {
+		writeServiceError(w, err, "User")
+		return false
+	}
+	log.Printf("Updated PINs of user %s", user.Username)
+	return true
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/notify"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// NotificationPreferenceRequest is the request body for creating and
+// updating notification preferences
+type NotificationPreferenceRequest struct {
+	Channel string   `json:"channel"`
+	Address string   `json:"address"`
+	Events  []string `json:"events"`  // Empty for all events
+	Enabled *bool    `json:"enabled"` // Defaults to true
+}
+
+func (req NotificationPreferenceRequest) apply(pref *models.NotificationPreference) {
+	pref.Channel = req.Channel
+	pref.Address = req.Address
+	pref.Events = strings.Join(req.Events, ",")
+	pref.Enabled = req.Enabled == nil || *req.Enabled
+}
+
+// NotificationTemplateRequest is the request body for PUT /api/notifications/templates/{event}
+type NotificationTemplateRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// NotificationTemplateResponse is the template in use for an event
+type NotificationTemplateResponse struct {
+	Event  string `json:"event"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	Custom bool   `json:"custom"` // Whether the built-in template is overridden
+}
+
+// TestNotificationRequest is the request body for POST /api/notifications/test
+type TestNotificationRequest struct {
+	PreferenceID *uint `json:"preference_id"` // Defaults to all enabled preferences
+}
+
+// getNotificationChannels handles GET /api/notifications/channels
+func getNotificationChannels(notifier *notify.Dispatcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string][]string{
+			"channels": notifier.Channels(),
+			"events":   notify.Events,
+		})
+	}
+}
+
+// notificationPreferencesHandler handles GET and POST /api/notifications/preferences.
+// Users only see and change their own preferences.
+func notificationPreferencesHandler(notifier *notify.Dispatcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			prefs, err := services.Notification.ListPreferences(user.ID)
+			if err != nil {
+				log.Printf("Error fetching notification preferences: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			writeJSON(w, http.StatusOK, prefs)
+
+		case http.MethodPost:
+			req, ok := decodeNotificationPreferenceRequest(w, r, notifier)
+			if !ok {
+				return
+			}
+
+			pref := models.NotificationPreference{UserID: user.ID}
+			req.apply(&pref)
+			if err := services.Notification.CreatePreference(&pref); err != nil {
+				log.Printf("Error creating notification preference: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			audit(r, "create", "notification_preference", fmt.Sprint(pref.ID), nil, pref)
+			writeJSON(w, http.StatusCreated, pref)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// notificationPreferenceHandler handles GET, PUT and DELETE /api/notifications/preferences/{id}
+func notificationPreferenceHandler(notifier *notify.Dispatcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+		id, err := parseID(r, "id")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid preference ID")
+			return
+		}
+
+		existing, err := services.Notification.GetPreference(id)
+		if err != nil {
+			log.Printf("Error fetching notification preference: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		if existing == nil || existing.UserID != user.ID {
+			writeError(w, http.StatusNotFound, "Notification preference not found")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, existing)
+
+		case http.MethodPut:
+			req, ok := decodeNotificationPreferenceRequest(w, r, notifier)
+			if !ok {
+				return
+			}
+
+			before := *existing
+			req.apply(existing)
+			if err := services.Notification.UpdatePreference(existing); err != nil {
+				log.Printf("Error updating notification preference: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			audit(r, "update", "notification_preference", fmt.Sprint(id), before, existing)
+			writeJSON(w, http.StatusOK, existing)
+
+		case http.MethodDelete:
+			if err := services.Notification.DeletePreference(id); err != nil {
+				writeServiceError(w, err, "Notification preference")
+				return
+			}
+			audit(r, "delete", "notification_preference", fmt.Sprint(id), existing, nil)
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// sendTestNotification handles POST /api/notifications/test
+func sendTestNotification(notifier *notify.Dispatcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		user, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var req TestNotificationRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+		}
+
+		prefs, err := services.Notification.ListPreferences(user.ID)
+		if err != nil {
+			log.Printf("Error fetching notification preferences: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		prefs = slices.DeleteFunc(prefs, func(pref models.NotificationPreference) bool {
+			if req.PreferenceID != nil {
+				return pref.ID != *req.PreferenceID
+			}
+			return !pref.Enabled
+		})
+		if len(prefs) == 0 {
+			writeError(w, http.StatusNotFound, "No notification preference to test")
+			return
+		}
+
+		deliveries, err := notifier.Test(user, prefs)
+		if err != nil {
+			log.Printf("Error sending test notification: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		writeJSON(w, http.StatusAccepted, deliveries)
+	}
+}
+
+// getNotificationDeliveries handles GET /api/notifications/deliveries
+func getNotificationDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	page, pageSize := parsePagination(r)
+	since, until, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	filter := services.DeliveryFilter{
+		Event:   query.Get("event"),
+		Channel: query.Get("channel"),
+		Status:  query.Get("status"),
+		Since:   since,
+		Until:   until,
+	}
+	if u := query.Get("user_id"); u != "" {
+		userID, err := strconv.ParseUint(u, 10, 0)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+		filter.UserID = uint(userID)
+	}
+
+	deliveries, totalCount, err := services.Notification.GetDeliveries(filter, page, pageSize)
+	if err != nil {
+		log.Printf("Error fetching notification deliveries: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	writeJSON(w, http.StatusOK, newPaginatedResponse(deliveries, page, pageSize, totalCount))
+}
+
+// getNotificationTemplates handles GET /api/notifications/templates
+func getNotificationTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	overrides, err := services.Notification.ListTemplates()
+	if err != nil {
+		log.Printf("Error fetching notification templates: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	events := append(slices.Clone(notify.Events), models.NotifyEventEscalation, models.NotifyEventTest)
+	templates := make([]NotificationTemplateResponse, 0, len(events))
+	for _, event := range events {
+		template := NotificationTemplateResponse{Event: event, Title: notify.Defaults[event].Title, Body: notify.Defaults[event].Body}
+		for _, override := range overrides {
+			if override.Event == event {
+				template.Title, template.Body, template.Custom = override.Title, override.Body, true
+			}
+		}
+		templates = append(templates, template)
+	}
+	writeJSON(w, http.StatusOK, templates)
+}
+
+// notificationTemplateHandler handles PUT and DELETE /api/notifications/templates/{event}.
+// Deleting a template restores the built-in one.
+func notificationTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	event := r.PathValue("event")
+	if _, ok := notify.Defaults[event]; !ok {
+		writeError(w, http.StatusNotFound, "Unknown notification event")
+		return
+	}
+
+	existing, err := services.Notification.GetTemplate(event)
+	if err != nil {
+		log.Printf("Error fetching notification template: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var req NotificationTemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if strings.TrimSpace(req.Title) == "" || strings.TrimSpace(req.Body) == "" {
+			writeError(w, http.StatusBadRequest, "title and body are required")
+			return
+		}
+		if err := notify.CheckTemplate(req.Title, req.Body); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		template := models.NotificationTemplate{Event: event, Title: req.Title, Body: req.Body}
+		if err := services.Notification.SaveTemplate(&template); err != nil {
+			log.Printf("Error saving notification template: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		var before interface{}
+		if existing != nil {
+			before = *existing
+		}
+		audit(r, "update", "notification_template", event, before, template)
+		writeJSON(w, http.StatusOK, NotificationTemplateResponse{Event: event, Title: template.Title, Body: template.Body, Custom: true})
+
+	case http.MethodDelete:
+		if err := services.Notification.DeleteTemplate(event); err != nil {
+			writeServiceError(w, err, "Notification template")
+			return
+		}
+		audit(r, "delete", "notification_template", event, existing, nil)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func decodeNotificationPreferenceRequest(w http.ResponseWriter, r *http.Request, notifier *notify.Dispatcher) (NotificationPreferenceRequest, bool) {
+	var req NotificationPreferenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return req, false
+	}
+
+	req.Channel = strings.ToLower(strings.TrimSpace(req.Channel))
+	req.Address = strings.TrimSpace(req.Address)
+	channel, ok := notifier.Channel(req.Channel)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "channel must be one of: "+strings.Join(notifier.Channels(), ", "))
+		return req, false
+	}
+	if err := channel.Validate(req.Address); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return req, false
+	}
+
+	for i, event := range req.Events {
+		req.Events[i] = strings.TrimSpace(event)
+		if !slices.Contains(notify.Events, req.Events[i]) {
+			writeError(w, http.StatusBadRequest, "events must be any of: "+strings.Join(notify.Events, ", "))
+			return req, false
+		}
+	}
+	slices.Sort(req.Events)
+	req.Events = slices.Compact(req.Events)
+
+	return req, true
+}
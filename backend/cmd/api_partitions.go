@@ -0,0 +1,181 @@
+package main
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/alarm"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// PartitionRequest is the request body for creating and updating partitions
+type PartitionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// PartitionResponse is a partition together with its current alarm state
+type PartitionResponse struct {
+	models.Partition
+	Alarm *alarm.Status `json:"alarm,omitempty"`
+}
+
+func partitionResponse(system *alarm.System, partition models.Partition) PartitionResponse {
+	resp := PartitionResponse{Partition: partition}
+	if panel, err := system.Panel(partition.ID); err == nil {
+		status := panel.Status()
+		resp.Alarm = &status
+	}
+	return resp
+}
+
+// partitionsHandler handles GET and POST /api/partitions
+func partitionsHandler(system *alarm.System) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			list, err := services.Partition.List()
+			if err != nil {
+				log.Printf("Error fetching partitions: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+
+			resp := make([]PartitionResponse, 0, len(list))
+			for _, partition := range list {
+				resp = append(resp, partitionResponse(system, partition))
+			}
+			writeJSON(w, http.StatusOK, resp)
+
+		case http.MethodPost:
+			req, ok := decodePartitionRequest(w, r)
+			if !ok {
+				return
+			}
+
+			partition := models.Partition{Name: req.Name, Description: req.Description}
+			if err := services.Partition.Create(&partition); err != nil {
+				log.Printf("Error creating partition: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			if err := system.AddPartition(partition.ID); err != nil {
+				log.Printf("Error creating panel: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+
+			audit(r, "create", "partition", fmt.Sprint(partition.ID), nil, partition)
+			writeJSON(w, http.StatusCreated, partitionResponse(system, partition))
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// partitionHandler handles GET, PUT and DELETE /api/partitions/{id}
+func partitionHandler(system *alarm.System) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseID(r, "id")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid partition ID")
+			return
+		}
+
+		if r.Method == http.MethodDelete {
+			if id == system.DefaultPartitionID() {
+				writeError(w, http.StatusConflict, "The default partition can't be deleted")
+				return
+			}
+			before, err := services.Partition.Get(id)
+			if err != nil {
+				log.Printf("Error fetching partition: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			if err := services.Partition.Delete(id); err != nil {
+				writeServiceError(w, err, "Partition")
+				return
+			}
+			audit(r, "delete", "partition", fmt.Sprint(id), before, nil)
+			system.RemovePartition(id)
+			if err := services.AlarmState.Delete(id); err != nil {
+				log.Printf("Error deleting alarm state: %v", err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		existing, err := services.Partition.Get(id)
+		if err != nil {
+			log.Printf("Error fetching partition: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		if existing == nil {
+			writeError(w, http.StatusNotFound, "Partition not found")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, partitionResponse(system, *existing))
+
+		case http.MethodPut:
+			req, ok := decodePartitionRequest(w, r)
+			if !ok {
+				return
+			}
+			if id == system.DefaultPartitionID() && req.Name != existing.Name {
+				writeError(w, http.StatusConflict, "The default partition can't be renamed")
+				return
+			}
+
+			before := *existing
+			existing.Name = req.Name
+			existing.Description = req.Description
+			if err := services.Partition.Update(existing); err != nil {
+				log.Printf("Error updating partition: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			audit(r, "update", "partition", fmt.Sprint(id), before, existing)
+			writeJSON(w, http.StatusOK, partitionResponse(system, *existing))
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+func decodePartitionRequest(w http.ResponseWriter, r *http.Request) (PartitionRequest, bool) {
+	var req PartitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return req, false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "Name is required")
+		return req, false
+	}
+	return req, true
+}
+
+// writeServiceError maps service errors of a delete or update to HTTP status codes
+func writeServiceError(w http.ResponseWriter, err error, resource string) {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		writeError(w, http.StatusNotFound, resource+" not found")
+	case errors.Is(err, services.ErrConflict):
+		writeError(w, http.StatusConflict, resource+" is still in use")
+	default:
+		log.Printf("Error modifying %s: %v", strings.ToLower(resource), err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
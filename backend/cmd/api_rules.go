@@ -0,0 +1,223 @@
+package main
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/alarm"
+	"backend/pkg/auth"
+	"backend/pkg/rules"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// RuleRequest is the request body for creating and updating rules
+type RuleRequest struct {
+	Name    string `json:"name"`
+	Enabled *bool  `json:"enabled"` // Defaults to true
+
+	SensorID   string  `json:"sensor_id"`
+	SensorType string  `json:"sensor_type"`
+	ZoneID     *uint   `json:"zone_id"`
+	Kind       string  `json:"kind"`
+	Operator   string  `json:"operator"`
+	Threshold  float64 `json:"threshold"`
+	TimeFrom   string  `json:"time_from"`
+	TimeUntil  string  `json:"time_until"`
+	ArmState   string  `json:"arm_state"`
+	Cooldown   int     `json:"cooldown"`
+
+	Action      string `json:"action"`
+	Topic       string `json:"topic"`
+	Payload     string `json:"payload"`
+	QoS         byte   `json:"qos"`
+	ArmMode     string `json:"arm_mode"`
+	PartitionID *uint  `json:"partition_id"`
+}
+
+func (req RuleRequest) apply(rule *models.Rule) {
+	rule.Name = req.Name
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	rule.SensorID = req.SensorID
+	rule.SensorType = req.SensorType
+	rule.ZoneID = req.ZoneID
+	rule.Kind = req.Kind
+	rule.Operator = req.Operator
+	rule.Threshold = req.Threshold
+	rule.TimeFrom = req.TimeFrom
+	rule.TimeUntil = req.TimeUntil
+	rule.ArmState = req.ArmState
+	rule.Cooldown = req.Cooldown
+	rule.Action = req.Action
+	rule.Topic = req.Topic
+	rule.Payload = req.Payload
+	rule.QoS = req.QoS
+	rule.ArmMode = req.ArmMode
+	rule.PartitionID = req.PartitionID
+}
+
+// rulesHandler handles GET and POST /api/rules
+func rulesHandler(system *alarm.System, engine *rules.Engine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			list, err := services.Rule.List()
+			if err != nil {
+				log.Printf("Error fetching rules: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			writeJSON(w, http.StatusOK, list)
+
+		case http.MethodPost:
+			var rule models.Rule
+			if !decodeRule(w, r, system, &rule) {
+				return
+			}
+
+			if err := services.Rule.Create(&rule); err != nil {
+				log.Printf("Error creating rule: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			reloadRules(engine)
+			audit(r, "create", "rule", fmt.Sprint(rule.ID), nil, rule)
+			writeJSON(w, http.StatusCreated, rule)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// ruleHandler handles GET, PUT and DELETE /api/rules/{id}
+func ruleHandler(system *alarm.System, engine *rules.Engine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseID(r, "id")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid rule ID")
+			return
+		}
+
+		existing, err := services.Rule.Get(id)
+		if err != nil {
+			log.Printf("Error fetching rule: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		if existing == nil {
+			writeError(w, http.StatusNotFound, "Rule not found")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, existing)
+
+		case http.MethodPut:
+			// Taking away a rule that arms or disarms needs the same permission as adding one
+			if rules.NeedsArmPermission(existing) && !canArm(w, r) {
+				return
+			}
+
+			before := *existing
+			if !decodeRule(w, r, system, existing) {
+				return
+			}
+
+			if err := services.Rule.Update(existing); err != nil {
+				log.Printf("Error updating rule: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			reloadRules(engine)
+			audit(r, "update", "rule", fmt.Sprint(id), before, existing)
+			writeJSON(w, http.StatusOK, existing)
+
+		case http.MethodDelete:
+			if rules.NeedsArmPermission(existing) && !canArm(w, r) {
+				return
+			}
+
+			if err := services.Rule.Delete(id); err != nil {
+				writeServiceError(w, err, "Rule")
+				return
+			}
+			reloadRules(engine)
+			audit(r, "delete", "rule", fmt.Sprint(id), existing, nil)
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// decodeRule reads a RuleRequest into rule and validates the result
+func decodeRule(w http.ResponseWriter, r *http.Request, system *alarm.System, rule *models.Rule) bool {
+	var req RuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	req.SensorID = strings.TrimSpace(req.SensorID)
+	req.SensorType = strings.ToLower(strings.TrimSpace(req.SensorType))
+	req.Kind = strings.TrimSpace(req.Kind)
+	req.Topic = strings.TrimSpace(req.Topic)
+	req.apply(rule)
+
+	if err := rules.Validate(rule); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	if rule.PartitionID != nil {
+		if _, err := system.Panel(*rule.PartitionID); errors.Is(err, alarm.ErrUnknownPartition) {
+			writeError(w, http.StatusBadRequest, "Unknown partition")
+			return false
+		}
+	}
+	if rule.ZoneID != nil {
+		zone, err := services.Zone.Get(*rule.ZoneID)
+		if err != nil {
+			log.Printf("Error fetching zone: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return false
+		}
+		if zone == nil {
+			writeError(w, http.StatusBadRequest, "Unknown zone")
+			return false
+		}
+	}
+
+	// Configuring sensors doesn't allow arming, so neither does configuring a rule that arms
+	// or publishes commands
+	if rules.NeedsArmPermission(rule) && !canArm(w, r) {
+		return false
+	}
+	return true
+}
+
+// canArm checks that the current user may arm and disarm, writing the error response if not
+func canArm(w http.ResponseWriter, r *http.Request) bool {
+	user, ok := currentUser(w, r)
+	if !ok {
+		return false
+	}
+	if err := auth.Authorize(user, auth.PermAlarmArm); err != nil {
+		writeError(w, http.StatusForbidden, "Rules that arm, disarm, trigger the alarm or publish to MQTT need the "+string(auth.PermAlarmArm)+" permission")
+		return false
+	}
+	return true
+}
+
+// reloadRules makes rule changes take effect for new messages
+func reloadRules(engine *rules.Engine) {
+	if err := engine.Reload(); err != nil {
+		log.Printf("Error reloading rules: %v", err)
+	}
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxDelaySeconds is the longest entry or exit delay that can be configured
+const maxDelaySeconds = 600
+
+// sensorIDPattern restricts sensor IDs to characters that are valid in a single MQTT topic level
+var sensorIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,50}$`)
+
+// SensorRequest is the request body for creating and updating sensors. The
+// sensor_id is taken from the path on updates.
+type SensorRequest struct {
+	SensorID    string `json:"sensor_id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Location    string `json:"location"`
+	EntryDelay  int    `json:"entry_delay"`
+	ExitDelay   int    `json:"exit_delay"`
+	ZoneID      *uint  `json:"zone_id"`
+}
+
+func (req SensorRequest) apply(sensor *models.Sensor) {
+	sensor.Name = req.Name
+	sensor.Type = req.Type
+	sensor.Description = req.Description
+	sensor.Location = req.Location
+	sensor.EntryDelay = req.EntryDelay
+	sensor.ExitDelay = req.ExitDelay
+	sensor.ZoneID = req.ZoneID
+}
+
+// sensorsHandler handles GET and POST /api/sensors
+func sensorsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		filter := services.SensorFilter{
+			Type:     query.Get("type"),
+			Status:   query.Get("status"),
+			Location: query.Get("location"),
+		}
+		if z := query.Get("zone_id"); z != "" {
+			zoneID, err := strconv.ParseUint(z, 10, 0)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "Invalid zone ID")
+				return
+			}
+			filter.ZoneID = uint(zoneID)
+		}
+		if o := query.Get("online"); o != "" {
+			online, err := strconv.ParseBool(o)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "Invalid online filter, expected true or false")
+				return
+			}
+			filter.Online = &online
+		}
+
+		page, pageSize := parsePagination(r)
+		sensors, totalCount, err := services.Sensor.GetPaginated(filter, page, pageSize)
+		if err != nil {
+			log.Printf("Error fetching sensors: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		writeJSON(w, http.StatusOK, newPaginatedResponse(sensors, page, pageSize, totalCount))
+
+	case http.MethodPost:
+		req, ok := decodeSensorRequest(w, r)
+		if !ok {
+			return
+		}
+		if !sensorIDPattern.MatchString(req.SensorID) {
+			writeError(w, http.StatusBadRequest, "sensor_id must be 1-50 letters, digits, '_' or '-'")
+			return
+		}
+
+		sensor := models.Sensor{SensorID: req.SensorID}
+		req.apply(&sensor)
+		if err := services.Sensor.Create(&sensor); err != nil {
+			if errors.Is(err, services.ErrDuplicate) {
+				writeError(w, http.StatusConflict, "A sensor with this sensor_id already exists")
+				return
+			}
+			log.Printf("Error creating sensor: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		audit(r, "create", "sensor", sensor.SensorID, nil, sensor)
+		writeJSON(w, http.StatusCreated, sensor)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// sensorHandler handles GET, PUT and DELETE /api/sensors/{sensor_id}
+func sensorHandler(w http.ResponseWriter, r *http.Request) {
+	sensorID := r.PathValue("sensor_id")
+
+	existing, err := services.Sensor.GetBySensorID(sensorID)
+	if err != nil {
+		log.Printf("Error fetching sensor: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if existing == nil {
+		writeError(w, http.StatusNotFound, "Sensor not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, existing)
+
+	case http.MethodPut:
+		req, ok := decodeSensorRequest(w, r)
+		if !ok {
+			return
+		}
+		if req.SensorID != "" && req.SensorID != sensorID {
+			writeError(w, http.StatusBadRequest, "sensor_id can't be changed")
+			return
+		}
+
+		before := *existing
+		req.apply(existing)
+		if err := services.Sensor.Update(existing); err != nil {
+			log.Printf("Error updating sensor: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		audit(r, "update", "sensor", sensorID, before, existing)
+		writeJSON(w, http.StatusOK, existing)
+
+	case http.MethodDelete:
+		if err := services.Sensor.Delete(sensorID); err != nil {
+			writeServiceError(w, err, "Sensor")
+			return
+		}
+		audit(r, "delete", "sensor", sensorID, existing, nil)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// sensorStatusAction handles POST /api/sensors/{sensor_id}/approve, /reject
+// and /block. Rejecting removes a pending sensor, so it is registered as
+// pending again if it keeps sending messages.
+func sensorStatusAction(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		sensorID := r.PathValue("sensor_id")
+		sensor, err := services.Sensor.GetBySensorID(sensorID)
+		if err != nil {
+			log.Printf("Error fetching sensor: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		if sensor == nil {
+			writeError(w, http.StatusNotFound, "Sensor not found")
+			return
+		}
+
+		before := *sensor
+		switch action {
+		case "approve":
+			err = services.Sensor.SetStatus(sensorID, models.SensorStatusActive)
+			sensor.Status = models.SensorStatusActive
+		case "block":
+			err = services.Sensor.SetStatus(sensorID, models.SensorStatusBlocked)
+			sensor.Status = models.SensorStatusBlocked
+		case "reject":
+			if sensor.Status != models.SensorStatusPending {
+				writeError(w, http.StatusConflict, "Only pending sensors can be rejected")
+				return
+			}
+			if err := services.Sensor.Delete(sensorID); err != nil {
+				writeServiceError(w, err, "Sensor")
+				return
+			}
+			audit(r, action, "sensor", sensorID, before, nil)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if err != nil {
+			writeServiceError(w, err, "Sensor")
+			return
+		}
+		audit(r, action, "sensor", sensorID, before, sensor)
+
+		writeJSON(w, http.StatusOK, sensor)
+	}
+}
+
+// getSensorReadingsBySensor handles GET /api/sensors/{sensor_id}/readings with pagination
+func getSensorReadingsBySensor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	page, pageSize := parsePagination(r)
+	readings, totalCount, err := services.SensorReading.GetPaginatedBySensor(r.PathValue("sensor_id"), page, pageSize)
+	if err != nil {
+		log.Printf("Error fetching sensor readings: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newPaginatedResponse(readings, page, pageSize, totalCount))
+}
+
+func decodeSensorRequest(w http.ResponseWriter, r *http.Request) (SensorRequest, bool) {
+	var req SensorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return req, false
+	}
+
+	req.SensorID = strings.TrimSpace(req.SensorID)
+	req.Name = strings.TrimSpace(req.Name)
+	req.Type = strings.ToLower(strings.TrimSpace(req.Type))
+	if req.Name == "" || req.Type == "" {
+		writeError(w, http.StatusBadRequest, "name and type are required")
+		return req, false
+	}
+	if req.EntryDelay < 0 || req.EntryDelay > maxDelaySeconds ||
+		req.ExitDelay < 0 || req.ExitDelay > maxDelaySeconds {
+		writeError(w, http.StatusBadRequest, "Delays must be between 0 and 600 seconds")
+		return req, false
+	}
+
+	if req.ZoneID != nil {
+		zone, err := services.Zone.Get(*req.ZoneID)
+		if err != nil {
+			log.Printf("Error fetching zone: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return req, false
+		}
+		if zone == nil {
+			writeError(w, http.StatusBadRequest, "Zone not found")
+			return req, false
+		}
+	}
+
+	return req, true
+}
+
+// SensorDelaysRequest is the request body for PUT /api/sensors/{sensor_id}/delays
+type SensorDelaysRequest struct {
+	EntryDelay int `json:"entry_delay"`
+	ExitDelay  int `json:"exit_delay"`
+}
+
+// updateSensorDelays handles PUT /api/sensors/{sensor_id}/delays
+func updateSensorDelays(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req SensorDelaysRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.EntryDelay < 0 || req.EntryDelay > maxDelaySeconds ||
+		req.ExitDelay < 0 || req.ExitDelay > maxDelaySeconds {
+		writeError(w, http.StatusBadRequest, "Delays must be between 0 and 600 seconds")
+		return
+	}
+
+	sensorID := r.PathValue("sensor_id")
+	sensor, err := services.Sensor.GetBySensorID(sensorID)
+	if err != nil {
+		log.Printf("Error fetching sensor: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if sensor == nil {
+		writeError(w, http.StatusNotFound, "Sensor not found")
+		return
+	}
+
+	if err := services.Sensor.UpdateDelays(sensorID, req.EntryDelay, req.ExitDelay); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "Sensor not found")
+			return
+		}
+		log.Printf("Error updating sensor delays: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	audit(r, "delays_change", "sensor", sensorID, SensorDelaysRequest{EntryDelay: sensor.EntryDelay, ExitDelay: sensor.ExitDelay}, req)
+
+	writeJSON(w, http.StatusOK, req)
+}
+
+// maxSeriesPoints is the largest number of points returned by a series request
+const maxSeriesPoints = 10000
+
+// SeriesResponse is a time series of one metric of a sensor
+type SeriesResponse struct {
+	SensorID string                 `json:"sensor_id"`
+	Metric   string                 `json:"metric"`
+	Unit     string                 `json:"unit"`
+	Points   []services.SeriesPoint `json:"points"`
+}
+
+// getSensorMetrics handles GET /api/sensors/{sensor_id}/metrics
+func getSensorMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	metrics, err := services.SensorReading.GetMetrics(r.PathValue("sensor_id"))
+	if err != nil {
+		log.Printf("Error fetching sensor metrics: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, metrics)
+}
+
+// getSensorSeries handles GET /api/sensors/{sensor_id}/series
+func getSensorSeries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	metric := r.URL.Query().Get("metric")
+	if metric == "" {
+		writeError(w, http.StatusBadRequest, "metric is required")
+		return
+	}
+
+	since, until, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := 1000
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = min(l, maxSeriesPoints)
+	}
+
+	sensorID := r.PathValue("sensor_id")
+	points, err := services.SensorReading.GetSeries(sensorID, metric, since, until, limit)
+	if err != nil {
+		log.Printf("Error fetching sensor series: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	resp := SeriesResponse{SensorID: sensorID, Metric: metric, Points: points}
+	if len(points) > 0 {
+		resp.Unit = points[len(points)-1].Unit
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
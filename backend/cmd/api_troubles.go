@@ -0,0 +1,80 @@
+package main
+
+import (
+	"backend/database/services"
+	"backend/pkg/trouble"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// getTroubles handles GET /api/troubles
+func getTroubles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	page, pageSize := parsePagination(r)
+	since, until, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	filter := services.TroubleFilter{
+		SensorID: query.Get("sensor_id"),
+		Type:     query.Get("type"),
+		Since:    since,
+		Until:    until,
+	}
+	if a := query.Get("active"); a != "" {
+		active, err := strconv.ParseBool(a)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid active filter, expected true or false")
+			return
+		}
+		filter.Active = &active
+	}
+
+	troubles, totalCount, err := services.Trouble.GetPaginated(filter, page, pageSize)
+	if err != nil {
+		log.Printf("Error fetching troubles: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newPaginatedResponse(troubles, page, pageSize, totalCount))
+}
+
+// acknowledgeTrouble handles POST /api/troubles/{id}/acknowledge
+func acknowledgeTrouble(troubles *trouble.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		id, err := parseID(r, "id")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid trouble ID")
+			return
+		}
+
+		acknowledged, err := troubles.Acknowledge(id, actor(r))
+		switch {
+		case errors.Is(err, trouble.ErrAlreadyAcknowledged):
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		case err != nil:
+			writeServiceError(w, err, "Trouble")
+			return
+		}
+
+		audit(r, "acknowledge", "trouble", fmt.Sprint(id), nil, acknowledged)
+		writeJSON(w, http.StatusOK, acknowledged)
+	}
+}
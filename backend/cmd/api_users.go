@@ -0,0 +1,199 @@
+package main
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/auth"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// UserRequest is the request body for creating and updating users. The
+// password is optional on updates.
+type UserRequest struct {
+	Username    string     `json:"username"`
+	Password    string     `json:"password"`
+	Role        string     `json:"role"`
+	AccessFrom  *time.Time `json:"access_from"`
+	AccessUntil *time.Time `json:"access_until"`
+}
+
+// usersHandler handles GET and POST /api/users
+func usersHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		users, err := services.User.List()
+		if err != nil {
+			log.Printf("Error fetching users: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		writeJSON(w, http.StatusOK, users)
+
+	case http.MethodPost:
+		req, ok := decodeUserRequest(w, r)
+		if !ok {
+			return
+		}
+
+		user := models.User{
+			Username:    req.Username,
+			Role:        req.Role,
+			AccessFrom:  req.AccessFrom,
+			AccessUntil: req.AccessUntil,
+		}
+		if !setPassword(w, &user, req.Password) {
+			return
+		}
+		if err := services.User.Create(&user); err != nil {
+			if errors.Is(err, services.ErrDuplicate) {
+				writeError(w, http.StatusConflict, "Username is already taken")
+				return
+			}
+			log.Printf("Error creating user: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		audit(r, "create", "user", fmt.Sprint(user.ID), nil, user)
+		writeJSON(w, http.StatusCreated, user)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// userHandler handles GET, PUT and DELETE /api/users/{id}
+func userHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	existing, err := services.User.Get(id)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if existing == nil {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, existing)
+
+	case http.MethodPut:
+		req, ok := decodeUserRequest(w, r)
+		if !ok {
+			return
+		}
+		if req.Username != existing.Username {
+			writeError(w, http.StatusBadRequest, "username can't be changed")
+			return
+		}
+		if existing.Role == string(auth.RoleAdmin) && req.Role != existing.Role && !otherAdminExists(w) {
+			return
+		}
+
+		before := *existing
+		existing.Role = req.Role
+		existing.AccessFrom = req.AccessFrom
+		existing.AccessUntil = req.AccessUntil
+		if req.Password != "" && !setPassword(w, existing, req.Password) {
+			return
+		}
+		if err := services.User.Update(existing); err != nil {
+			log.Printf("Error updating user: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		audit(r, "update", "user", fmt.Sprint(id), before, existing)
+		if req.Password != "" {
+			audit(r, "password_change", "user", fmt.Sprint(id), nil, nil)
+		}
+		writeJSON(w, http.StatusOK, existing)
+
+	case http.MethodDelete:
+		if existing.Role == string(auth.RoleAdmin) && !otherAdminExists(w) {
+			return
+		}
+		if err := services.User.Delete(id); err != nil {
+			writeServiceError(w, err, "User")
+			return
+		}
+		audit(r, "delete", "user", fmt.Sprint(id), existing, nil)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// otherAdminExists writes a conflict response and returns false if the
+// admin being changed or deleted is the last one
+func otherAdminExists(w http.ResponseWriter) bool {
+	count, err := services.User.CountByRole(string(auth.RoleAdmin))
+	if err != nil {
+		log.Printf("Error counting admins: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return false
+	}
+	if count <= 1 {
+		writeError(w, http.StatusConflict, "The last admin can't be removed")
+		return false
+	}
+	return true
+}
+
+// setPassword hashes password into user, writing an error response on failure
+func setPassword(w http.ResponseWriter, user *models.User, password string) bool {
+	hash, err := auth.HashPassword(password)
+	if errors.Is(err, auth.ErrPasswordTooShort) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return false
+	}
+	user.Password = hash
+	return true
+}
+
+func decodeUserRequest(w http.ResponseWriter, r *http.Request) (UserRequest, bool) {
+	var req UserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return req, false
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		writeError(w, http.StatusBadRequest, "username is required")
+		return req, false
+	}
+	if !auth.IsValidRole(req.Role) {
+		writeError(w, http.StatusBadRequest, "role must be one of admin, resident, guest, installer")
+		return req, false
+	}
+	if req.AccessFrom != nil && req.AccessUntil != nil && !req.AccessUntil.After(*req.AccessFrom) {
+		writeError(w, http.StatusBadRequest, "access_until must be after access_from")
+		return req, false
+	}
+	// Guests only get time-boxed access
+	if req.Role == string(auth.RoleGuest) && req.AccessUntil == nil {
+		writeError(w, http.StatusBadRequest, "Guests need an access_until")
+		return req, false
+	}
+
+	return req, true
+}
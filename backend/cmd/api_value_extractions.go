@@ -0,0 +1,153 @@
+package main
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/extract"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// ValueExtractionRequest is the request body for creating and updating value extractions
+type ValueExtractionRequest struct {
+	SensorType string  `json:"sensor_type"`
+	Kind       string  `json:"kind"`
+	Metric     string  `json:"metric"`
+	ValuePath  string  `json:"value_path"`
+	UnitPath   string  `json:"unit_path"`
+	Unit       string  `json:"unit"`
+	Scale      float64 `json:"scale"`
+	Offset     float64 `json:"offset"`
+}
+
+func (req ValueExtractionRequest) apply(e *models.ValueExtraction) {
+	e.SensorType = req.SensorType
+	e.Kind = req.Kind
+	e.Metric = req.Metric
+	e.ValuePath = req.ValuePath
+	e.UnitPath = req.UnitPath
+	e.Unit = req.Unit
+	e.Scale = req.Scale
+	e.Offset = req.Offset
+}
+
+// valueExtractionsHandler handles GET and POST /api/value-extractions
+func valueExtractionsHandler(extractors *extract.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			list, err := services.ValueExtraction.List()
+			if err != nil {
+				log.Printf("Error fetching value extractions: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			writeJSON(w, http.StatusOK, list)
+
+		case http.MethodPost:
+			req, ok := decodeValueExtractionRequest(w, r)
+			if !ok {
+				return
+			}
+
+			var extraction models.ValueExtraction
+			req.apply(&extraction)
+			if err := services.ValueExtraction.Create(&extraction); err != nil {
+				log.Printf("Error creating value extraction: %v", err)
+				writeError(w, http.StatusConflict, "An extraction for this sensor type and kind already exists")
+				return
+			}
+			reloadExtractors(extractors)
+			audit(r, "create", "value_extraction", fmt.Sprint(extraction.ID), nil, extraction)
+			writeJSON(w, http.StatusCreated, extraction)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// valueExtractionHandler handles GET, PUT and DELETE /api/value-extractions/{id}
+func valueExtractionHandler(extractors *extract.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseID(r, "id")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid value extraction ID")
+			return
+		}
+
+		existing, err := services.ValueExtraction.Get(id)
+		if err != nil {
+			log.Printf("Error fetching value extraction: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		if existing == nil {
+			writeError(w, http.StatusNotFound, "Value extraction not found")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, existing)
+
+		case http.MethodPut:
+			req, ok := decodeValueExtractionRequest(w, r)
+			if !ok {
+				return
+			}
+
+			before := *existing
+			req.apply(existing)
+			if err := services.ValueExtraction.Update(existing); err != nil {
+				log.Printf("Error updating value extraction: %v", err)
+				writeError(w, http.StatusConflict, "An extraction for this sensor type and kind already exists")
+				return
+			}
+			reloadExtractors(extractors)
+			audit(r, "update", "value_extraction", fmt.Sprint(id), before, existing)
+			writeJSON(w, http.StatusOK, existing)
+
+		case http.MethodDelete:
+			if err := services.ValueExtraction.Delete(id); err != nil {
+				writeServiceError(w, err, "Value extraction")
+				return
+			}
+			reloadExtractors(extractors)
+			audit(r, "delete", "value_extraction", fmt.Sprint(id), existing, nil)
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+func decodeValueExtractionRequest(w http.ResponseWriter, r *http.Request) (ValueExtractionRequest, bool) {
+	var req ValueExtractionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return req, false
+	}
+
+	req.SensorType = strings.ToLower(strings.TrimSpace(req.SensorType))
+	req.Kind = strings.TrimSpace(req.Kind)
+	req.Metric = strings.TrimSpace(req.Metric)
+	req.ValuePath = strings.TrimSpace(req.ValuePath)
+	if req.Kind == "" || req.Metric == "" || req.ValuePath == "" {
+		writeError(w, http.StatusBadRequest, "kind, metric and value_path are required")
+		return req, false
+	}
+
+	return req, true
+}
+
+// reloadExtractors makes configuration changes take effect for new messages
+func reloadExtractors(extractors *extract.Registry) {
+	if err := extractors.Reload(); err != nil {
+		log.Printf("Error reloading value extractions: %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/alarm"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ZoneRequest is the request body for creating and updating zones
+type ZoneRequest struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	PartitionID uint   `json:"partition_id"`
+}
+
+// SensorZoneRequest is the request body for PUT /api/sensors/{sensor_id}/zone
+type SensorZoneRequest struct {
+	ZoneID *uint `json:"zone_id"`
+}
+
+// zonesHandler handles GET and POST /api/zones
+func zonesHandler(system *alarm.System) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			var partitionID uint
+			if p := r.URL.Query().Get("partition_id"); p != "" {
+				id, err := strconv.ParseUint(p, 10, 0)
+				if err != nil {
+					writeError(w, http.StatusBadRequest, "Invalid partition ID")
+					return
+				}
+				partitionID = uint(id)
+			}
+
+			list, err := services.Zone.List(partitionID)
+			if err != nil {
+				log.Printf("Error fetching zones: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			writeJSON(w, http.StatusOK, list)
+
+		case http.MethodPost:
+			req, ok := decodeZoneRequest(system, w, r)
+			if !ok {
+				return
+			}
+
+			zone := models.Zone{
+				Name:        req.Name,
+				Type:        req.Type,
+				Description: req.Description,
+				PartitionID: req.PartitionID,
+			}
+			if err := services.Zone.Create(&zone); err != nil {
+				log.Printf("Error creating zone: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			audit(r, "create", "zone", fmt.Sprint(zone.ID), nil, zone)
+			writeJSON(w, http.StatusCreated, zone)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// zoneHandler handles GET, PUT and DELETE /api/zones/{id}
+func zoneHandler(system *alarm.System) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := parseID(r, "id")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid zone ID")
+			return
+		}
+
+		if r.Method == http.MethodDelete {
+			before, err := services.Zone.Get(id)
+			if err != nil {
+				log.Printf("Error fetching zone: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			if err := services.Zone.Delete(id); err != nil {
+				writeServiceError(w, err, "Zone")
+				return
+			}
+			audit(r, "delete", "zone", fmt.Sprint(id), before, nil)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		existing, err := services.Zone.Get(id)
+		if err != nil {
+			log.Printf("Error fetching zone: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		if existing == nil {
+			writeError(w, http.StatusNotFound, "Zone not found")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, existing)
+
+		case http.MethodPut:
+			req, ok := decodeZoneRequest(system, w, r)
+			if !ok {
+				return
+			}
+
+			before := *existing
+			existing.Name = req.Name
+			existing.Type = req.Type
+			existing.Description = req.Description
+			existing.PartitionID = req.PartitionID
+			if err := services.Zone.Update(existing); err != nil {
+				log.Printf("Error updating zone: %v", err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			audit(r, "update", "zone", fmt.Sprint(id), before, existing)
+			writeJSON(w, http.StatusOK, existing)
+
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+// updateSensorZone handles PUT /api/sensors/{sensor_id}/zone
+func updateSensorZone(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req SensorZoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.ZoneID != nil {
+		zone, err := services.Zone.Get(*req.ZoneID)
+		if err != nil {
+			log.Printf("Error fetching zone: %v", err)
+			writeError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		if zone == nil {
+			writeError(w, http.StatusBadRequest, "Zone not found")
+			return
+		}
+	}
+
+	sensorID := r.PathValue("sensor_id")
+	sensor, err := services.Sensor.GetBySensorID(sensorID)
+	if err != nil {
+		log.Printf("Error fetching sensor: %v", err)
+		writeError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if sensor == nil {
+		writeError(w, http.StatusNotFound, "Sensor not found")
+		return
+	}
+
+	if err := services.Sensor.SetZone(sensorID, req.ZoneID); err != nil {
+		writeServiceError(w, err, "Sensor")
+		return
+	}
+	audit(r, "zone_change", "sensor", sensorID, SensorZoneRequest{ZoneID: sensor.ZoneID}, req)
+
+	writeJSON(w, http.StatusOK, req)
+}
+
+func decodeZoneRequest(system *alarm.System, w http.ResponseWriter, r *http.Request) (ZoneRequest, bool) {
+	var req ZoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return req, false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "Name is required")
+		return req, false
+	}
+	if !alarm.IsValidZoneType(req.Type) {
+		writeError(w, http.StatusBadRequest, "Type must be one of perimeter, interior, 24h_fire, 24h_water, panic")
+		return req, false
+	}
+
+	if req.PartitionID == 0 {
+		req.PartitionID = system.DefaultPartitionID()
+	}
+	if _, err := system.Panel(req.PartitionID); err != nil {
+		writeError(w, http.StatusBadRequest, "Partition not found")
+		return req, false
+	}
+
+	return req, true
+}
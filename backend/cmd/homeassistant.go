@@ -0,0 +1,96 @@
+package main
+
+import (
+	"backend/pkg/alarm"
+	"backend/pkg/auth"
+	"backend/pkg/homeassistant"
+	"errors"
+	"fmt"
+	"log"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// homeAssistantActor is who arms and disarms from Home Assistant when no PIN
+// is required. It is also the audit source and lockout key of its commands.
+const homeAssistantActor = "homeassistant"
+
+// newHomeAssistantHandler carries out the arm and disarm commands of the
+// alarm panels published to Home Assistant. With requireCode the PIN sent
+// along decides who is acting, the same way as on a keypad.
+func newHomeAssistantHandler(system *alarm.System, bridge *homeassistant.Bridge, lockout *auth.Lockout, requireCode bool) mqtt.MessageHandler {
+	return func(_ mqtt.Client, msg mqtt.Message) {
+		// A retained command was left on the broker, acting on it again would be a surprise
+		if msg.Retained() {
+			log.Printf("Ignoring retained Home Assistant command on %s", msg.Topic())
+			return
+		}
+
+		command, err := bridge.ParseCommand(msg.Topic(), msg.Payload())
+		if err != nil {
+			log.Printf("Rejected Home Assistant command: %v", err)
+			return
+		}
+		panel, err := system.Panel(command.PartitionID)
+		if err != nil {
+			log.Printf("Rejected Home Assistant command for partition %d: %v", command.PartitionID, err)
+			return
+		}
+
+		action := "arm"
+		if command.Action == homeassistant.ActionDisarm {
+			action = "disarm"
+		}
+
+		by := homeAssistantActor
+		if requireCode {
+			if until := lockout.LockedUntil(homeAssistantActor); !until.IsZero() {
+				log.Printf("Ignoring Home Assistant command, locked out until %s", until.Format("15:04:05"))
+				return
+			}
+
+			user, duress, err := matchPin(command.Code)
+			if err != nil {
+				log.Printf("Error checking PIN: %v", err)
+				return
+			}
+			if user == nil {
+				log.Printf("Wrong PIN entered in Home Assistant")
+				auditAs("", homeAssistantActor, "pin_failed", "keypad", "", nil, nil)
+				if lockout.Fail(homeAssistantActor) {
+					log.Printf("Home Assistant locked out after too many wrong PINs")
+					auditAs("", homeAssistantActor, "lockout", "keypad", "", nil, nil)
+				}
+				return
+			}
+			lockout.Reset(homeAssistantActor)
+
+			if duress {
+				panel.RaiseDuress(user.Username, action)
+				auditAs(user.Username, homeAssistantActor, "duress", "alarm", fmt.Sprint(command.PartitionID), nil, map[string]string{"action": action})
+			}
+
+			switch err := auth.Authorize(user, auth.PermAlarmArm); {
+			case errors.Is(err, auth.ErrOutsideWindow):
+				log.Printf("User %s may not %s from Home Assistant at this time", user.Username, action)
+				return
+			case err != nil:
+				log.Printf("User %s may not %s from Home Assistant", user.Username, action)
+				return
+			}
+			by = user.Username
+		}
+
+		before := panel.Status()
+		if command.Action == homeassistant.ActionDisarm {
+			err = panel.Disarm(by)
+		} else {
+			err = panel.Arm(command.ArmMode(), by, false)
+		}
+		if err != nil {
+			log.Printf("Failed to %s partition %d from Home Assistant: %v", action, command.PartitionID, err)
+			return
+		}
+		auditAs(by, homeAssistantActor, action, "alarm", fmt.Sprint(command.PartitionID), before, panel.Status())
+	}
+}
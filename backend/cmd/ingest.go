@@ -0,0 +1,351 @@
+package main
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/adapter"
+	"backend/pkg/alarm"
+	"backend/pkg/extract"
+	"backend/pkg/homeassistant"
+	"backend/pkg/incident"
+	"backend/pkg/payloads"
+	"backend/pkg/reporting"
+	"backend/pkg/rules"
+	"backend/pkg/siren"
+	"backend/pkg/supervision"
+	"backend/pkg/topics"
+	"backend/pkg/trouble"
+	"backend/pkg/websockets"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Reasons a message is rejected, stored on models.DeadLetter
+const (
+	reasonInvalidTopic   = "invalid_topic"
+	reasonUnknownKind    = "unknown_kind"
+	reasonInvalidPayload = "invalid_payload"
+	reasonSensorMismatch = "sensor_mismatch"
+	reasonExtraction     = "extraction_failed"
+	reasonDatabase       = "database_error"
+)
+
+const (
+	// unknownSensorType is the type of sensors registered automatically
+	unknownSensorType = "unknown"
+	// pendingSensorsTopic is the WebSocket topic newly seen sensors are announced on
+	pendingSensorsTopic = "sensors/pending"
+)
+
+// IngestError is returned by Ingestor.Process when a message is rejected
+type IngestError struct {
+	Reason string
+	Err    error
+}
+
+func (e *IngestError) Error() string {
+	return e.Reason + ": " + e.Err.Error()
+}
+
+func (e *IngestError) Unwrap() error {
+	return e.Err
+}
+
+// rejectionReason returns the reason of an *IngestError, errors of any other
+// type are treated as database errors
+func rejectionReason(err error) string {
+	var ingestErr *IngestError
+	if errors.As(err, &ingestErr) {
+		return ingestErr.Reason
+	}
+	return reasonDatabase
+}
+
+// InboundMessage is an MQTT message to be processed
+type InboundMessage struct {
+	Topic      string
+	Payload    []byte
+	ReceivedAt time.Time
+	// Replay is set when re-processing a dead letter. Replayed messages are
+	// stored and broadcast but not evaluated by the alarm panels, since they
+	// describe the past.
+	Replay bool
+	// Retained is set for messages the broker kept from before we subscribed.
+	// They tell the last known status of a sensor but alarms in them are old.
+	Retained bool
+}
+
+// Ingestor turns MQTT messages into sensor readings and alarm panel events
+type Ingestor struct {
+	wsHub      *websockets.WsHub
+	system     *alarm.System
+	extractors *extract.Registry
+	supervisor *supervision.Supervisor
+	troubles   *trouble.Manager
+	incidents  *incident.Manager
+	sirens     *siren.Controller
+	rules      *rules.Engine
+	reporter   *reporting.Reporter   // nil unless SIA reporting is enabled
+	bridge     *homeassistant.Bridge // nil unless Home Assistant discovery is enabled
+	// Topic templates of native sensor messages, tried in order
+	subscriptions []topics.Subscription
+	adapters      []adapter.Adapter
+	adapterQoS    map[string]byte // By adapter name
+}
+
+func NewIngestor(wsHub *websockets.WsHub, system *alarm.System, extractors *extract.Registry, supervisor *supervision.Supervisor, troubles *trouble.Manager, incidents *incident.Manager, sirens *siren.Controller, engine *rules.Engine, reporter *reporting.Reporter, bridge *homeassistant.Bridge) *Ingestor {
+	return &Ingestor{wsHub: wsHub, system: system, extractors: extractors, supervisor: supervisor, troubles: troubles, incidents: incidents, sirens: sirens, rules: engine, reporter: reporter, bridge: bridge, adapterQoS: make(map[string]byte)}
+}
+
+// SetSubscriptions sets the topic templates native sensor messages are
+// read from. Call it before connecting to the broker.
+func (in *Ingestor) SetSubscriptions(subscriptions []topics.Subscription) {
+	in.subscriptions = subscriptions
+}
+
+// AddAdapter adds an adapter for devices that don't publish native sensor
+// messages, its topics are subscribed to with qos. Call it before
+// connecting to the broker.
+func (in *Ingestor) AddAdapter(a adapter.Adapter, qos byte) {
+	in.adapters = append(in.adapters, a)
+	in.adapterQoS[a.Name()] = qos
+}
+
+// Filters returns the topic filters of the native sensor messages and of
+// every adapter with the QoS to subscribe with. Templates sharing a filter
+// are subscribed to once with the highest QoS.
+func (in *Ingestor) Filters() map[string]byte {
+	filters := make(map[string]byte)
+	add := func(filter string, qos byte) {
+		if current, ok := filters[filter]; !ok || qos > current {
+			filters[filter] = qos
+		}
+	}
+	for _, s := range in.subscriptions {
+		add(s.Template.Filter(), s.QoS)
+	}
+	for _, a := range in.adapters {
+		for _, filter := range a.Subscriptions() {
+			add(filter, in.adapterQoS[a.Name()])
+		}
+	}
+	return filters
+}
+
+// Process validates and stores a message, returning an *IngestError if it is rejected
+func (in *Ingestor) Process(msg InboundMessage) error {
+	if a := adapter.Find(in.adapters, msg.Topic); a != nil {
+		messages, err := a.Translate(msg.Topic, msg.Payload, msg.ReceivedAt)
+		if err != nil {
+			return &IngestError{reasonInvalidPayload, fmt.Errorf("%s: %w", a.Name(), err)}
+		}
+		// One device message can carry several readings, an alarm and a
+		// battery level for example. A rejected message is kept as a whole,
+		// re-processing it stores the readings before the rejected one again.
+		for _, native := range messages {
+			if err := in.process(msg, native); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// The first template matching the topic tells the sensor and kind
+	for _, s := range in.subscriptions {
+		fields, ok := s.Template.Match(msg.Topic)
+		if !ok {
+			continue
+		}
+		return in.process(msg, adapter.Message{
+			SensorID:   fields[topics.FieldSensorID],
+			SensorType: fields[topics.FieldType],
+			Location:   fields[topics.FieldLocation],
+			Kind:       fields[topics.FieldKind],
+			Payload:    msg.Payload,
+		})
+	}
+	return &IngestError{reasonInvalidTopic, fmt.Errorf("topic %s matches no subscription", msg.Topic)}
+}
+
+// process stores a native sensor message and hands it to the alarm panels
+func (in *Ingestor) process(msg InboundMessage, native adapter.Message) error {
+	sensorId, kind, payload := native.SensorID, native.Kind, native.Payload
+
+	// Decode and validate the payload with the decoder registered for its kind
+	decoded, err := payloads.Decode(kind, payload)
+	if errors.Is(err, payloads.ErrUnknownKind) {
+		return &IngestError{reasonUnknownKind, err}
+	}
+	if err != nil {
+		return &IngestError{reasonInvalidPayload, err}
+	}
+	if id := decoded.Sensor(); id != "" && id != sensorId {
+		return &IngestError{reasonSensorMismatch, fmt.Errorf("sensor_id %q in payload does not match topic", id)}
+	}
+
+	sensor, err := in.lookupSensor(native)
+	if err != nil {
+		return &IngestError{reasonDatabase, err}
+	}
+	if sensor.Status == models.SensorStatusBlocked {
+		log.Printf("Dropped message from blocked sensor %s\n", sensorId)
+		return nil
+	}
+	// Any valid message proves the sensor is alive, not just heartbeats,
+	// except for the offline status that devices publish as Last Will
+	if !msg.Replay {
+		if status, ok := decoded.(*payloads.Status); ok && status.Status == "offline" {
+			in.supervisor.Offline(sensor)
+		} else {
+			in.supervisor.Seen(sensor, msg.ReceivedAt)
+		}
+	}
+	sensorType := sensor.Type
+
+	// Create a new sensor reading
+	reading := &models.SensorReading{
+		SensorID:         sensorId,
+		Message:          string(payload),
+		Timestamp:        msg.ReceivedAt,
+		MessageTimestamp: decoded.Time(),
+	}
+
+	// Messages without an extraction rule (alarm, status...) are stored without a value
+	value, ok, err := in.extractors.Extract(sensorType, kind, payload)
+	if err != nil {
+		return &IngestError{reasonExtraction, err}
+	}
+	if ok {
+		reading.Metric = value.Metric
+		reading.Value = value.Value
+		reading.Unit = value.Unit
+	}
+
+	if err := services.SensorReading.Create(reading); err != nil {
+		return &IngestError{reasonDatabase, err}
+	}
+
+	in.wsHub.BroadcastToTopic([]byte(reading.Message), "sensor/"+sensorId)
+	in.wsHub.BroadcastToTopic([]byte(reading.Message), "sensors")
+
+	// Tamper and battery reports of approved sensors can raise or clear troubles
+	if !msg.Replay && sensor.Status == models.SensorStatusActive {
+		in.troubles.HandlePayload(sensor, decoded)
+	}
+
+	// Home Assistant mirrors the metrics and alarms of approved sensors
+	if in.bridge != nil && !msg.Replay && sensor.Status == models.SensorStatusActive {
+		in.bridge.HandleReading(sensor, reading, decoded.Kind() == payloads.KindAlarm && !msg.Retained)
+	}
+
+	// Rules see the panel state from before the message, so a rule for an
+	// armed partition still fires for the alarm that triggers it
+	if !msg.Replay && !msg.Retained && sensor.Status == models.SensorStatusActive {
+		in.rules.Evaluate(rules.Event{Reading: reading, Sensor: sensor, Kind: kind})
+	}
+
+	if decoded.Kind() == payloads.KindAlarm && !msg.Replay && !msg.Retained {
+		if sensor.Status == models.SensorStatusPending {
+			log.Printf("Ignoring alarm from pending sensor %s\n", sensorId)
+			return nil
+		}
+
+		// Only forward alarms the panel of the sensor's partition decides to raise
+		if partitionID, raised := in.system.HandleSensorAlarm(sensorId, sensor); raised {
+			in.wsHub.BroadcastToTopic(payload, alarm.AlertTopic)
+			alert := alarm.Alert{PartitionID: partitionID, SensorID: sensorId, Message: decoded.(*payloads.Alarm).Message}
+			in.incidents.RecordAlarm(partitionID, sensorId, &reading.ID, alert.Message)
+			// A sensor tripping in an already triggered partition sounds sirens
+			// again that the cutoff silenced
+			in.sirens.HandleAlert(alert)
+			if in.reporter != nil {
+				in.reporter.HandleAlert(alert)
+			}
+		}
+	}
+
+	return nil
+}
+
+// lookupSensor returns the registered sensor, registering unknown sensors
+// as pending so an admin can decide what to do with them. They get the type
+// and location the topic or adapter tells, if any.
+func (in *Ingestor) lookupSensor(native adapter.Message) (*models.Sensor, error) {
+	sensorId := native.SensorID
+	sensor, err := services.Sensor.GetBySensorID(sensorId)
+	if err != nil {
+		return nil, err
+	}
+	if sensor != nil {
+		return sensor, nil
+	}
+
+	sensorType := native.SensorType
+	if sensorType == "" {
+		sensorType = unknownSensorType
+	}
+	sensor, err = services.Sensor.RegisterPending(sensorId, sensorType, native.Location)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Registered unknown sensor %s as pending\n", sensorId)
+
+	if message, err := json.Marshal(sensor); err == nil {
+		in.wsHub.BroadcastToTopic(message, pendingSensorsTopic)
+	}
+	return sensor, nil
+}
+
+// Reprocess runs a dead letter through Process again and records the outcome
+func (in *Ingestor) Reprocess(letter *models.DeadLetter) error {
+	if letter.Status == models.DeadLetterReprocessed {
+		return errors.New("dead letter was already re-processed")
+	}
+
+	err := in.Process(InboundMessage{
+		Topic:      letter.Topic,
+		Payload:    []byte(letter.Payload),
+		ReceivedAt: letter.ReceivedAt,
+		Replay:     true,
+	})
+	if err == nil {
+		return services.DeadLetter.MarkReprocessed(letter)
+	}
+
+	if markErr := services.DeadLetter.MarkFailed(letter, rejectionReason(err), err); markErr != nil {
+		log.Printf("Failed to update dead letter %d: %v", letter.ID, markErr)
+	}
+	return err
+}
+
+func createMessageHandler(ingestor *Ingestor) mqtt.MessageHandler {
+	return func(client mqtt.Client, msg mqtt.Message) {
+		topic := msg.Topic()
+		payload := msg.Payload()
+		log.Printf("Received message: %s from topic: %s\n", payload, topic)
+
+		inbound := InboundMessage{Topic: topic, Payload: payload, ReceivedAt: time.Now(), Retained: msg.Retained()}
+		err := ingestor.Process(inbound)
+		if err == nil {
+			return
+		}
+		log.Printf("Rejected message from %s: %v\n", topic, err)
+
+		// Keep the message so it can be inspected and re-processed later
+		letter := &models.DeadLetter{
+			Topic:      topic,
+			Payload:    string(payload),
+			Reason:     rejectionReason(err),
+			Error:      err.Error(),
+			ReceivedAt: inbound.ReceivedAt,
+		}
+		if err := services.DeadLetter.Create(letter); err != nil {
+			log.Printf("Failed to store dead letter: %v\n", err)
+		}
+	}
+}
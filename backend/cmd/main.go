@@ -3,23 +3,108 @@ package main
 import (
 	postgres "backend/database"
 	"backend/database/models"
-	"backend/database/services"
+	"backend/pkg/actuator"
+	"backend/pkg/adapter"
+	"backend/pkg/alarm"
+	"backend/pkg/auth"
+	"backend/pkg/escalation"
+	"backend/pkg/extract"
+	"backend/pkg/homeassistant"
+	"backend/pkg/incident"
+	"backend/pkg/notify"
+	"backend/pkg/reporting"
+	"backend/pkg/rules"
+	"backend/pkg/sia"
+	"backend/pkg/siren"
+	"backend/pkg/supervision"
+	"backend/pkg/topics"
+	"backend/pkg/trouble"
 	"backend/pkg/utils"
 	"backend/pkg/websockets"
 	"crypto/tls"
 	"crypto/x509"
-	"encoding/json"
 	"log"
 	"os"
-	"regexp"
-	"strings"
+	"os/signal"
+	"syscall"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
-var connectHandler mqtt.OnConnectHandler = func(client mqtt.Client) {
-	log.Println("Connected")
+// Payloads of the retained backend status message
+const (
+	backendOnline  = "online"
+	backendOffline = "offline"
+)
+
+// newConnectHandler announces the backend as online and subscribes to the
+// sensor topics. It runs again after every reconnect, since the broker
+// publishes our Last Will when the connection drops.
+func newConnectHandler(statusTopic string, sensorTopics map[string]byte, actuators *actuator.Manager, bridge *homeassistant.Bridge, haCommands mqtt.MessageHandler) mqtt.OnConnectHandler {
+	return func(client mqtt.Client) {
+		log.Println("Connected")
+
+		token := client.Publish(statusTopic, 1, true, backendOnline)
+		if token.WaitTimeout(5*time.Second) && token.Error() != nil {
+			log.Printf("Failed to publish backend status: %v", token.Error())
+		}
+
+		// Subscribe to sensor topics, native ones and those of the adapters,
+		// each with its own QoS
+		token = client.SubscribeMultiple(sensorTopics, nil)
+		token.Wait()
+		if token.Error() != nil {
+			log.Printf("Failed to subscribe to sensor topics: %v", token.Error())
+		} else {
+			for topic, qos := range sensorTopics {
+				log.Printf("Subscribed to topic: %s (QoS %d)\n", topic, qos)
+			}
+		}
+
+		// Replies from actuators bypass the sensor ingestion
+		for _, topic := range []string{actuator.AckTopic, actuator.StateTopic} {
+			token = client.Subscribe(topic, 1, func(_ mqtt.Client, msg mqtt.Message) {
+				actuators.HandleMessage(msg.Topic(), msg.Payload())
+			})
+			token.Wait()
+			if token.Error() != nil {
+				log.Printf("Failed to subscribe to %s: %v", topic, token.Error())
+				continue
+			}
+			log.Printf("Subscribed to topic: %s\n", topic)
+		}
+
+		if bridge == nil {
+			return
+		}
+		subscriptions := map[string]mqtt.MessageHandler{
+			bridge.CommandTopic(): haCommands,
+			bridge.StatusTopic(): func(_ mqtt.Client, msg mqtt.Message) {
+				bridge.HandleStatus(msg.Payload())
+			},
+		}
+		for topic, handler := range subscriptions {
+			token = client.Subscribe(topic, 1, handler)
+			token.Wait()
+			if token.Error() != nil {
+				log.Printf("Failed to subscribe to %s: %v", topic, token.Error())
+				continue
+			}
+			log.Printf("Subscribed to topic: %s\n", topic)
+		}
+		// Retained configs may have been cleared while we were away
+		bridge.Sync()
+	}
+}
+
+// envQoS reads an MQTT QoS level from the environment, 1 if it isn't set
+func envQoS(key string) byte {
+	qos := utils.GetEnvInt(key, 1)
+	if qos < 0 || qos > 2 {
+		log.Fatalf("Invalid %s: QoS must be 0, 1 or 2", key)
+	}
+	return byte(qos)
 }
 
 var connectLostHandler mqtt.ConnectionLostHandler = func(client mqtt.Client, err error) {
@@ -37,11 +122,224 @@ func main() {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// Create the first user if there are none yet
+	if err := bootstrapAdmin(); err != nil {
+		log.Fatalf("Failed to create initial user: %v", err)
+	}
+
+	// Session tokens for the API and WebSocket connections
+	tokens, err := auth.NewTokenManager(
+		utils.GetEnv("AUTH_TOKEN_SECRET", ""),
+		utils.GetEnvDuration("AUTH_TOKEN_TTL", 24*time.Hour),
+	)
+	if err != nil {
+		log.Fatalf("Failed to initialize session tokens: %v", err)
+	}
+
 	// Initialize Websocket
-	wsHub := websockets.StartWebsocketServer()
+	wsHub := websockets.StartWebsocketServer(tokens)
+	wsHub.RestrictTopic(alarm.StateTopic, auth.PermAlarmRead)
+	wsHub.RestrictTopic(alarm.CountdownTopic, auth.PermAlarmRead)
+	wsHub.RestrictTopic(alarm.AlertTopic, auth.PermAlarmRead)
+	wsHub.RestrictTopic(alarm.DuressTopic, auth.PermAlarmDuress)
+	wsHub.RestrictTopic("sensor", auth.PermSensorsRead) // sensor/<id>, sensors and sensors/pending
+	wsHub.RestrictTopic(trouble.Topic, auth.PermSensorsRead)
+	wsHub.RestrictTopic(incident.Topic, auth.PermAlarmRead)
+	wsHub.RestrictTopic(rules.NotifyTopic, auth.PermAlarmRead)
+	wsHub.RestrictTopic(actuator.Topic, auth.PermSensorsRead)
+	wsHub.RestrictTopic(siren.Topic, auth.PermAlarmRead)
+
+	// Restore the alarm panels of all partitions
+	system, err := alarm.NewSystem(wsHub)
+	if err != nil {
+		log.Fatalf("Failed to initialize alarm system: %v", err)
+	}
+
+	// Alarms, troubles, openings and closings are reported to the monitoring
+	// station when SIA DC-09 reporting is enabled
+	var reporter *reporting.Reporter
+	if utils.GetEnvBool("SIA_ENABLED", false) {
+		key, err := sia.ParseKey(utils.GetEnv("SIA_KEY", ""))
+		if err != nil {
+			log.Fatalf("Invalid SIA_KEY: %v", err)
+		}
+		reporter, err = reporting.NewReporter(reporting.Config{
+			Address:    utils.GetEnv("SIA_RECEIVER", "localhost:12000"),
+			Protocol:   utils.GetEnv("SIA_PROTOCOL", "tcp"),
+			Token:      utils.GetEnv("SIA_FORMAT", sia.TokenSIA),
+			Account:    utils.GetEnv("SIA_ACCOUNT", ""),
+			Receiver:   utils.GetEnv("SIA_RECEIVER_NUMBER", ""),
+			Prefix:     utils.GetEnv("SIA_PREFIX", "0"),
+			Key:        key,
+			AckTimeout: utils.GetEnvDuration("SIA_ACK_TIMEOUT", 10*time.Second),
+			RetryDelay: utils.GetEnvDuration("SIA_RETRY_DELAY", 5*time.Second),
+			Heartbeat:  utils.GetEnvDuration("SIA_HEARTBEAT", 0),
+			Expire:     utils.GetEnvDuration("SIA_EXPIRE", 24*time.Hour),
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize SIA reporting: %v", err)
+		}
+		reporter.Start()
+	}
+
+	// Load the rules for reading numeric values from payloads
+	extractors, err := extract.NewRegistry()
+	if err != nil {
+		log.Fatalf("Failed to load value extraction rules: %v", err)
+	}
+
+	// Troubles (tamper, low battery, supervision) keep partitions from being armed
+	troubles := trouble.NewManager(wsHub,
+		float64(utils.GetEnvInt("LOW_BATTERY_THRESHOLD", 20)),
+		utils.GetEnv("ARM_WITH_TROUBLES", trouble.PolicyForce),
+	)
+	system.SetArmGuard(troubles.ArmGuard)
+
+	// Mark sensors offline that stop sending messages
+	supervisor := supervision.NewSupervisor(troubles, utils.GetEnvDuration("SUPERVISION_WINDOW", 60*time.Second))
+	supervisor.Start()
+
+	// Raised alarms are grouped into incidents that users work through
+	incidents := incident.NewManager(wsHub)
+	system.SetAlarmHandler(func(alert alarm.Alert) {
+		incidents.HandleAlert(alert)
+		if reporter != nil {
+			reporter.HandleAlert(alert)
+		}
+	})
+
+	// Commands to sirens, locks and other actuators are retried until acknowledged
+	actuators := actuator.NewManager(wsHub,
+		utils.GetEnvDuration("ACTUATOR_ACK_TIMEOUT", 5*time.Second),
+		utils.GetEnvInt("ACTUATOR_RETRIES", 3),
+	)
+
+	// Sirens sound while a partition is triggered, up to the legal cutoff
+	sirens := siren.NewController(wsHub, actuators, system.DefaultPartitionID(), siren.Config{
+		Cutoff:       utils.GetEnvDuration("SIREN_CUTOFF", 5*time.Minute),
+		Pattern:      utils.GetEnv("SIREN_PATTERN", "continuous"),
+		Strobe:       utils.GetEnvBool("SIREN_STROBE", true),
+		MaxSoundings: utils.GetEnvInt("SIREN_MAX_SOUNDINGS", 3),
+	})
+
+	// Users are notified about alarms, arming and troubles over their own channels
+	notifier := notify.NewDispatcher(notify.Config{
+		MaxAttempts: utils.GetEnvInt("NOTIFY_MAX_ATTEMPTS", 3),
+		RetryDelay:  utils.GetEnvDuration("NOTIFY_RETRY_DELAY", 30*time.Second),
+		Workers:     utils.GetEnvInt("NOTIFY_WORKERS", 4),
+	})
+	allowPrivate := utils.GetEnvBool("NOTIFY_ALLOW_PRIVATE_URLS", false)
+	notifier.RegisterChannel(models.NotifyChannelWebhook, notify.NewWebhook(utils.GetEnv("NOTIFY_WEBHOOK_SECRET", ""), allowPrivate))
+	notifier.RegisterChannel(models.NotifyChannelNtfy, notify.NewNtfy(
+		utils.GetEnv("NTFY_URL", "https://ntfy.sh"),
+		utils.GetEnv("NTFY_TOKEN", ""),
+		allowPrivate,
+	))
+	if smtpHost := utils.GetEnv("SMTP_HOST", ""); smtpHost != "" {
+		notifier.RegisterChannel(models.NotifyChannelEmail, &notify.Email{
+			Host:     smtpHost,
+			Port:     utils.GetEnvInt("SMTP_PORT", 587),
+			Username: utils.GetEnv("SMTP_USERNAME", ""),
+			Password: utils.GetEnv("SMTP_PASSWORD", ""),
+			From:     utils.GetEnv("SMTP_FROM", "home-security@localhost"),
+		})
+	}
+	notifier.Start()
+
+	// Unacknowledged incidents are escalated along the partition's escalation policy
+	escalator := escalation.NewEscalator(incidents, notifier)
+	incidents.SetListener(escalator.HandleIncident)
+	escalator.Start()
+
+	// Partitions and sensors show up in Home Assistant through MQTT discovery
+	statusTopic := utils.GetEnv("MQTT_STATUS_TOPIC", "backend/status")
+	var bridge *homeassistant.Bridge
+	if utils.GetEnvBool("HA_DISCOVERY_ENABLED", false) {
+		bridge = homeassistant.NewBridge(system, homeassistant.Config{
+			DiscoveryPrefix:   utils.GetEnv("HA_DISCOVERY_PREFIX", "homeassistant"),
+			BaseTopic:         utils.GetEnv("HA_BASE_TOPIC", "home-security"),
+			NodeID:            utils.GetEnv("HA_NODE_ID", "home_security"),
+			AvailabilityTopic: statusTopic,
+			RequireCode:       utils.GetEnvBool("HA_REQUIRE_CODE", true),
+			SyncInterval:      utils.GetEnvDuration("HA_SYNC_INTERVAL", 5*time.Minute),
+		})
+		bridge.Start()
+	}
+
+	troubles.SetListener(func(event trouble.Event) {
+		notifier.HandleTrouble(event)
+		if reporter != nil {
+			reporter.HandleTrouble(event)
+		}
+		if bridge != nil {
+			bridge.HandleTrouble(event)
+		}
+	})
+
+	system.SetStateListener(func(change alarm.StateChange) {
+		sirens.HandleStateChange(change)
+		notifier.HandleStateChange(change)
+		if reporter != nil {
+			reporter.HandleStateChange(change)
+		}
+		if bridge != nil {
+			bridge.HandleStateChange(change)
+		}
+	})
+
+	if reporter != nil {
+		system.SetDuressListener(reporter.HandleDuress)
+	}
+
+	// User defined automations run on every sensor message
+	automations, err := rules.NewEngine(wsHub, system)
+	if err != nil {
+		log.Fatalf("Failed to load rules: %v", err)
+	}
+	// Notify actions still show up in the UI and also reach subscribed users
+	automations.SetNotifier(func(title, message string) {
+		automations.BroadcastNotification(title, message)
+		notifier.HandleRule(title, message)
+	})
+
+	ingestor := NewIngestor(wsHub, system, extractors, supervisor, troubles, incidents, sirens, automations, reporter, bridge)
+
+	// Topic templates tell where native sensor messages are published and
+	// which parts of the topic are the sensor ID and the message kind
+	subscriptions, err := topics.ParseSubscriptions(utils.GetEnv("MQTT_SUBSCRIPTIONS", "sensor/{sensor_id}/{kind}"), 1)
+	if err != nil {
+		log.Fatalf("Invalid MQTT_SUBSCRIPTIONS: %v", err)
+	}
+	ingestor.SetSubscriptions(subscriptions)
+
+	// Off-the-shelf devices publish in their own formats, adapters translate them
+	if utils.GetEnvBool("ZIGBEE2MQTT_ENABLED", false) {
+		ingestor.AddAdapter(adapter.NewZigbee2MQTT(
+			utils.GetEnv("ZIGBEE2MQTT_BASE_TOPIC", "zigbee2mqtt"),
+			utils.GetEnv("ZIGBEE2MQTT_SENSOR_PREFIX", "z2m-"),
+		), envQoS("ZIGBEE2MQTT_QOS"))
+	}
+	if utils.GetEnvBool("TASMOTA_ENABLED", false) {
+		ingestor.AddAdapter(adapter.NewTasmota(
+			utils.GetEnv("TASMOTA_PREFIX", "tele"),
+			utils.GetEnv("TASMOTA_SENSOR_PREFIX", "tasmota-"),
+		), envQoS("TASMOTA_QOS"))
+	}
 
 	// Start API server
-	StartAPIServer()
+	StartAPIServer(APIDeps{
+		System:     system,
+		Ingestor:   ingestor,
+		Extractors: extractors,
+		Tokens:     tokens,
+		Troubles:   troubles,
+		Incidents:  incidents,
+		Rules:      automations,
+		Actuators:  actuators,
+		Sirens:     sirens,
+		Notifier:   notifier,
+		Reporter:   reporter,
+	})
 
 	// Get environment variables with defaults
 	broker := utils.GetEnv("MQTT_BROKER", "mqtt://localhost:1883")
@@ -65,31 +363,51 @@ func main() {
 	}
 
 	opts.SetAutoReconnect(true)
-	opts.SetDefaultPublishHandler(createMessageHandler(wsHub))
-	opts.OnConnect = connectHandler
+	// The broker publishes this for us when the connection drops unexpectedly
+	opts.SetWill(statusTopic, backendOffline, 1, true)
+	opts.SetDefaultPublishHandler(createMessageHandler(ingestor))
+	var haCommands mqtt.MessageHandler
+	if bridge != nil {
+		// Wrong PINs entered in Home Assistant lock it out like a keypad
+		lockout := auth.NewLockout(
+			utils.GetEnvInt("KEYPAD_MAX_ATTEMPTS", 5),
+			utils.GetEnvDuration("KEYPAD_LOCKOUT", 5*time.Minute),
+		)
+		haCommands = newHomeAssistantHandler(system, bridge, lockout, utils.GetEnvBool("HA_REQUIRE_CODE", true))
+	}
+	opts.OnConnect = newConnectHandler(statusTopic, ingestor.Filters(), actuators, bridge, haCommands)
 	opts.OnConnectionLost = connectLostHandler
 
 	// Create and connect the client
 	client := mqtt.NewClient(opts)
+	automations.SetPublisher(client)
+	actuators.SetPublisher(client)
+	if bridge != nil {
+		bridge.SetPublisher(client)
+	}
 	if token := client.Connect(); token.WaitTimeout(5 * time.Second) {
 		if token.Error() != nil {
 			log.Printf("Failed to connect to MQTT broker: %v", token.Error())
 			log.Println("Application will continue running without MQTT connectivity")
 		} else {
 			log.Println("Connected to MQTT broker")
-			// Subscribe to sensor topics
-			topic := "sensor/#"
-			token := client.Subscribe(topic, 1, nil)
-			token.Wait()
-			log.Printf("Subscribed to topic: %s\n", topic)
 		}
 	} else {
 		log.Printf("Failed to connect to MQTT broker: timed out")
 		log.Println("Application will continue running without MQTT connectivity")
 	}
 
-	// Keep the program running
-	select {}
+	// Keep the program running until it is stopped
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	// A clean disconnect doesn't trigger the Last Will, so say goodbye ourselves
+	log.Println("Shutting down")
+	if client.IsConnected() {
+		client.Publish(statusTopic, 1, true, backendOffline).WaitTimeout(2 * time.Second)
+		client.Disconnect(250)
+	}
 }
 
 func NewTLSConfig() *tls.Config {
@@ -131,50 +449,3 @@ func NewTLSConfig() *tls.Config {
 		Certificates: []tls.Certificate{cert},
 	}
 }
-
-func createMessageHandler(wsHub *websockets.WsHub) mqtt.MessageHandler {
-	return func(client mqtt.Client, msg mqtt.Message) {
-		topic := msg.Topic()
-		payload := msg.Payload()
-		log.Printf("Received message: %s from topic: %s\n", payload, topic)
-
-		if strings.HasPrefix(topic, "sensor/") && len(strings.Split(topic, "/")) >= 3 {
-			parts := strings.Split(topic, "/")
-			if len(parts) < 3 {
-				log.Println("Invalid topic format: " + topic)
-				return
-			}
-			sensorId := parts[1]
-
-			var dat map[string]interface{}
-			if err := json.Unmarshal(payload, &dat); err != nil {
-				log.Printf("Error unmarshalling JSON: %v\n", err)
-				return
-			}
-			// Get message timestamp
-			messageTimestamp := dat["timestamp"].(float64)
-
-			// Create a new sensor reading
-			reading := &models.SensorReading{
-				SensorID:         sensorId,
-				Value:            0, // Assuming value is 0 for alarm messages
-				Message:          string(payload),
-				Timestamp:        time.Now(),
-				MessageTimestamp: time.Unix(int64(messageTimestamp), 0),
-			}
-
-			if err := services.SensorReading.Create(reading); err != nil {
-				log.Printf("Failed to create sensor reading: %v\n", err)
-				return
-			}
-
-			wsHub.BroadcastToTopic([]byte(reading.Message), "sensor/"+sensorId)
-			wsHub.BroadcastToTopic([]byte(reading.Message), "sensors")
-
-			if match, _ := regexp.MatchString(`sensor/\w*/alarm`, topic); match {
-				wsHub.BroadcastToTopic(payload, "alerts")
-			}
-
-		}
-	}
-}
@@ -0,0 +1,126 @@
+// Command sia-receiver is a minimal SIA DC-09 receiver for developing and
+// testing the backend's alarm reporting. It prints every message and
+// acknowledges it, or rejects some of them to exercise retries.
+//
+//	go run ./cmd/sia-receiver -listen :12000 -key 000102030405060708090A0B0C0D0E0F
+package main
+
+import (
+	"backend/pkg/sia"
+	"bufio"
+	"flag"
+	"log"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	listen    = flag.String("listen", ":12000", "Address to listen on")
+	protocol  = flag.String("protocol", "tcp", "tcp or udp")
+	keyHex    = flag.String("key", "", "AES key in hex for encrypted messages")
+	nakEvery  = flag.Int("nak-every", 0, "Reply NAK to every nth message, 0 to acknowledge all")
+	dropEvery = flag.Int("drop-every", 0, "Don't reply to every nth message, 0 to reply to all")
+)
+
+var received atomic.Int64
+
+func main() {
+	flag.Parse()
+	key, err := sia.ParseKey(*keyHex)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch *protocol {
+	case "tcp":
+		listener, err := net.Listen("tcp", *listen)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Listening for SIA DC-09 on tcp %s", *listen)
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				log.Printf("Accept failed: %v", err)
+				continue
+			}
+			go serveTCP(conn, key)
+		}
+
+	case "udp":
+		conn, err := net.ListenPacket("udp", *listen)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Listening for SIA DC-09 on udp %s", *listen)
+		buf := make([]byte, 2048)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				log.Printf("Read failed: %v", err)
+				continue
+			}
+			if reply := handle(buf[:n], key); reply != nil {
+				conn.WriteTo(reply, addr)
+			}
+		}
+
+	default:
+		log.Fatalf("Unknown protocol %s", *protocol)
+	}
+}
+
+func serveTCP(conn net.Conn, key []byte) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(time.Minute))
+		frame, err := reader.ReadBytes('\r')
+		if err != nil {
+			return
+		}
+		if reply := handle(frame, key); reply != nil {
+			conn.Write(reply)
+		}
+	}
+}
+
+// handle prints a frame and returns the reply to it, nil for none
+func handle(frame []byte, key []byte) []byte {
+	n := received.Add(1)
+	msg, err := sia.Decode(frame, key)
+	if err != nil {
+		log.Printf("Invalid frame %q: %v", frame, err)
+		return encode(sia.Message{Token: sia.TokenDUH, Prefix: "0", Time: time.Now()}, nil)
+	}
+	log.Printf("#%s seq %04d %s %q (sent %s)", msg.Account, msg.Sequence, msg.Token, msg.Data, msg.Time.Format(time.TimeOnly))
+
+	if *dropEvery > 0 && n%int64(*dropEvery) == 0 {
+		log.Printf("Dropping message %d without reply", n)
+		return nil
+	}
+	if *nakEvery > 0 && n%int64(*nakEvery) == 0 {
+		log.Printf("Rejecting message %d", n)
+		return encode(sia.Message{Token: sia.TokenNAK, Prefix: "0", Time: time.Now()}, nil)
+	}
+
+	return encode(sia.Message{
+		Token:     sia.TokenACK,
+		Encrypted: msg.Encrypted,
+		Sequence:  msg.Sequence,
+		Receiver:  msg.Receiver,
+		Prefix:    msg.Prefix,
+		Account:   msg.Account,
+		Time:      time.Now(),
+	}, key)
+}
+
+func encode(msg sia.Message, key []byte) []byte {
+	frame, err := sia.Encode(msg, key)
+	if err != nil {
+		log.Printf("Failed to encode %s: %v", msg.Token, err)
+		return nil
+	}
+	return frame
+}
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS alarm_states (
+    id SERIAL PRIMARY KEY,
+    state VARCHAR(20) NOT NULL DEFAULT 'disarmed',
+    armed_mode VARCHAR(20),
+    triggered_by VARCHAR(50),
+    changed_by VARCHAR(50),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
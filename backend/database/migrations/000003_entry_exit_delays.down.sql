@@ -0,0 +1,5 @@
+ALTER TABLE alarm_states DROP COLUMN IF EXISTS delay_ends_at;
+ALTER TABLE alarm_states DROP COLUMN IF EXISTS delay_started_at;
+
+ALTER TABLE sensors DROP COLUMN IF EXISTS exit_delay;
+ALTER TABLE sensors DROP COLUMN IF EXISTS entry_delay;
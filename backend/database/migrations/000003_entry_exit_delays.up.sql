@@ -0,0 +1,5 @@
+ALTER TABLE sensors ADD COLUMN IF NOT EXISTS entry_delay INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sensors ADD COLUMN IF NOT EXISTS exit_delay INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE alarm_states ADD COLUMN IF NOT EXISTS delay_started_at TIMESTAMP;
+ALTER TABLE alarm_states ADD COLUMN IF NOT EXISTS delay_ends_at TIMESTAMP;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_alarm_states_partition_id;
+ALTER TABLE alarm_states DROP COLUMN IF EXISTS partition_id;
+
+ALTER TABLE sensors DROP COLUMN IF EXISTS zone_id;
+
+DROP TABLE IF EXISTS zones;
+DROP TABLE IF EXISTS partitions;
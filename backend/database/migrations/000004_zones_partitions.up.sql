@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS partitions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+-- The default partition takes over the state of the single panel
+INSERT INTO partitions (id, name, description) VALUES (1, 'house', 'Default partition')
+ON CONFLICT DO NOTHING;
+SELECT setval('partitions_id_seq', (SELECT MAX(id) FROM partitions));
+
+CREATE TABLE IF NOT EXISTS zones (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    description TEXT,
+    partition_id INTEGER NOT NULL REFERENCES partitions(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_zones_partition_id ON zones(partition_id);
+
+ALTER TABLE sensors ADD COLUMN IF NOT EXISTS zone_id INTEGER REFERENCES zones(id);
+
+ALTER TABLE alarm_states ADD COLUMN IF NOT EXISTS partition_id INTEGER REFERENCES partitions(id);
+UPDATE alarm_states SET partition_id = 1 WHERE partition_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alarm_states_partition_id ON alarm_states(partition_id);
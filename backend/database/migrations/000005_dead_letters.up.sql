@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id SERIAL PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    payload TEXT,
+    reason VARCHAR(50) NOT NULL,
+    error TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 1,
+    received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    reprocessed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_dead_letters_topic ON dead_letters(topic);
+CREATE INDEX IF NOT EXISTS idx_dead_letters_reason ON dead_letters(reason);
+CREATE INDEX IF NOT EXISTS idx_dead_letters_status ON dead_letters(status);
+CREATE INDEX IF NOT EXISTS idx_dead_letters_received_at ON dead_letters(received_at);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS value_extractions;
+
+DROP INDEX IF EXISTS idx_sensor_readings_sensor_metric_timestamp;
+ALTER TABLE sensor_readings DROP COLUMN IF EXISTS unit;
+ALTER TABLE sensor_readings DROP COLUMN IF EXISTS metric;
@@ -0,0 +1,22 @@
+ALTER TABLE sensor_readings ADD COLUMN IF NOT EXISTS metric VARCHAR(50);
+ALTER TABLE sensor_readings ADD COLUMN IF NOT EXISTS unit VARCHAR(20);
+ALTER TABLE sensor_readings ADD COLUMN IF NOT EXISTS message_timestamp TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_sensor_readings_sensor_metric_timestamp ON sensor_readings(sensor_id, metric, timestamp);
+
+CREATE TABLE IF NOT EXISTS value_extractions (
+    id SERIAL PRIMARY KEY,
+    sensor_type VARCHAR(50) NOT NULL DEFAULT '',
+    kind VARCHAR(50) NOT NULL,
+    metric VARCHAR(50) NOT NULL,
+    value_path VARCHAR(255) NOT NULL,
+    unit_path VARCHAR(255),
+    unit VARCHAR(20),
+    scale FLOAT NOT NULL DEFAULT 0,
+    "offset" FLOAT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_value_extractions_type_kind ON value_extractions(sensor_type, kind);
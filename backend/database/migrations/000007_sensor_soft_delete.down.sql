@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_sensors_sensor_id;
+DROP INDEX IF EXISTS idx_sensors_deleted_at;
+
+ALTER TABLE sensors DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE sensors DROP COLUMN IF EXISTS updated_at;
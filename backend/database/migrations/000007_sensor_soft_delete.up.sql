@@ -0,0 +1,6 @@
+-- Columns of gorm.Model that the initial schema did not declare
+ALTER TABLE sensors ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE sensors ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_sensors_deleted_at ON sensors(deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sensors_sensor_id ON sensors(sensor_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_sensors_status;
+
+ALTER TABLE sensors DROP COLUMN IF EXISTS status;
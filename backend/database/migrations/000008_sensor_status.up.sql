@@ -0,0 +1,3 @@
+ALTER TABLE sensors ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active';
+
+CREATE INDEX IF NOT EXISTS idx_sensors_status ON sensors(status);
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS access_until;
+ALTER TABLE users DROP COLUMN IF EXISTS access_from;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'resident';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS access_from TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS access_until TIMESTAMP;
+
+-- Everyone had full access before roles existed
+UPDATE users SET role = 'admin';
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS duress_pin_hash;
+ALTER TABLE users DROP COLUMN IF EXISTS pin_hash;
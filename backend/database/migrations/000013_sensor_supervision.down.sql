@@ -0,0 +1,2 @@
+ALTER TABLE sensors DROP COLUMN IF EXISTS online;
+ALTER TABLE sensors DROP COLUMN IF EXISTS last_seen_at;
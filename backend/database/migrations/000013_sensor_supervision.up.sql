@@ -0,0 +1,2 @@
+ALTER TABLE sensors ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP;
+ALTER TABLE sensors ADD COLUMN IF NOT EXISTS online BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS troubles (
+    id SERIAL PRIMARY KEY,
+    sensor_id VARCHAR(50) NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    message TEXT,
+    raised_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    cleared_at TIMESTAMP,
+    acknowledged_at TIMESTAMP,
+    acknowledged_by VARCHAR(255)
+);
+
+CREATE INDEX IF NOT EXISTS idx_troubles_sensor_id ON troubles(sensor_id);
+CREATE INDEX IF NOT EXISTS idx_troubles_type ON troubles(type);
+CREATE INDEX IF NOT EXISTS idx_troubles_raised_at ON troubles(raised_at);
+CREATE INDEX IF NOT EXISTS idx_troubles_cleared_at ON troubles(cleared_at);
+
+-- A sensor has at most one active trouble of each type
+CREATE UNIQUE INDEX IF NOT EXISTS idx_troubles_active ON troubles(sensor_id, type) WHERE cleared_at IS NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS incident_events;
+DROP TABLE IF EXISTS incidents;
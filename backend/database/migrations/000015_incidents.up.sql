@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS incidents (
+    id SERIAL PRIMARY KEY,
+    partition_id INTEGER NOT NULL, -- no foreign key, incidents outlive deleted partitions
+    state VARCHAR(20) NOT NULL DEFAULT 'open',
+    sensor_id VARCHAR(50),
+    message TEXT,
+    alarm_count INTEGER NOT NULL DEFAULT 0,
+    assignee VARCHAR(255),
+    opened_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    acknowledged_at TIMESTAMP,
+    acknowledged_by VARCHAR(255),
+    resolved_at TIMESTAMP,
+    resolved_by VARCHAR(255),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_partition_id ON incidents(partition_id);
+CREATE INDEX IF NOT EXISTS idx_incidents_state ON incidents(state);
+CREATE INDEX IF NOT EXISTS idx_incidents_opened_at ON incidents(opened_at);
+
+CREATE TABLE IF NOT EXISTS incident_events (
+    id SERIAL PRIMARY KEY,
+    incident_id INTEGER NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL,
+    actor VARCHAR(255),
+    sensor_reading_id INTEGER REFERENCES sensor_readings(id),
+    message TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_events_incident_id ON incident_events(incident_id);
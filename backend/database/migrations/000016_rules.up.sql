@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    sensor_id VARCHAR(50),
+    sensor_type VARCHAR(50),
+    zone_id INTEGER REFERENCES zones(id),
+    kind VARCHAR(50),
+    operator VARCHAR(10),
+    threshold DOUBLE PRECISION NOT NULL DEFAULT 0,
+    time_from VARCHAR(5),
+    time_until VARCHAR(5),
+    arm_state VARCHAR(20),
+    cooldown INTEGER NOT NULL DEFAULT 0,
+    action VARCHAR(20) NOT NULL,
+    topic VARCHAR(255),
+    payload TEXT,
+    qos SMALLINT NOT NULL DEFAULT 0,
+    arm_mode VARCHAR(20),
+    partition_id INTEGER,
+    last_fired_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_rules_deleted_at ON rules(deleted_at);
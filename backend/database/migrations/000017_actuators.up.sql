@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS actuators (
+    id SERIAL PRIMARY KEY,
+    actuator_id VARCHAR(50) NOT NULL,
+    name VARCHAR(100),
+    type VARCHAR(50),
+    description TEXT,
+    location VARCHAR(100),
+    zone_id INTEGER REFERENCES zones(id),
+    qos SMALLINT NOT NULL DEFAULT 1,
+    state VARCHAR(100),
+    state_updated_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_actuators_actuator_id ON actuators(actuator_id);
+CREATE INDEX IF NOT EXISTS idx_actuators_deleted_at ON actuators(deleted_at);
+
+CREATE TABLE IF NOT EXISTS actuator_commands (
+    id SERIAL PRIMARY KEY,
+    command_id VARCHAR(32) NOT NULL,
+    actuator_id VARCHAR(50) NOT NULL,
+    payload TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    requested_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP,
+    acknowledged_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_actuator_commands_command_id ON actuator_commands(command_id);
+CREATE INDEX IF NOT EXISTS idx_actuator_commands_actuator_id ON actuator_commands(actuator_id);
+CREATE INDEX IF NOT EXISTS idx_actuator_commands_status ON actuator_commands(status);
+CREATE INDEX IF NOT EXISTS idx_actuator_commands_created_at ON actuator_commands(created_at);
@@ -0,0 +1,6 @@
+ALTER TABLE incidents DROP COLUMN IF EXISTS escalated_at;
+ALTER TABLE incidents DROP COLUMN IF EXISTS escalation_step;
+ALTER TABLE incidents DROP COLUMN IF EXISTS escalation_policy_id;
+
+DROP TABLE IF EXISTS escalation_steps;
+DROP TABLE IF EXISTS escalation_policies;
@@ -0,0 +1,31 @@
+-- No foreign key on partition_id, partitions are hard deleted
+CREATE TABLE IF NOT EXISTS escalation_policies (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    partition_id INTEGER,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_escalation_policies_name ON escalation_policies(name);
+CREATE INDEX IF NOT EXISTS idx_escalation_policies_partition_id ON escalation_policies(partition_id);
+
+-- No foreign key on user_id, a step whose user was deleted is skipped
+CREATE TABLE IF NOT EXISTS escalation_steps (
+    id SERIAL PRIMARY KEY,
+    policy_id INTEGER NOT NULL REFERENCES escalation_policies(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    delay INTEGER NOT NULL DEFAULT 0,
+    target VARCHAR(20) NOT NULL,
+    user_id INTEGER,
+    name VARCHAR(100),
+    channel VARCHAR(20),
+    address VARCHAR(255)
+);
+
+CREATE INDEX IF NOT EXISTS idx_escalation_steps_policy_id ON escalation_steps(policy_id);
+
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS escalation_policy_id INTEGER;
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS escalation_step INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS escalated_at TIMESTAMP;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS alarm_reports (
+    id SERIAL PRIMARY KEY,
+    token VARCHAR(10) NOT NULL,
+    sequence INTEGER NOT NULL,
+    account VARCHAR(16) NOT NULL,
+    code VARCHAR(10) NOT NULL,
+    description TEXT,
+    partition_id INTEGER NOT NULL DEFAULT 0,
+    zone INTEGER NOT NULL DEFAULT 0,
+    data TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP,
+    acknowledged_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_alarm_reports_status ON alarm_reports(status);
+CREATE INDEX IF NOT EXISTS idx_alarm_reports_created_at ON alarm_reports(created_at);
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Actuator types the backend drives itself, any other type can be registered too
+const (
+	ActuatorTypeSiren = "siren"
+	ActuatorTypeLock  = "lock"
+	ActuatorTypeLight = "light"
+	ActuatorTypeValve = "valve"
+)
+
+// Actuator is a device the backend sends commands to on actuator/<id>/set
+type Actuator struct {
+	gorm.Model
+	ActuatorID     string     `json:"actuator_id" gorm:"uniqueIndex" db:"actuator_id"`
+	Name           string     `json:"name" db:"name"`
+	Type           string     `json:"type" db:"type"`
+	Description    string     `json:"description" db:"description"`
+	Location       string     `json:"location" db:"location"`
+	ZoneID         *uint      `json:"zone_id" db:"zone_id"`                   // nil for actuators in the default partition without a zone
+	QoS            byte       `json:"qos" db:"qos"`                           // QoS commands are published with
+	State          string     `json:"state" db:"state"`                       // Last state the device reported, empty if it never did
+	StateUpdatedAt *time.Time `json:"state_updated_at" db:"state_updated_at"` // When State was reported
+}
+
+// Actuator command statuses
+const (
+	CommandPending      = "pending"      // Not published yet
+	CommandSent         = "sent"         // Published, waiting for the device to acknowledge
+	CommandAcknowledged = "acknowledged" // The device carried it out
+	CommandFailed       = "failed"       // The device reported an error, or the backend gave up
+	CommandTimedOut     = "timed_out"    // Not acknowledged after all retries
+)
+
+// ActuatorCommand is a command sent to an actuator and its delivery state
+type ActuatorCommand struct {
+	ID             uint       `json:"id" gorm:"primaryKey" db:"id"`
+	CommandID      string     `json:"command_id" gorm:"uniqueIndex" db:"command_id"` // Sent with the command, the device echoes it in its ack
+	ActuatorID     string     `json:"actuator_id" gorm:"index" db:"actuator_id"`
+	Payload        string     `json:"payload" db:"payload"` // JSON published to the device
+	Status         string     `json:"status" gorm:"index" db:"status"`
+	Attempts       int        `json:"attempts" db:"attempts"`
+	Error          string     `json:"error" db:"error"`
+	RequestedBy    string     `json:"requested_by" db:"requested_by"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"index" db:"created_at"`
+	SentAt         *time.Time `json:"sent_at" db:"sent_at"` // Last time it was published
+	AcknowledgedAt *time.Time `json:"acknowledged_at" db:"acknowledged_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
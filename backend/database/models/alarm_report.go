@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Alarm report statuses
+const (
+	ReportPending      = "pending" // Queued until the receiver acknowledges it
+	ReportAcknowledged = "acknowledged"
+	ReportExpired      = "expired" // Not acknowledged in time, given up on
+)
+
+// AlarmReport is an event reported to the alarm receiving centre of the
+// monitoring station
+type AlarmReport struct {
+	ID             uint       `json:"id" gorm:"primaryKey" db:"id"`
+	Token          string     `json:"token" db:"token"`       // SIA-DCS or ADM-CID
+	Sequence       int        `json:"sequence" db:"sequence"` // DC-09 sequence number, kept for retransmissions
+	Account        string     `json:"account" db:"account"`
+	Code           string     `json:"code" db:"code"`                 // Event code, e.g. BA or 1130
+	Description    string     `json:"description" db:"description"`   // What happened, for people reading the log
+	PartitionID    uint       `json:"partition_id" db:"partition_id"` // Reported as the area/group
+	Zone           int        `json:"zone" db:"zone"`                 // Zone or user number
+	Data           string     `json:"data" db:"data"`                 // Message data as sent
+	Status         string     `json:"status" gorm:"index" db:"status"`
+	Attempts       int        `json:"attempts" db:"attempts"`
+	Error          string     `json:"error" db:"error"` // Error of the last attempt
+	CreatedAt      time.Time  `json:"created_at" gorm:"index" db:"created_at"`
+	SentAt         *time.Time `json:"sent_at" db:"sent_at"` // Last attempt
+	AcknowledgedAt *time.Time `json:"acknowledged_at" db:"acknowledged_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// AlarmState is the persisted state of a partition's alarm panel. There is
+// one row per partition which is updated on every transition so the panel
+// can be restored after a restart.
+type AlarmState struct {
+	ID             uint       `json:"-" gorm:"primaryKey" db:"id"`
+	PartitionID    uint       `json:"partition_id" gorm:"uniqueIndex" db:"partition_id"`
+	State          string     `json:"state" db:"state"`
+	ArmedMode      string     `json:"armed_mode" db:"armed_mode"`     // Mode the panel is (being) armed in, kept while triggered
+	TriggeredBy    string     `json:"triggered_by" db:"triggered_by"` // Sensor that caused the last alarm or entry delay
+	ChangedBy      string     `json:"changed_by" db:"changed_by"`
+	DelayStartedAt *time.Time `json:"delay_started_at" db:"delay_started_at"` // Start of the running entry/exit delay
+	DelayEndsAt    *time.Time `json:"delay_ends_at" db:"delay_ends_at"`       // Deadline of the running entry/exit delay
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// AuditEntry is one record of the append-only audit log. Every entry holds
+// the hash of the previous one, so changing or deleting an entry breaks the
+// chain from there on.
+type AuditEntry struct {
+	ID         uint      `json:"id" gorm:"primaryKey" db:"id"`
+	Timestamp  time.Time `json:"timestamp" gorm:"index" db:"timestamp"`
+	Actor      string    `json:"actor" gorm:"index" db:"actor"` // Username, or what was entered for failed logins
+	SourceIP   string    `json:"source_ip" db:"source_ip"`
+	Action     string    `json:"action" gorm:"index" db:"action"`     // e.g. arm, login_failed, update
+	Resource   string    `json:"resource" gorm:"index" db:"resource"` // e.g. alarm, sensor, user
+	ResourceID string    `json:"resource_id" db:"resource_id"`
+	Before     string    `json:"before" db:"before"` // JSON of the value before the change, empty if there was none
+	After      string    `json:"after" db:"after"`   // JSON of the value after the change, empty if there is none
+	PrevHash   string    `json:"prev_hash" db:"prev_hash"`
+	Hash       string    `json:"hash" gorm:"uniqueIndex" db:"hash"` // SHA-256 over PrevHash and the fields above
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Dead letter statuses
+const (
+	DeadLetterPending     = "pending"
+	DeadLetterReprocessed = "reprocessed"
+)
+
+// DeadLetter is an MQTT message that was rejected by the message handler,
+// kept so it can be inspected and re-processed later
+type DeadLetter struct {
+	ID            uint       `json:"id" gorm:"primaryKey" db:"id"`
+	Topic         string     `json:"topic" gorm:"index" db:"topic"`
+	Payload       string     `json:"payload" db:"payload"`
+	Reason        string     `json:"reason" gorm:"index" db:"reason"` // Machine readable rejection category
+	Error         string     `json:"error" db:"error"`                // Error of the last processing attempt
+	Status        string     `json:"status" gorm:"index" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	ReceivedAt    time.Time  `json:"received_at" gorm:"index" db:"received_at"`
+	ReprocessedAt *time.Time `json:"reprocessed_at" db:"reprocessed_at"`
+}
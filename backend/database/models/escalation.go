@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Escalation step targets
+const (
+	EscalationTargetUser    = "user"    // A user, notified over their enabled notification preferences
+	EscalationTargetContact = "contact" // Someone without an account, e.g. a neighbour or a monitoring service
+)
+
+// EscalationPolicy is the chain of people notified, one after another, while
+// an incident stays unacknowledged
+type EscalationPolicy struct {
+	ID          uint             `json:"id" gorm:"primaryKey" db:"id"`
+	Name        string           `json:"name" gorm:"uniqueIndex" db:"name"`
+	PartitionID *uint            `json:"partition_id" gorm:"index" db:"partition_id"` // nil for incidents of partitions without their own policy
+	Enabled     bool             `json:"enabled" db:"enabled"`
+	Steps       []EscalationStep `json:"steps" gorm:"foreignKey:PolicyID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// EscalationStep is one link of an escalation chain
+type EscalationStep struct {
+	ID       uint   `json:"id" gorm:"primaryKey" db:"id"`
+	PolicyID uint   `json:"policy_id" gorm:"index" db:"policy_id"`
+	Position int    `json:"position" db:"position"` // Order in the chain, starting at 0
+	Delay    int    `json:"delay" db:"delay"`       // Seconds to wait after the previous step, or after the incident opened
+	Target   string `json:"target" db:"target"`
+	UserID   *uint  `json:"user_id" db:"user_id"` // Set for user targets
+	Name     string `json:"name" db:"name"`       // Who the contact is, e.g. "Neighbour"
+	Channel  string `json:"channel" db:"channel"` // Set for contact targets
+	Address  string `json:"address" db:"address"`
+}
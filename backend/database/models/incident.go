@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Incident states
+const (
+	IncidentOpen         = "open"
+	IncidentAcknowledged = "acknowledged"
+	IncidentResolved     = "resolved"
+	IncidentFalseAlarm   = "false_alarm"
+)
+
+// Incident event types
+const (
+	IncidentEventAlarm    = "alarm"    // An alarm was raised while the incident was open
+	IncidentEventState    = "state"    // The incident changed state
+	IncidentEventAssigned = "assigned" // The assignee changed
+	IncidentEventNote     = "note"     // Someone added a note
+	IncidentEventEscalate = "escalate" // A step of the escalation chain was notified
+)
+
+// Incident groups the alarms a partition raised until someone handled them
+type Incident struct {
+	ID             uint       `json:"id" gorm:"primaryKey" db:"id"`
+	PartitionID    uint       `json:"partition_id" gorm:"index" db:"partition_id"`
+	State          string     `json:"state" gorm:"index" db:"state"`
+	SensorID       string     `json:"sensor_id" db:"sensor_id"` // Sensor that raised the first alarm
+	Message        string     `json:"message" db:"message"`
+	AlarmCount     int        `json:"alarm_count" db:"alarm_count"`
+	Assignee       string     `json:"assignee" db:"assignee"` // Username of the user handling it, empty if nobody
+	OpenedAt       time.Time  `json:"opened_at" gorm:"index" db:"opened_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at" db:"acknowledged_at"`
+	AcknowledgedBy string     `json:"acknowledged_by" db:"acknowledged_by"`
+	ResolvedAt     *time.Time `json:"resolved_at" db:"resolved_at"` // Set for resolved and false alarm incidents
+	ResolvedBy     string     `json:"resolved_by" db:"resolved_by"`
+
+	EscalationPolicyID *uint      `json:"escalation_policy_id" db:"escalation_policy_id"` // Policy escalating the incident, nil if none
+	EscalationStep     int        `json:"escalation_step" db:"escalation_step"`           // Steps of the chain done so far
+	EscalatedAt        *time.Time `json:"escalated_at" db:"escalated_at"`                 // When the last step was done
+	UpdatedAt          time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IncidentEvent is one entry of an incident's timeline
+type IncidentEvent struct {
+	ID              uint      `json:"id" gorm:"primaryKey" db:"id"`
+	IncidentID      uint      `json:"incident_id" gorm:"index" db:"incident_id"`
+	Type            string    `json:"type" db:"type"`
+	Actor           string    `json:"actor" db:"actor"` // Username, or the sensor for alarms
+	SensorReadingID *int      `json:"sensor_reading_id" db:"sensor_reading_id"`
+	Message         string    `json:"message" db:"message"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
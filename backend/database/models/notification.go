@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Notification events users can subscribe to
+const (
+	NotifyEventAlarm    = "alarm"     // A partition's alarm was triggered
+	NotifyEventArmState = "arm_state" // A partition was armed or disarmed
+	NotifyEventTrouble  = "trouble"   // A trouble was raised
+	NotifyEventRule     = "rule"      // A rule with the notify action fired
+	NotifyEventTest     = "test"      // Sent on request to check the settings
+
+	// NotifyEventEscalation goes to the targets of escalation steps, not to subscribers
+	NotifyEventEscalation = "escalation"
+)
+
+// Notification channels
+const (
+	NotifyChannelWebhook = "webhook" // Address is the URL the notification is POSTed to
+	NotifyChannelEmail   = "email"   // Address is an email address
+	NotifyChannelNtfy    = "ntfy"    // Address is an ntfy topic or topic URL
+)
+
+// Notification delivery statuses
+const (
+	DeliveryPending = "pending" // Waiting for the first or another attempt
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed" // Gave up after the last attempt
+)
+
+// NotificationPreference is where and for which events a user wants to be notified
+type NotificationPreference struct {
+	ID        uint      `json:"id" gorm:"primaryKey" db:"id"`
+	UserID    uint      `json:"user_id" gorm:"index" db:"user_id"`
+	Channel   string    `json:"channel" db:"channel"`
+	Address   string    `json:"address" db:"address"`
+	Events    string    `json:"events" db:"events"` // Comma separated events, empty for all
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// NotificationTemplate overrides the built-in title and body of an event.
+// Both are Go text/template strings.
+type NotificationTemplate struct {
+	ID        uint      `json:"id" gorm:"primaryKey" db:"id"`
+	Event     string    `json:"event" gorm:"uniqueIndex" db:"event"`
+	Title     string    `json:"title" db:"title"`
+	Body      string    `json:"body" db:"body"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// NotificationDelivery is one notification sent, or being sent, to one address
+type NotificationDelivery struct {
+	ID        uint       `json:"id" gorm:"primaryKey" db:"id"`
+	UserID    uint       `json:"user_id" gorm:"index" db:"user_id"`
+	Username  string     `json:"username" db:"username"` // Kept for the log after the user is deleted
+	Event     string     `json:"event" gorm:"index" db:"event"`
+	Channel   string     `json:"channel" db:"channel"`
+	Address   string     `json:"address" db:"address"`
+	Title     string     `json:"title" db:"title"`
+	Body      string     `json:"body" db:"body"`
+	Status    string     `json:"status" gorm:"index" db:"status"`
+	Attempts  int        `json:"attempts" db:"attempts"`
+	Error     string     `json:"error" db:"error"` // Error of the last attempt
+	CreatedAt time.Time  `json:"created_at" gorm:"index" db:"created_at"`
+	SentAt    *time.Time `json:"sent_at" db:"sent_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Partition is an independently armed area of the premises, e.g. house or garage
+type Partition struct {
+	gorm.Model
+	Name        string `json:"name" gorm:"uniqueIndex" db:"name"`
+	Description string `json:"description" db:"description"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Rule actions
+const (
+	RuleActionBroadcast   = "broadcast"    // Push the payload to a WebSocket topic
+	RuleActionMQTTPublish = "mqtt_publish" // Publish the payload to an MQTT topic
+	RuleActionNotify      = "notify"       // Send the payload as a notification
+	RuleActionArm         = "arm"          // Arm the partition in ArmMode
+	RuleActionDisarm      = "disarm"       // Disarm the partition
+	RuleActionAlarm       = "alarm"        // Raise the alarm in the partition
+)
+
+// Rule is a user defined automation. When a sensor message matches all of
+// its conditions the action is executed. Empty conditions match everything.
+type Rule struct {
+	gorm.Model
+	Name    string `json:"name" db:"name"`
+	Enabled bool   `json:"enabled" db:"enabled"`
+
+	SensorID   string  `json:"sensor_id" db:"sensor_id"`
+	SensorType string  `json:"sensor_type" db:"sensor_type"`
+	ZoneID     *uint   `json:"zone_id" db:"zone_id"`
+	Kind       string  `json:"kind" db:"kind"`           // Message kind, the last topic segment, e.g. alarm or water
+	Operator   string  `json:"operator" db:"operator"`   // Compares the extracted value to Threshold: gt, gte, lt, lte, eq or ne
+	Threshold  float64 `json:"threshold" db:"threshold"` // Only used with an Operator
+	TimeFrom   string  `json:"time_from" db:"time_from"` // HH:MM local time, the window wraps past midnight if TimeUntil is earlier
+	TimeUntil  string  `json:"time_until" db:"time_until"`
+	ArmState   string  `json:"arm_state" db:"arm_state"` // State of the sensor's partition, e.g. armed_home
+	Cooldown   int     `json:"cooldown" db:"cooldown"`   // Seconds after firing during which the rule doesn't fire again
+
+	Action      string     `json:"action" db:"action"`
+	Topic       string     `json:"topic" db:"topic"`               // WebSocket topic below rules/ for broadcast, MQTT topic for mqtt_publish
+	Payload     string     `json:"payload" db:"payload"`           // Template with {sensor_id}, {kind}, {value}, {unit} and {message}, empty sends the sensor message
+	QoS         byte       `json:"qos" db:"qos"`                   // For mqtt_publish
+	ArmMode     string     `json:"arm_mode" db:"arm_mode"`         // armed_home or armed_away for arm
+	PartitionID *uint      `json:"partition_id" db:"partition_id"` // Partition arm, disarm and alarm act on, nil for the sensor's partition
+	LastFiredAt *time.Time `json:"last_fired_at" db:"last_fired_at"`
+}
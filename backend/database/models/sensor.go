@@ -1,14 +1,30 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// Sensor statuses. Unknown sensors are registered as pending until an admin
+// approves, rejects or blocks them.
+const (
+	SensorStatusActive  = "active"
+	SensorStatusPending = "pending"
+	SensorStatusBlocked = "blocked"
+)
+
 type Sensor struct {
-    gorm.Model
-    SensorID    string    `json:"sensor_id" db:"sensor_id"`
-    Name        string    `json:"name" db:"name"`
-    Type        string    `json:"type" db:"type"`
-    Description string    `json:"description" db:"description"`
-    Location    string    `json:"location" db:"location"`
-}
\ No newline at end of file
+	gorm.Model
+	SensorID    string     `json:"sensor_id" gorm:"uniqueIndex" db:"sensor_id"`
+	Name        string     `json:"name" db:"name"`
+	Type        string     `json:"type" db:"type"`
+	Description string     `json:"description" db:"description"`
+	Location    string     `json:"location" db:"location"`
+	EntryDelay  int        `json:"entry_delay" db:"entry_delay"` // Seconds to disarm after this sensor opens, 0 = instant
+	ExitDelay   int        `json:"exit_delay" db:"exit_delay"`   // Seconds after arming during which this sensor is ignored
+	ZoneID      *uint      `json:"zone_id" db:"zone_id"`         // nil for sensors in the default partition without a zone
+	Status      string     `json:"status" gorm:"default:active" db:"status"`
+	LastSeenAt  *time.Time `json:"last_seen_at" db:"last_seen_at"` // Time of the last message, nil if never seen
+	Online      bool       `json:"online" db:"online"`             // False once supervision hasn't heard from the sensor in time
+}
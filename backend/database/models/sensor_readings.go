@@ -5,7 +5,9 @@ import "time"
 type SensorReading struct {
 	ID               int       `json:"id" db:"id"`
 	SensorID         string    `json:"sensor_id" db:"sensor_id"`
+	Metric           string    `json:"metric" db:"metric"` // e.g. temperature, empty for messages without a numeric value
 	Value            float64   `json:"value" db:"value"`
+	Unit             string    `json:"unit" db:"unit"`
 	Message          string    `json:"message" db:"message"`
 	Timestamp        time.Time `json:"timestamp" db:"timestamp"`
 	MessageTimestamp time.Time `json:"message_timestamp" db:"message_timestamp"`
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Trouble types
+const (
+	TroubleTamper      = "tamper"
+	TroubleLowBattery  = "low_battery"
+	TroubleSupervision = "supervision_failure"
+)
+
+// Trouble is a fault condition of a sensor that isn't an alarm, like an
+// opened tamper switch. It is active until ClearedAt is set.
+type Trouble struct {
+	ID             uint       `json:"id" gorm:"primaryKey" db:"id"`
+	SensorID       string     `json:"sensor_id" gorm:"index" db:"sensor_id"`
+	Type           string     `json:"type" gorm:"index" db:"type"`
+	Message        string     `json:"message" db:"message"`
+	RaisedAt       time.Time  `json:"raised_at" gorm:"index" db:"raised_at"`
+	ClearedAt      *time.Time `json:"cleared_at" gorm:"index" db:"cleared_at"` // nil while the trouble is active
+	AcknowledgedAt *time.Time `json:"acknowledged_at" db:"acknowledged_at"`
+	AcknowledgedBy string     `json:"acknowledged_by" db:"acknowledged_by"`
+}
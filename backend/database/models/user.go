@@ -1,11 +1,18 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type User struct {
-    gorm.Model
-    Username  string    `json:"username" db:"username"`
-    Password  string    `json:"-" db:"password"`          // Don't expose in JSON
-}
\ No newline at end of file
+	gorm.Model
+	Username      string     `json:"username" gorm:"uniqueIndex" db:"username"`
+	Password      string     `json:"-" db:"password"` // bcrypt hash, don't expose in JSON
+	Role          string     `json:"role" gorm:"default:resident" db:"role"`
+	AccessFrom    *time.Time `json:"access_from" db:"access_from"`   // Start of the access window, nil = no start
+	AccessUntil   *time.Time `json:"access_until" db:"access_until"` // End of the access window, nil = no end
+	PinHash       string     `json:"-" db:"pin_hash"`                // bcrypt hash of the keypad PIN, empty = no PIN
+	DuressPinHash string     `json:"-" db:"duress_pin_hash"`         // bcrypt hash of the duress PIN, empty = none
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// ValueExtraction configures how the numeric value of a message is read
+// from its payload for sensors of a given type
+type ValueExtraction struct {
+	gorm.Model
+	SensorType string  `json:"sensor_type" gorm:"uniqueIndex:idx_value_extractions_type_kind" db:"sensor_type"` // Empty matches every sensor type
+	Kind       string  `json:"kind" gorm:"uniqueIndex:idx_value_extractions_type_kind" db:"kind"`               // Message kind, the last topic segment
+	Metric     string  `json:"metric" db:"metric"`
+	ValuePath  string  `json:"value_path" db:"value_path"` // Dotted path into the JSON payload, e.g. data.temperature
+	UnitPath   string  `json:"unit_path" db:"unit_path"`   // Optional path of the unit in the payload
+	Unit       string  `json:"unit" db:"unit"`             // Unit used when the payload doesn't carry one
+	Scale      float64 `json:"scale" db:"scale"`           // Multiplier applied to the raw value, 0 means 1
+	Offset     float64 `json:"offset" db:"offset"`         // Added after scaling
+}
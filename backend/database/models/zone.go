@@ -0,0 +1,14 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Zone groups sensors of a partition that share the same alarm rules
+type Zone struct {
+	gorm.Model
+	Name        string `json:"name" db:"name"`
+	Type        string `json:"type" db:"type"` // perimeter, interior, 24h_fire, 24h_water or panic
+	Description string `json:"description" db:"description"`
+	PartitionID uint   `json:"partition_id" db:"partition_id"`
+}
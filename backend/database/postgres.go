@@ -85,7 +85,29 @@ func InitDb() error {
 	}
 	db = d
 
-	err = db.AutoMigrate(&models.Sensor{}, &models.User{}, &models.SensorReading{})
+	err = db.AutoMigrate(
+		&models.Partition{},
+		&models.Zone{},
+		&models.Sensor{},
+		&models.User{},
+		&models.SensorReading{},
+		&models.AlarmState{},
+		&models.DeadLetter{},
+		&models.ValueExtraction{},
+		&models.AuditEntry{},
+		&models.Trouble{},
+		&models.Incident{},
+		&models.IncidentEvent{},
+		&models.Rule{},
+		&models.Actuator{},
+		&models.ActuatorCommand{},
+		&models.NotificationPreference{},
+		&models.NotificationTemplate{},
+		&models.NotificationDelivery{},
+		&models.EscalationPolicy{},
+		&models.EscalationStep{},
+		&models.AlarmReport{},
+	)
 	if err != nil {
 		fmt.Printf("Failed to auto migrate models: %v\n", err)
 		return err
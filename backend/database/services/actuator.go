@@ -0,0 +1,143 @@
+package services
+
+import (
+	postgres "backend/database"
+	"backend/database/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ActuatorService struct{}
+
+var Actuator = ActuatorService{}
+
+// ActuatorFilter narrows down ActuatorService.GetPaginated, zero values match everything
+type ActuatorFilter struct {
+	Type     string
+	Location string
+	ZoneID   uint
+}
+
+// GetByActuatorID returns the actuator with the given actuator_id, or nil if it is not registered
+func (s ActuatorService) GetByActuatorID(actuatorID string) (*models.Actuator, error) {
+	var actuator models.Actuator
+	err := postgres.DB().Where("actuator_id = ?", actuatorID).First(&actuator).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch actuator %s: %w", actuatorID, err)
+	}
+	return &actuator, nil
+}
+
+// ListInPartition returns the actuators of a type in a partition. Actuators
+// without a zone belong to the default partition.
+func (s ActuatorService) ListInPartition(actuatorType string, partitionID uint, isDefault bool) ([]models.Actuator, error) {
+	query := postgres.DB().
+		Joins("LEFT JOIN zones ON zones.id = actuators.zone_id AND zones.deleted_at IS NULL").
+		Where("actuators.type = ?", actuatorType)
+	if isDefault {
+		query = query.Where("zones.partition_id = ? OR actuators.zone_id IS NULL", partitionID)
+	} else {
+		query = query.Where("zones.partition_id = ?", partitionID)
+	}
+
+	var actuators []models.Actuator
+	if err := query.Order("actuators.actuator_id").Find(&actuators).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch %s actuators of partition %d: %w", actuatorType, partitionID, err)
+	}
+	return actuators, nil
+}
+
+func (s ActuatorService) GetPaginated(filter ActuatorFilter, page, pageSize int) ([]models.Actuator, int64, error) {
+	var actuators []models.Actuator
+	var totalCount int64
+
+	query := postgres.DB().Model(&models.Actuator{})
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Location != "" {
+		query = query.Where("location = ?", filter.Location)
+	}
+	if filter.ZoneID != 0 {
+		query = query.Where("zone_id = ?", filter.ZoneID)
+	}
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count actuators: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("actuator_id").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&actuators).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch actuators: %w", err)
+	}
+
+	return actuators, totalCount, nil
+}
+
+// Create registers a new actuator. A soft deleted actuator with the same
+// actuator_id is restored with the new details, since its commands still
+// reference it.
+func (s ActuatorService) Create(actuator *models.Actuator) error {
+	db := postgres.DB()
+
+	var existing models.Actuator
+	err := db.Unscoped().Where("actuator_id = ?", actuator.ActuatorID).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check actuator %s: %w", actuator.ActuatorID, err)
+	}
+	if err == nil {
+		if !existing.DeletedAt.Valid {
+			return ErrDuplicate
+		}
+		actuator.ID = existing.ID
+		actuator.CreatedAt = existing.CreatedAt
+		actuator.DeletedAt = gorm.DeletedAt{}
+		if err := db.Unscoped().Save(actuator).Error; err != nil {
+			return fmt.Errorf("failed to restore actuator %s: %w", actuator.ActuatorID, err)
+		}
+		return nil
+	}
+
+	if err := db.Create(actuator).Error; err != nil {
+		return fmt.Errorf("failed to create actuator %s: %w", actuator.ActuatorID, err)
+	}
+	return nil
+}
+
+func (s ActuatorService) Update(actuator *models.Actuator) error {
+	if err := postgres.DB().Save(actuator).Error; err != nil {
+		return fmt.Errorf("failed to update actuator %s: %w", actuator.ActuatorID, err)
+	}
+	return nil
+}
+
+// SetState records the state an actuator reported
+func (s ActuatorService) SetState(actuatorID, state string, at time.Time) error {
+	err := postgres.DB().Model(&models.Actuator{}).
+		Where("actuator_id = ?", actuatorID).
+		Updates(map[string]interface{}{"state": state, "state_updated_at": at}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update state of actuator %s: %w", actuatorID, err)
+	}
+	return nil
+}
+
+func (s ActuatorService) Delete(actuatorID string) error {
+	res := postgres.DB().Where("actuator_id = ?", actuatorID).Delete(&models.Actuator{})
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete actuator %s: %w", actuatorID, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
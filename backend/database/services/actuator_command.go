@@ -0,0 +1,86 @@
+package services
+
+import (
+	postgres "backend/database"
+	"backend/database/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type ActuatorCommandService struct{}
+
+var ActuatorCommand = ActuatorCommandService{}
+
+// ActuatorCommandFilter narrows down ActuatorCommandService.GetPaginated, zero values match everything
+type ActuatorCommandFilter struct {
+	ActuatorID string
+	Status     string
+}
+
+// GetByCommandID returns the command with the given command_id, or nil if it doesn't exist
+func (s ActuatorCommandService) GetByCommandID(commandID string) (*models.ActuatorCommand, error) {
+	var command models.ActuatorCommand
+	err := postgres.DB().Where("command_id = ?", commandID).First(&command).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch command %s: %w", commandID, err)
+	}
+	return &command, nil
+}
+
+func (s ActuatorCommandService) GetPaginated(filter ActuatorCommandFilter, page, pageSize int) ([]models.ActuatorCommand, int64, error) {
+	var commands []models.ActuatorCommand
+	var totalCount int64
+
+	query := postgres.DB().Model(&models.ActuatorCommand{})
+	if filter.ActuatorID != "" {
+		query = query.Where("actuator_id = ?", filter.ActuatorID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count commands: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("created_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&commands).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch commands: %w", err)
+	}
+
+	return commands, totalCount, nil
+}
+
+func (s ActuatorCommandService) Create(command *models.ActuatorCommand) error {
+	if err := postgres.DB().Create(command).Error; err != nil {
+		return fmt.Errorf("failed to create command for actuator %s: %w", command.ActuatorID, err)
+	}
+	return nil
+}
+
+func (s ActuatorCommandService) Update(command *models.ActuatorCommand) error {
+	if err := postgres.DB().Save(command).Error; err != nil {
+		return fmt.Errorf("failed to update command %s: %w", command.CommandID, err)
+	}
+	return nil
+}
+
+// FailUnfinished marks every command still waiting for delivery as failed,
+// used on startup since nobody retries them anymore
+func (s ActuatorCommandService) FailUnfinished(reason string) (int64, error) {
+	res := postgres.DB().Model(&models.ActuatorCommand{}).
+		Where("status IN ?", []string{models.CommandPending, models.CommandSent}).
+		Updates(map[string]interface{}{"status": models.CommandFailed, "error": reason})
+	if res.Error != nil {
+		return 0, fmt.Errorf("failed to fail unfinished commands: %w", res.Error)
+	}
+	return res.RowsAffected, nil
+}
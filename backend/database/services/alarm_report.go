@@ -0,0 +1,105 @@
+package services
+
+import (
+	postgres "backend/database"
+	"backend/database/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AlarmReportService struct{}
+
+var AlarmReport = AlarmReportService{}
+
+// AlarmReportFilter narrows down AlarmReportService.GetPaginated, zero values match everything
+type AlarmReportFilter struct {
+	Status string
+	Code   string
+	Since  time.Time
+	Until  time.Time
+}
+
+// NextPending returns the oldest report waiting for the receiver, or nil if there is none
+func (s AlarmReportService) NextPending() (*models.AlarmReport, error) {
+	var report models.AlarmReport
+	err := postgres.DB().Where("status = ?", models.ReportPending).Order("id").First(&report).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pending alarm report: %w", err)
+	}
+	return &report, nil
+}
+
+// CountPending returns how many reports wait for the receiver
+func (s AlarmReportService) CountPending() (int64, error) {
+	var count int64
+	if err := postgres.DB().Model(&models.AlarmReport{}).Where("status = ?", models.ReportPending).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count pending alarm reports: %w", err)
+	}
+	return count, nil
+}
+
+// LastSequence returns the sequence number of the latest report, 0 if there is none
+func (s AlarmReportService) LastSequence() (int, error) {
+	var report models.AlarmReport
+	err := postgres.DB().Order("id DESC").First(&report).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch last alarm report: %w", err)
+	}
+	return report.Sequence, nil
+}
+
+func (s AlarmReportService) GetPaginated(filter AlarmReportFilter, page, pageSize int) ([]models.AlarmReport, int64, error) {
+	var reports []models.AlarmReport
+	var totalCount int64
+
+	query := postgres.DB().Model(&models.AlarmReport{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Code != "" {
+		query = query.Where("code = ?", filter.Code)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count alarm reports: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&reports).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch alarm reports: %w", err)
+	}
+
+	return reports, totalCount, nil
+}
+
+func (s AlarmReportService) Create(report *models.AlarmReport) error {
+	if err := postgres.DB().Create(report).Error; err != nil {
+		return fmt.Errorf("failed to create alarm report: %w", err)
+	}
+	return nil
+}
+
+func (s AlarmReportService) Update(report *models.AlarmReport) error {
+	if err := postgres.DB().Save(report).Error; err != nil {
+		return fmt.Errorf("failed to update alarm report %d: %w", report.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	postgres "backend/database"
+	"backend/database/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type AlarmStateService struct{}
+
+var AlarmState = AlarmStateService{}
+
+// Get returns the persisted alarm state of a partition, or nil if none has been saved yet
+func (s AlarmStateService) Get(partitionID uint) (*models.AlarmState, error) {
+	var state models.AlarmState
+	err := postgres.DB().Where("partition_id = ?", partitionID).First(&state).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch alarm state of partition %d: %w", partitionID, err)
+	}
+	return &state, nil
+}
+
+// Save creates or updates the alarm state row of state.PartitionID
+func (s AlarmStateService) Save(state *models.AlarmState) error {
+	db := postgres.DB()
+
+	var existing models.AlarmState
+	err := db.Select("id").Where("partition_id = ?", state.PartitionID).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to fetch alarm state of partition %d: %w", state.PartitionID, err)
+	}
+	state.ID = existing.ID
+
+	if err := db.Save(state).Error; err != nil {
+		return fmt.Errorf("failed to save alarm state: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the alarm state of a partition
+func (s AlarmStateService) Delete(partitionID uint) error {
+	if err := postgres.DB().Where("partition_id = ?", partitionID).Delete(&models.AlarmState{}).Error; err != nil {
+		return fmt.Errorf("failed to delete alarm state of partition %d: %w", partitionID, err)
+	}
+	return nil
+}
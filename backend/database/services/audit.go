@@ -0,0 +1,144 @@
+package services
+
+import (
+	postgres "backend/database"
+	"backend/database/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// auditLockKey is the Postgres advisory lock serializing audit writes, so
+// two concurrent entries can't chain off the same predecessor
+const auditLockKey = 0x617564697401
+
+type AuditService struct{}
+
+var Audit = AuditService{}
+
+// AuditFilter narrows down AuditService.GetPaginated, zero values match everything
+type AuditFilter struct {
+	Actor    string
+	Action   string
+	Resource string
+	Since    time.Time
+	Until    time.Time
+}
+
+// AuditVerification is the result of checking the hash chain
+type AuditVerification struct {
+	Valid    bool  `json:"valid"`
+	Checked  int64 `json:"checked"`
+	BrokenAt *uint `json:"broken_at,omitempty"` // ID of the first entry that doesn't match the chain
+}
+
+// Append adds an entry to the end of the audit log, filling in the
+// timestamp and hashes
+func (s AuditService) Append(entry *models.AuditEntry) error {
+	return postgres.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditLockKey).Error; err != nil {
+			return fmt.Errorf("failed to lock audit log: %w", err)
+		}
+
+		var last models.AuditEntry
+		err := tx.Order("id DESC").First(&last).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to fetch last audit entry: %w", err)
+		}
+
+		// Postgres keeps microseconds, hash what will be read back
+		entry.Timestamp = time.Now().UTC().Truncate(time.Microsecond)
+		entry.PrevHash = last.Hash
+		entry.Hash = auditHash(entry)
+
+		if err := tx.Create(entry).Error; err != nil {
+			return fmt.Errorf("failed to create audit entry: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s AuditService) GetPaginated(filter AuditFilter, page, pageSize int) ([]models.AuditEntry, int64, error) {
+	var entries []models.AuditEntry
+	var totalCount int64
+
+	query := postgres.DB().Model(&models.AuditEntry{})
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Resource != "" {
+		query = query.Where("resource = ?", filter.Resource)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("timestamp >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("timestamp < ?", filter.Until)
+	}
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&entries).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch audit entries: %w", err)
+	}
+
+	return entries, totalCount, nil
+}
+
+// Verify walks the whole audit log and recomputes the hash chain
+func (s AuditService) Verify() (*AuditVerification, error) {
+	result := &AuditVerification{Valid: true}
+	prevHash := ""
+
+	var batch []models.AuditEntry
+	err := postgres.DB().Order("id").FindInBatches(&batch, 1000, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			entry := &batch[i]
+			if entry.PrevHash != prevHash || entry.Hash != auditHash(entry) {
+				result.Valid = false
+				result.BrokenAt = &entry.ID
+				return errStopVerify
+			}
+			prevHash = entry.Hash
+			result.Checked++
+		}
+		return nil
+	}).Error
+	if err != nil && !errors.Is(err, errStopVerify) {
+		return nil, fmt.Errorf("failed to verify audit log: %w", err)
+	}
+	return result, nil
+}
+
+var errStopVerify = errors.New("audit chain broken")
+
+// auditHash hashes an entry together with the hash of its predecessor
+func auditHash(entry *models.AuditEntry) string {
+	data, _ := json.Marshal([]string{
+		entry.PrevHash,
+		entry.Timestamp.UTC().Format(time.RFC3339Nano),
+		entry.Actor,
+		entry.SourceIP,
+		entry.Action,
+		entry.Resource,
+		entry.ResourceID,
+		entry.Before,
+		entry.After,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	postgres "backend/database"
+	"backend/database/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type DeadLetterService struct{}
+
+var DeadLetter = DeadLetterService{}
+
+// DeadLetterFilter narrows down DeadLetterService.GetPaginated, zero values match everything
+type DeadLetterFilter struct {
+	Topic  string // Topic prefix
+	Reason string
+	Status string
+	Since  time.Time
+	Until  time.Time
+}
+
+func (s DeadLetterService) Create(letter *models.DeadLetter) error {
+	if letter.Status == "" {
+		letter.Status = models.DeadLetterPending
+	}
+	if letter.Attempts == 0 {
+		letter.Attempts = 1
+	}
+	if err := postgres.DB().Create(letter).Error; err != nil {
+		return fmt.Errorf("failed to create dead letter: %w", err)
+	}
+	return nil
+}
+
+// Get returns the dead letter with the given ID, or nil if it doesn't exist
+func (s DeadLetterService) Get(id uint) (*models.DeadLetter, error) {
+	var letter models.DeadLetter
+	err := postgres.DB().First(&letter, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dead letter %d: %w", id, err)
+	}
+	return &letter, nil
+}
+
+func (s DeadLetterService) GetPaginated(filter DeadLetterFilter, page, pageSize int) ([]models.DeadLetter, int64, error) {
+	var letters []models.DeadLetter
+	var totalCount int64
+
+	query := postgres.DB().Model(&models.DeadLetter{})
+	if filter.Topic != "" {
+		query = query.Where("topic LIKE ?", filter.Topic+"%")
+	}
+	if filter.Reason != "" {
+		query = query.Where("reason = ?", filter.Reason)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("received_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("received_at < ?", filter.Until)
+	}
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count dead letters: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("received_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&letters).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch dead letters: %w", err)
+	}
+
+	return letters, totalCount, nil
+}
+
+// MarkReprocessed records a successful re-processing attempt
+func (s DeadLetterService) MarkReprocessed(letter *models.DeadLetter) error {
+	now := time.Now()
+	letter.Status = models.DeadLetterReprocessed
+	letter.Attempts++
+	letter.Error = ""
+	letter.ReprocessedAt = &now
+	if err := postgres.DB().Save(letter).Error; err != nil {
+		return fmt.Errorf("failed to update dead letter %d: %w", letter.ID, err)
+	}
+	return nil
+}
+
+// MarkFailed records a failed re-processing attempt
+func (s DeadLetterService) MarkFailed(letter *models.DeadLetter, reason string, cause error) error {
+	letter.Attempts++
+	letter.Reason = reason
+	letter.Error = cause.Error()
+	if err := postgres.DB().Save(letter).Error; err != nil {
+		return fmt.Errorf("failed to update dead letter %d: %w", letter.ID, err)
+	}
+	return nil
+}
+
+func (s DeadLetterService) Delete(id uint) error {
+	res := postgres.DB().Delete(&models.DeadLetter{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete dead letter %d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
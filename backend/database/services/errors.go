@@ -0,0 +1,12 @@
+package services
+
+import "errors"
+
+var (
+	// ErrNotFound is returned when the record being modified does not exist
+	ErrNotFound = errors.New("record not found")
+	// ErrConflict is returned when a record cannot be modified because other records depend on it
+	ErrConflict = errors.New("record is in use")
+	// ErrDuplicate is returned when creating a record whose unique key is already taken
+	ErrDuplicate = errors.New("record already exists")
+)
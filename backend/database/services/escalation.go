@@ -0,0 +1,118 @@
+package services
+
+import (
+	postgres "backend/database"
+	"backend/database/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type EscalationService struct{}
+
+var Escalation = EscalationService{}
+
+// withSteps loads the steps of policies in chain order
+func withSteps(db *gorm.DB) *gorm.DB {
+	return db.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position, id")
+	})
+}
+
+// List returns all escalation policies with their steps
+func (s EscalationService) List() ([]models.EscalationPolicy, error) {
+	var policies []models.EscalationPolicy
+	if err := withSteps(postgres.DB()).Order("id").Find(&policies).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch escalation policies: %w", err)
+	}
+	return policies, nil
+}
+
+// Get returns the policy with the given ID, or nil if it doesn't exist
+func (s EscalationService) Get(id uint) (*models.EscalationPolicy, error) {
+	var policy models.EscalationPolicy
+	err := withSteps(postgres.DB()).First(&policy, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch escalation policy %d: %w", id, err)
+	}
+	return &policy, nil
+}
+
+// ForPartition returns the enabled policy of a partition, falling back to
+// the enabled policy without a partition. Returns nil if neither exists.
+func (s EscalationService) ForPartition(partitionID uint) (*models.EscalationPolicy, error) {
+	var policy models.EscalationPolicy
+	err := withSteps(postgres.DB()).
+		Where("enabled AND (partition_id = ? OR partition_id IS NULL)", partitionID).
+		Order("partition_id IS NULL, id").
+		First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch escalation policy of partition %d: %w", partitionID, err)
+	}
+	return &policy, nil
+}
+
+// Create adds a policy together with its steps
+func (s EscalationService) Create(policy *models.EscalationPolicy) error {
+	if err := s.checkName(policy); err != nil {
+		return err
+	}
+	if err := postgres.DB().Create(policy).Error; err != nil {
+		return fmt.Errorf("failed to create escalation policy: %w", err)
+	}
+	return nil
+}
+
+// Update saves a policy and replaces its steps
+func (s EscalationService) Update(policy *models.EscalationPolicy) error {
+	if err := s.checkName(policy); err != nil {
+		return err
+	}
+	err := postgres.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("policy_id = ?", policy.ID).Delete(&models.EscalationStep{}).Error; err != nil {
+			return err
+		}
+		for i := range policy.Steps {
+			policy.Steps[i].ID = 0
+			policy.Steps[i].PolicyID = policy.ID
+		}
+		return tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(policy).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update escalation policy %d: %w", policy.ID, err)
+	}
+	return nil
+}
+
+func (s EscalationService) Delete(id uint) error {
+	res := postgres.DB().Delete(&models.EscalationPolicy{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete escalation policy %d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// checkName returns ErrDuplicate if another policy has the same name
+func (s EscalationService) checkName(policy *models.EscalationPolicy) error {
+	var count int64
+	err := postgres.DB().Model(&models.EscalationPolicy{}).
+		Where("name = ? AND id <> ?", policy.Name, policy.ID).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to check escalation policy name: %w", err)
+	}
+	if count > 0 {
+		return ErrDuplicate
+	}
+	return nil
+}
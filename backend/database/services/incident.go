@@ -0,0 +1,133 @@
+package services
+
+import (
+	postgres "backend/database"
+	"backend/database/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type IncidentService struct{}
+
+var Incident = IncidentService{}
+
+// IncidentFilter narrows down IncidentService.GetPaginated, zero values match everything
+type IncidentFilter struct {
+	State       string
+	PartitionID uint
+	Assignee    string
+	Since       time.Time
+	Until       time.Time
+}
+
+// Get returns the incident with the given ID, or nil if it doesn't exist
+func (s IncidentService) Get(id uint) (*models.Incident, error) {
+	var incident models.Incident
+	err := postgres.DB().First(&incident, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch incident %d: %w", id, err)
+	}
+	return &incident, nil
+}
+
+// GetOpen returns the open or acknowledged incident of a partition, or nil if there is none
+func (s IncidentService) GetOpen(partitionID uint) (*models.Incident, error) {
+	var incident models.Incident
+	err := postgres.DB().
+		Where("partition_id = ? AND state IN ?", partitionID, []string{models.IncidentOpen, models.IncidentAcknowledged}).
+		Order("opened_at DESC").
+		First(&incident).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch open incident of partition %d: %w", partitionID, err)
+	}
+	return &incident, nil
+}
+
+func (s IncidentService) GetPaginated(filter IncidentFilter, page, pageSize int) ([]models.Incident, int64, error) {
+	var incidents []models.Incident
+	var totalCount int64
+
+	query := postgres.DB().Model(&models.Incident{})
+	if filter.State != "" {
+		query = query.Where("state = ?", filter.State)
+	}
+	if filter.PartitionID != 0 {
+		query = query.Where("partition_id = ?", filter.PartitionID)
+	}
+	if filter.Assignee != "" {
+		query = query.Where("assignee = ?", filter.Assignee)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("opened_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("opened_at < ?", filter.Until)
+	}
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count incidents: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("opened_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&incidents).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch incidents: %w", err)
+	}
+
+	return incidents, totalCount, nil
+}
+
+func (s IncidentService) Create(incident *models.Incident) error {
+	if err := postgres.DB().Create(incident).Error; err != nil {
+		return fmt.Errorf("failed to create incident: %w", err)
+	}
+	return nil
+}
+
+func (s IncidentService) Update(incident *models.Incident) error {
+	if err := postgres.DB().Save(incident).Error; err != nil {
+		return fmt.Errorf("failed to update incident %d: %w", incident.ID, err)
+	}
+	return nil
+}
+
+// AddEvent appends an entry to the timeline of an incident
+func (s IncidentService) AddEvent(event *models.IncidentEvent) error {
+	if err := postgres.DB().Create(event).Error; err != nil {
+		return fmt.Errorf("failed to add event to incident %d: %w", event.IncidentID, err)
+	}
+	return nil
+}
+
+// ListEvents returns the timeline of an incident, oldest first
+func (s IncidentService) ListEvents(incidentID uint) ([]models.IncidentEvent, error) {
+	var events []models.IncidentEvent
+	if err := postgres.DB().Where("incident_id = ?", incidentID).Order("created_at, id").Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch events of incident %d: %w", incidentID, err)
+	}
+	return events, nil
+}
+
+// ListEscalating returns the open incidents with an escalation chain, oldest first
+func (s IncidentService) ListEscalating() ([]models.Incident, error) {
+	var incidents []models.Incident
+	err := postgres.DB().
+		Where("state = ? AND escalation_policy_id IS NOT NULL", models.IncidentOpen).
+		Order("opened_at").
+		Find(&incidents).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch escalating incidents: %w", err)
+	}
+	return incidents, nil
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	postgres "backend/database"
+	"backend/database/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationService struct{}
+
+var Notification = NotificationService{}
+
+// DeliveryFilter narrows down NotificationService.GetDeliveries, zero values match everything
+type DeliveryFilter struct {
+	UserID  uint
+	Event   string
+	Channel string
+	Status  string
+	Since   time.Time
+	Until   time.Time
+}
+
+// ListPreferences returns the notification preferences of a user
+func (s NotificationService) ListPreferences(userID uint) ([]models.NotificationPreference, error) {
+	var prefs []models.NotificationPreference
+	if err := postgres.DB().Where("user_id = ?", userID).Order("id").Find(&prefs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notification preferences of user %d: %w", userID, err)
+	}
+	return prefs, nil
+}
+
+// ListEnabledPreferences returns the enabled preferences of all users
+func (s NotificationService) ListEnabledPreferences() ([]models.NotificationPreference, error) {
+	var prefs []models.NotificationPreference
+	if err := postgres.DB().Where("enabled").Order("id").Find(&prefs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notification preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+// GetPreference returns the preference with the given ID, or nil if it doesn't exist
+func (s NotificationService) GetPreference(id uint) (*models.NotificationPreference, error) {
+	var pref models.NotificationPreference
+	err := postgres.DB().First(&pref, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch notification preference %d: %w", id, err)
+	}
+	return &pref, nil
+}
+
+func (s NotificationService) CreatePreference(pref *models.NotificationPreference) error {
+	if err := postgres.DB().Create(pref).Error; err != nil {
+		return fmt.Errorf("failed to create notification preference: %w", err)
+	}
+	return nil
+}
+
+func (s NotificationService) UpdatePreference(pref *models.NotificationPreference) error {
+	if err := postgres.DB().Save(pref).Error; err != nil {
+		return fmt.Errorf("failed to update notification preference %d: %w", pref.ID, err)
+	}
+	return nil
+}
+
+func (s NotificationService) DeletePreference(id uint) error {
+	res := postgres.DB().Delete(&models.NotificationPreference{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete notification preference %d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListTemplates returns the configured template overrides
+func (s NotificationService) ListTemplates() ([]models.NotificationTemplate, error) {
+	var templates []models.NotificationTemplate
+	if err := postgres.DB().Order("event").Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch notification templates: %w", err)
+	}
+	return templates, nil
+}
+
+// SaveTemplate creates or replaces the template override of an event
+func (s NotificationService) SaveTemplate(template *models.NotificationTemplate) error {
+	err := postgres.DB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event"}},
+		DoUpdates: clause.AssignmentColumns([]string{"title", "body", "updated_at"}),
+	}).Create(template).Error
+	if err != nil {
+		return fmt.Errorf("failed to save notification template of %s: %w", template.Event, err)
+	}
+	return nil
+}
+
+// DeleteTemplate removes the override of an event so the built-in template is used again
+func (s NotificationService) DeleteTemplate(event string) error {
+	res := postgres.DB().Where("event = ?", event).Delete(&models.NotificationTemplate{})
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete notification template of %s: %w", event, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetDelivery returns the delivery with the given ID, or nil if it doesn't exist
+func (s NotificationService) GetDelivery(id uint) (*models.NotificationDelivery, error) {
+	var delivery models.NotificationDelivery
+	err := postgres.DB().First(&delivery, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch notification delivery %d: %w", id, err)
+	}
+	return &delivery, nil
+}
+
+// ListPendingDeliveries returns the deliveries still waiting to be sent, oldest first
+func (s NotificationService) ListPendingDeliveries() ([]models.NotificationDelivery, error) {
+	var deliveries []models.NotificationDelivery
+	if err := postgres.DB().Where("status = ?", models.DeliveryPending).Order("id").Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch pending notification deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (s NotificationService) GetDeliveries(filter DeliveryFilter, page, pageSize int) ([]models.NotificationDelivery, int64, error) {
+	var deliveries []models.NotificationDelivery
+	var totalCount int64
+
+	query := postgres.DB().Model(&models.NotificationDelivery{})
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Event != "" {
+		query = query.Where("event = ?", filter.Event)
+	}
+	if filter.Channel != "" {
+		query = query.Where("channel = ?", filter.Channel)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count notification deliveries: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("created_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&deliveries).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch notification deliveries: %w", err)
+	}
+
+	return deliveries, totalCount, nil
+}
+
+func (s NotificationService) CreateDelivery(delivery *models.NotificationDelivery) error {
+	if err := postgres.DB().Create(delivery).Error; err != nil {
+		return fmt.Errorf("failed to create notification delivery: %w", err)
+	}
+	return nil
+}
+
+func (s NotificationService) UpdateDelivery(delivery *models.NotificationDelivery) error {
+	if err := postgres.DB().Save(delivery).Error; err != nil {
+		return fmt.Errorf("failed to update notification delivery %d: %w", delivery.ID, err)
+	}
+	return nil
+}
+
+// GetTemplate returns the template override of an event, or nil if there is none
+func (s NotificationService) GetTemplate(event string) (*models.NotificationTemplate, error) {
+	var template models.NotificationTemplate
+	err := postgres.DB().Where("event = ?", event).First(&template).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch notification template of %s: %w", event, err)
+	}
+	return &template, nil
+}
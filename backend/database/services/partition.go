@@ -0,0 +1,86 @@
+package services
+
+import (
+	postgres "backend/database"
+	"backend/database/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// DefaultPartitionName is the partition sensors without a zone belong to
+const DefaultPartitionName = "house"
+
+type PartitionService struct{}
+
+var Partition = PartitionService{}
+
+// EnsureDefault returns the default partition, creating it if it doesn't exist yet
+func (s PartitionService) EnsureDefault() (*models.Partition, error) {
+	partition := models.Partition{Name: DefaultPartitionName, Description: "Default partition"}
+	if err := postgres.DB().
+		Where("name = ?", DefaultPartitionName).
+		FirstOrCreate(&partition).Error; err != nil {
+		return nil, fmt.Errorf("failed to ensure default partition: %w", err)
+	}
+	return &partition, nil
+}
+
+// List returns all partitions ordered by ID
+func (s PartitionService) List() ([]models.Partition, error) {
+	var partitions []models.Partition
+	if err := postgres.DB().Order("id").Find(&partitions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch partitions: %w", err)
+	}
+	return partitions, nil
+}
+
+// Get returns the partition with the given ID, or nil if it doesn't exist
+func (s PartitionService) Get(id uint) (*models.Partition, error) {
+	var partition models.Partition
+	err := postgres.DB().First(&partition, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch partition %d: %w", id, err)
+	}
+	return &partition, nil
+}
+
+func (s PartitionService) Create(partition *models.Partition) error {
+	if err := postgres.DB().Create(partition).Error; err != nil {
+		return fmt.Errorf("failed to create partition: %w", err)
+	}
+	return nil
+}
+
+func (s PartitionService) Update(partition *models.Partition) error {
+	if err := postgres.DB().Save(partition).Error; err != nil {
+		return fmt.Errorf("failed to update partition %d: %w", partition.ID, err)
+	}
+	return nil
+}
+
+// Delete soft deletes a partition. Partitions that still have zones can't be deleted.
+func (s PartitionService) Delete(id uint) error {
+	db := postgres.DB()
+
+	var zones int64
+	if err := db.Model(&models.Zone{}).Where("partition_id = ?", id).Count(&zones).Error; err != nil {
+		return fmt.Errorf("failed to count zones of partition %d: %w", id, err)
+	}
+	if zones > 0 {
+		return ErrConflict
+	}
+
+	res := db.Delete(&models.Partition{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete partition %d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
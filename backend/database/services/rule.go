@@ -0,0 +1,79 @@
+package services
+
+import (
+	postgres "backend/database"
+	"backend/database/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RuleService struct{}
+
+var Rule = RuleService{}
+
+// List returns all rules ordered by ID
+func (s RuleService) List() ([]models.Rule, error) {
+	var rules []models.Rule
+	if err := postgres.DB().Order("id").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch rules: %w", err)
+	}
+	return rules, nil
+}
+
+// ListEnabled returns the rules that are evaluated against incoming messages
+func (s RuleService) ListEnabled() ([]models.Rule, error) {
+	var rules []models.Rule
+	if err := postgres.DB().Where("enabled").Order("id").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch enabled rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Get returns the rule with the given ID, or nil if it doesn't exist
+func (s RuleService) Get(id uint) (*models.Rule, error) {
+	var rule models.Rule
+	err := postgres.DB().First(&rule, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rule %d: %w", id, err)
+	}
+	return &rule, nil
+}
+
+func (s RuleService) Create(rule *models.Rule) error {
+	if err := postgres.DB().Create(rule).Error; err != nil {
+		return fmt.Errorf("failed to create rule: %w", err)
+	}
+	return nil
+}
+
+func (s RuleService) Update(rule *models.Rule) error {
+	if err := postgres.DB().Save(rule).Error; err != nil {
+		return fmt.Errorf("failed to update rule %d: %w", rule.ID, err)
+	}
+	return nil
+}
+
+func (s RuleService) Delete(id uint) error {
+	res := postgres.DB().Delete(&models.Rule{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete rule %d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MarkFired records when a rule last fired
+func (s RuleService) MarkFired(id uint, at time.Time) error {
+	if err := postgres.DB().Model(&models.Rule{}).Where("id = ?", id).Update("last_fired_at", at).Error; err != nil {
+		return fmt.Errorf("failed to record firing of rule %d: %w", id, err)
+	}
+	return nil
+}
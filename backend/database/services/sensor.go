@@ -0,0 +1,254 @@
+package services
+
+import (
+	postgres "backend/database"
+	"backend/database/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SensorService struct{}
+
+var Sensor = SensorService{}
+
+// GetBySensorID returns the sensor with the given sensor_id, or nil if it is not registered
+func (s SensorService) GetBySensorID(sensorID string) (*models.Sensor, error) {
+	var sensor models.Sensor
+	err := postgres.DB().Where("sensor_id = ?", sensorID).First(&sensor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sensor %s: %w", sensorID, err)
+	}
+	return &sensor, nil
+}
+
+// SensorFilter narrows down SensorService.GetPaginated, zero values match everything
+type SensorFilter struct {
+	Type     string
+	Status   string
+	Location string
+	ZoneID   uint
+	Online   *bool
+}
+
+func (s SensorService) GetPaginated(filter SensorFilter, page, pageSize int) ([]models.Sensor, int64, error) {
+	var sensors []models.Sensor
+	var totalCount int64
+
+	query := postgres.DB().Model(&models.Sensor{})
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Location != "" {
+		query = query.Where("location = ?", filter.Location)
+	}
+	if filter.ZoneID != 0 {
+		query = query.Where("zone_id = ?", filter.ZoneID)
+	}
+	if filter.Online != nil {
+		query = query.Where("online = ?", *filter.Online)
+	}
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count sensors: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("sensor_id").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&sensors).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch sensors: %w", err)
+	}
+
+	return sensors, totalCount, nil
+}
+
+// Create registers a new sensor. A soft deleted sensor with the same
+// sensor_id is restored with the new details, since its readings still
+// reference it.
+func (s SensorService) Create(sensor *models.Sensor) error {
+	db := postgres.DB()
+	if sensor.Status == "" {
+		sensor.Status = models.SensorStatusActive
+	}
+
+	var existing models.Sensor
+	err := db.Unscoped().Where("sensor_id = ?", sensor.SensorID).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check sensor %s: %w", sensor.SensorID, err)
+	}
+	if err == nil {
+		if !existing.DeletedAt.Valid {
+			return ErrDuplicate
+		}
+		sensor.ID = existing.ID
+		sensor.CreatedAt = existing.CreatedAt
+		sensor.DeletedAt = gorm.DeletedAt{}
+		if err := db.Unscoped().Save(sensor).Error; err != nil {
+			return fmt.Errorf("failed to restore sensor %s: %w", sensor.SensorID, err)
+		}
+		return nil
+	}
+
+	if err := db.Create(sensor).Error; err != nil {
+		return fmt.Errorf("failed to create sensor %s: %w", sensor.SensorID, err)
+	}
+	return nil
+}
+
+func (s SensorService) Update(sensor *models.Sensor) error {
+	if err := postgres.DB().Save(sensor).Error; err != nil {
+		return fmt.Errorf("failed to update sensor %s: %w", sensor.SensorID, err)
+	}
+	return nil
+}
+
+// RegisterPending records a sensor that was seen on MQTT but is not
+// registered yet, so an admin can approve, reject or block it
+func (s SensorService) RegisterPending(sensorID, sensorType, location string) (*models.Sensor, error) {
+	sensor := &models.Sensor{
+		SensorID:    sensorID,
+		Name:        sensorID,
+		Type:        sensorType,
+		Location:    location,
+		Description: "Registered automatically",
+		Status:      models.SensorStatusPending,
+	}
+
+	err := s.Create(sensor)
+	if errors.Is(err, ErrDuplicate) {
+		// Registered concurrently by another message from the same sensor
+		return s.GetBySensorID(sensorID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sensor, nil
+}
+
+// SetStatus changes the status of a sensor
+func (s SensorService) SetStatus(sensorID, status string) error {
+	res := postgres.DB().Model(&models.Sensor{}).
+		Where("sensor_id = ?", sensorID).
+		Update("status", status)
+	if res.Error != nil {
+		return fmt.Errorf("failed to update status of sensor %s: %w", sensorID, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete soft deletes a sensor, its readings are kept
+func (s SensorService) Delete(sensorID string) error {
+	res := postgres.DB().Where("sensor_id = ?", sensorID).Delete(&models.Sensor{})
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete sensor %s: %w", sensorID, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MaxExitDelay returns the longest exit delay configured on any sensor of a
+// partition, in seconds. Sensors without a zone belong to the default partition.
+func (s SensorService) MaxExitDelay(partitionID uint, isDefault bool) (int, error) {
+	query := postgres.DB().Model(&models.Sensor{}).
+		Joins("LEFT JOIN zones ON zones.id = sensors.zone_id AND zones.deleted_at IS NULL")
+	if isDefault {
+		query = query.Where("zones.partition_id = ? OR sensors.zone_id IS NULL", partitionID)
+	} else {
+		query = query.Where("zones.partition_id = ?", partitionID)
+	}
+
+	var maxDelay int
+	if err := query.Select("COALESCE(MAX(sensors.exit_delay), 0)").Scan(&maxDelay).Error; err != nil {
+		return 0, fmt.Errorf("failed to fetch max exit delay of partition %d: %w", partitionID, err)
+	}
+	return maxDelay, nil
+}
+
+// UpdateDelays sets the entry and exit delay of a sensor, in seconds
+func (s SensorService) UpdateDelays(sensorID string, entryDelay, exitDelay int) error {
+	res := postgres.DB().Model(&models.Sensor{}).
+		Where("sensor_id = ?", sensorID).
+		Updates(map[string]interface{}{"entry_delay": entryDelay, "exit_delay": exitDelay})
+	if res.Error != nil {
+		return fmt.Errorf("failed to update delays of sensor %s: %w", sensorID, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetZone assigns a sensor to a zone, or removes it from its zone if zoneID is nil
+func (s SensorService) SetZone(sensorID string, zoneID *uint) error {
+	res := postgres.DB().Model(&models.Sensor{}).
+		Where("sensor_id = ?", sensorID).
+		Update("zone_id", zoneID)
+	if res.Error != nil {
+		return fmt.Errorf("failed to update zone of sensor %s: %w", sensorID, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MarkSeen records that a message from the sensor arrived and marks it online
+func (s SensorService) MarkSeen(sensorID string, at time.Time) error {
+	err := postgres.DB().Model(&models.Sensor{}).
+		Where("sensor_id = ?", sensorID).
+		Updates(map[string]interface{}{"last_seen_at": at, "online": true}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update last seen of sensor %s: %w", sensorID, err)
+	}
+	return nil
+}
+
+// SetOffline marks a sensor offline
+func (s SensorService) SetOffline(sensorID string) error {
+	err := postgres.DB().Model(&models.Sensor{}).
+		Where("sensor_id = ?", sensorID).
+		Update("online", false).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark sensor %s offline: %w", sensorID, err)
+	}
+	return nil
+}
+
+// ListStale returns the active sensors that are still online but haven't
+// been seen since cutoff
+func (s SensorService) ListStale(cutoff time.Time) ([]models.Sensor, error) {
+	var sensors []models.Sensor
+	err := postgres.DB().
+		Where("status = ? AND online = ? AND last_seen_at < ?", models.SensorStatusActive, true, cutoff).
+		Order("sensor_id").
+		Find(&sensors).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch stale sensors: %w", err)
+	}
+	return sensors, nil
+}
+
+// ListActive returns the approved sensors
+func (s SensorService) ListActive() ([]models.Sensor, error) {
+	var sensors []models.Sensor
+	err := postgres.DB().Where("status = ?", models.SensorStatusActive).Order("sensor_id").Find(&sensors).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch active sensors: %w", err)
+	}
+	return sensors, nil
+}
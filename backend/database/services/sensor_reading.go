@@ -5,6 +5,10 @@ import (
 	"backend/database/models"
 	"backend/pkg/utils"
 	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 type SensorReadingService struct{}
@@ -23,11 +27,18 @@ func (s SensorReadingService) Create(reading *models.SensorReading) error {
 
 
 func (s SensorReadingService) GetPaginated(page, pageSize int) ([]models.SensorReading, int64, error) {
+	return s.getPaginated(postgres.DB(), page, pageSize)
+}
+
+// GetPaginatedBySensor returns the readings of a single sensor, newest first
+func (s SensorReadingService) GetPaginatedBySensor(sensorID string, page, pageSize int) ([]models.SensorReading, int64, error) {
+	return s.getPaginated(postgres.DB().Where("sensor_id = ?", sensorID), page, pageSize)
+}
+
+func (s SensorReadingService) getPaginated(db *gorm.DB, page, pageSize int) ([]models.SensorReading, int64, error) {
 	var readings []models.SensorReading
 	var totalCount int64
 
-	db := postgres.DB()
-
 	// Get total count
 	if err := db.Model(&models.SensorReading{}).Count(&totalCount).Error; err != nil {
 		return nil, 0, fmt.Errorf("failed to count sensor readings: %w", err)
@@ -46,3 +57,59 @@ func (s SensorReadingService) GetPaginated(page, pageSize int) ([]models.SensorR
 
 	return readings, totalCount, nil
 }
+
+// SeriesPoint is a single value of a metric time series
+type SeriesPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+	Unit      string    `json:"unit"`
+}
+
+// GetSeries returns the values of a sensor's metric received in [since, until),
+// oldest first. Zero times leave the range open, at most limit points are returned.
+func (s SensorReadingService) GetSeries(sensorID, metric string, since, until time.Time, limit int) ([]SeriesPoint, error) {
+	query := postgres.DB().Model(&models.SensorReading{}).
+		Select("timestamp, value, unit").
+		Where("sensor_id = ? AND metric = ?", sensorID, metric)
+	if !since.IsZero() {
+		query = query.Where("timestamp >= ?", since)
+	}
+	if !until.IsZero() {
+		query = query.Where("timestamp < ?", until)
+	}
+
+	// Take the newest points when the range holds more than limit
+	var points []SeriesPoint
+	if err := query.Order("timestamp DESC").Limit(limit).Scan(&points).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch %s series of sensor %s: %w", metric, sensorID, err)
+	}
+	slices.Reverse(points)
+
+	return points, nil
+}
+
+// GetMetrics returns the distinct metrics a sensor has reported
+func (s SensorReadingService) GetMetrics(sensorID string) ([]string, error) {
+	var metrics []string
+	if err := postgres.DB().Model(&models.SensorReading{}).
+		Where("sensor_id = ? AND metric IS NOT NULL AND metric <> ''", sensorID).
+		Distinct().
+		Order("metric").
+		Pluck("metric", &metrics).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch metrics of sensor %s: %w", sensorID, err)
+	}
+	return metrics, nil
+}
+
+// GetLatestMetrics returns the most recent reading of every metric a sensor has reported
+func (s SensorReadingService) GetLatestMetrics(sensorID string) ([]models.SensorReading, error) {
+	var readings []models.SensorReading
+	if err := postgres.DB().
+		Select("DISTINCT ON (metric) *").
+		Where("sensor_id = ? AND metric IS NOT NULL AND metric <> ''", sensorID).
+		Order("metric, timestamp DESC").
+		Find(&readings).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch latest metrics of sensor %s: %w", sensorID, err)
+	}
+	return readings, nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	postgres "backend/database"
+	"backend/database/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TroubleService struct{}
+
+var Trouble = TroubleService{}
+
+// TroubleFilter narrows down TroubleService.GetPaginated, zero values match everything
+type TroubleFilter struct {
+	SensorID string
+	Type     string
+	Active   *bool
+	Since    time.Time
+	Until    time.Time
+}
+
+// Get returns the trouble with the given ID, or nil if it doesn't exist
+func (s TroubleService) Get(id uint) (*models.Trouble, error) {
+	var trouble models.Trouble
+	err := postgres.DB().First(&trouble, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch trouble %d: %w", id, err)
+	}
+	return &trouble, nil
+}
+
+// GetActive returns the active trouble of a type on a sensor, or nil if there is none
+func (s TroubleService) GetActive(sensorID, troubleType string) (*models.Trouble, error) {
+	var trouble models.Trouble
+	err := postgres.DB().
+		Where("sensor_id = ? AND type = ? AND cleared_at IS NULL", sensorID, troubleType).
+		First(&trouble).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s trouble of sensor %s: %w", troubleType, sensorID, err)
+	}
+	return &trouble, nil
+}
+
+// ListActive returns all active troubles, oldest first
+func (s TroubleService) ListActive() ([]models.Trouble, error) {
+	var troubles []models.Trouble
+	if err := postgres.DB().Where("cleared_at IS NULL").Order("raised_at").Find(&troubles).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch active troubles: %w", err)
+	}
+	return troubles, nil
+}
+
+// ListActiveInPartition returns the active troubles of the sensors in a
+// partition. Sensors without a zone belong to the default partition.
+func (s TroubleService) ListActiveInPartition(partitionID uint, isDefault bool) ([]models.Trouble, error) {
+	query := postgres.DB().Model(&models.Trouble{}).
+		Joins("JOIN sensors ON sensors.sensor_id = troubles.sensor_id AND sensors.deleted_at IS NULL").
+		Joins("LEFT JOIN zones ON zones.id = sensors.zone_id AND zones.deleted_at IS NULL").
+		Where("troubles.cleared_at IS NULL")
+	if isDefault {
+		query = query.Where("zones.partition_id = ? OR sensors.zone_id IS NULL", partitionID)
+	} else {
+		query = query.Where("zones.partition_id = ?", partitionID)
+	}
+
+	var troubles []models.Trouble
+	if err := query.Order("troubles.raised_at").Find(&troubles).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch active troubles of partition %d: %w", partitionID, err)
+	}
+	return troubles, nil
+}
+
+func (s TroubleService) GetPaginated(filter TroubleFilter, page, pageSize int) ([]models.Trouble, int64, error) {
+	var troubles []models.Trouble
+	var totalCount int64
+
+	query := postgres.DB().Model(&models.Trouble{})
+	if filter.SensorID != "" {
+		query = query.Where("sensor_id = ?", filter.SensorID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Active != nil {
+		if *filter.Active {
+			query = query.Where("cleared_at IS NULL")
+		} else {
+			query = query.Where("cleared_at IS NOT NULL")
+		}
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("raised_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("raised_at < ?", filter.Until)
+	}
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count troubles: %w", err)
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("raised_at DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&troubles).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch troubles: %w", err)
+	}
+
+	return troubles, totalCount, nil
+}
+
+func (s TroubleService) Create(trouble *models.Trouble) error {
+	if err := postgres.DB().Create(trouble).Error; err != nil {
+		return fmt.Errorf("failed to create %s trouble of sensor %s: %w", trouble.Type, trouble.SensorID, err)
+	}
+	return nil
+}
+
+// Clear marks a trouble as no longer active
+func (s TroubleService) Clear(trouble *models.Trouble, at time.Time) error {
+	if err := postgres.DB().Model(trouble).Update("cleared_at", at).Error; err != nil {
+		return fmt.Errorf("failed to clear trouble %d: %w", trouble.ID, err)
+	}
+	return nil
+}
+
+// Acknowledge records who acknowledged a trouble
+func (s TroubleService) Acknowledge(trouble *models.Trouble, by string, at time.Time) error {
+	err := postgres.DB().Model(trouble).
+		Updates(map[string]interface{}{"acknowledged_at": at, "acknowledged_by": by}).Error
+	if err != nil {
+		return fmt.Errorf("failed to acknowledge trouble %d: %w", trouble.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	postgres "backend/database"
+	"backend/database/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type UserService struct{}
+
+var User = UserService{}
+
+// Get returns the user with the given ID, or nil if it doesn't exist
+func (s UserService) Get(id uint) (*models.User, error) {
+	var user models.User
+	err := postgres.DB().First(&user, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user %d: %w", id, err)
+	}
+	return &user, nil
+}
+
+// GetByUsername returns the user with the given username, or nil if it doesn't exist
+func (s UserService) GetByUsername(username string) (*models.User, error) {
+	var user models.User
+	err := postgres.DB().Where("username = ?", username).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user %s: %w", username, err)
+	}
+	return &user, nil
+}
+
+// Count returns the number of users
+func (s UserService) Count() (int64, error) {
+	var count int64
+	if err := postgres.DB().Model(&models.User{}).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// Create adds a user. user.Password must already be hashed.
+func (s UserService) Create(user *models.User) error {
+	existing, err := s.GetByUsername(user.Username)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return ErrDuplicate
+	}
+
+	if err := postgres.DB().Create(user).Error; err != nil {
+		return fmt.Errorf("failed to create user %s: %w", user.Username, err)
+	}
+	return nil
+}
+
+// List returns all users ordered by username
+func (s UserService) List() ([]models.User, error) {
+	var users []models.User
+	if err := postgres.DB().Order("username").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch users: %w", err)
+	}
+	return users, nil
+}
+
+func (s UserService) Update(user *models.User) error {
+	if err := postgres.DB().Save(user).Error; err != nil {
+		return fmt.Errorf("failed to update user %d: %w", user.ID, err)
+	}
+	return nil
+}
+
+// CountByRole returns the number of users with a role
+func (s UserService) CountByRole(role string) (int64, error) {
+	var count int64
+	if err := postgres.DB().Model(&models.User{}).Where("role = ?", role).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count %s users: %w", role, err)
+	}
+	return count, nil
+}
+
+// Delete permanently removes a user so the username can be used again
+func (s UserService) Delete(id uint) error {
+	res := postgres.DB().Unscoped().Delete(&models.User{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete user %d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdatePassword replaces the password hash of a user
+func (s UserService) UpdatePassword(id uint, hash string) error {
+	res := postgres.DB().Model(&models.User{}).Where("id = ?", id).Update("password", hash)
+	if res.Error != nil {
+		return fmt.Errorf("failed to update password of user %d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListWithPin returns the users that have a keypad or duress PIN set
+func (s UserService) ListWithPin() ([]models.User, error) {
+	var users []models.User
+	err := postgres.DB().Where("pin_hash <> '' OR duress_pin_hash <> ''").Order("id").Find(&users).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users with PINs: %w", err)
+	}
+	return users, nil
+}
+
+// UpdatePins replaces the keypad and duress PIN hashes of a user
+func (s UserService) UpdatePins(id uint, pinHash, duressHash string) error {
+	res := postgres.DB().Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"pin_hash": pinHash, "duress_pin_hash": duressHash})
+	if res.Error != nil {
+		return fmt.Errorf("failed to update PINs of user %d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	postgres "backend/database"
+	"backend/database/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type ValueExtractionService struct{}
+
+var ValueExtraction = ValueExtractionService{}
+
+// List returns all configured extractions ordered by sensor type and kind
+func (s ValueExtractionService) List() ([]models.ValueExtraction, error) {
+	var extractions []models.ValueExtraction
+	if err := postgres.DB().Order("sensor_type, kind").Find(&extractions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch value extractions: %w", err)
+	}
+	return extractions, nil
+}
+
+// Get returns the extraction with the given ID, or nil if it doesn't exist
+func (s ValueExtractionService) Get(id uint) (*models.ValueExtraction, error) {
+	var extraction models.ValueExtraction
+	err := postgres.DB().First(&extraction, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch value extraction %d: %w", id, err)
+	}
+	return &extraction, nil
+}
+
+func (s ValueExtractionService) Create(extraction *models.ValueExtraction) error {
+	if err := postgres.DB().Create(extraction).Error; err != nil {
+		return fmt.Errorf("failed to create value extraction: %w", err)
+	}
+	return nil
+}
+
+func (s ValueExtractionService) Update(extraction *models.ValueExtraction) error {
+	if err := postgres.DB().Save(extraction).Error; err != nil {
+		return fmt.Errorf("failed to update value extraction %d: %w", extraction.ID, err)
+	}
+	return nil
+}
+
+// Delete permanently removes an extraction so the sensor type and kind can be configured again
+func (s ValueExtractionService) Delete(id uint) error {
+	res := postgres.DB().Unscoped().Delete(&models.ValueExtraction{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete value extraction %d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	postgres "backend/database"
+	"backend/database/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type ZoneService struct{}
+
+var Zone = ZoneService{}
+
+// List returns all zones ordered by ID, limited to one partition if partitionID is not 0
+func (s ZoneService) List(partitionID uint) ([]models.Zone, error) {
+	var zones []models.Zone
+	query := postgres.DB().Order("id")
+	if partitionID != 0 {
+		query = query.Where("partition_id = ?", partitionID)
+	}
+	if err := query.Find(&zones).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch zones: %w", err)
+	}
+	return zones, nil
+}
+
+// Get returns the zone with the given ID, or nil if it doesn't exist
+func (s ZoneService) Get(id uint) (*models.Zone, error) {
+	var zone models.Zone
+	err := postgres.DB().First(&zone, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch zone %d: %w", id, err)
+	}
+	return &zone, nil
+}
+
+func (s ZoneService) Create(zone *models.Zone) error {
+	if err := postgres.DB().Create(zone).Error; err != nil {
+		return fmt.Errorf("failed to create zone: %w", err)
+	}
+	return nil
+}
+
+func (s ZoneService) Update(zone *models.Zone) error {
+	if err := postgres.DB().Save(zone).Error; err != nil {
+		return fmt.Errorf("failed to update zone %d: %w", zone.ID, err)
+	}
+	return nil
+}
+
+// Delete soft deletes a zone. Zones that still have sensors assigned can't be deleted.
+func (s ZoneService) Delete(id uint) error {
+	db := postgres.DB()
+
+	var sensors int64
+	if err := db.Model(&models.Sensor{}).Where("zone_id = ?", id).Count(&sensors).Error; err != nil {
+		return fmt.Errorf("failed to count sensors of zone %d: %w", id, err)
+	}
+	if sensors > 0 {
+		return ErrConflict
+	}
+
+	res := db.Delete(&models.Zone{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete zone %d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+package actuator
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/alarm"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Topic is the WebSocket topic command and state changes are pushed to
+const Topic = "actuators"
+
+// Event names broadcast on Topic
+const (
+	EventCommand = "command"
+	EventState   = "state"
+)
+
+// MQTT topics the backend subscribes to for replies from actuators
+const (
+	AckTopic   = "actuator/+/ack"
+	StateTopic = "actuator/+/state"
+)
+
+// publishTimeout is how long publishing to the broker may take
+const publishTimeout = 5 * time.Second
+
+var ErrNotConnected = errors.New("not connected to the MQTT broker")
+
+// Publisher publishes MQTT messages, implemented by mqtt.Client
+type Publisher interface {
+	Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token
+}
+
+// Event is broadcast on Topic whenever a command or actuator changes
+type Event struct {
+	Event    string                  `json:"event"`
+	Command  *models.ActuatorCommand `json:"command,omitempty"`
+	Actuator string                  `json:"actuator_id"`
+	State    string                  `json:"state,omitempty"`
+}
+
+// Ack is what a device publishes on actuator/<id>/ack after handling a command
+type Ack struct {
+	CommandID string `json:"command_id"`
+	Status    string `json:"status"` // ok or error
+	Error     string `json:"error"`
+	State     string `json:"state"` // State after the command, optional
+}
+
+// pendingCommand is a command waiting for its ack
+type pendingCommand struct {
+	command *models.ActuatorCommand
+	qos     byte
+	acked   chan struct{}
+}
+
+// Manager sends commands to actuators and follows them until the device
+// acknowledges them, republishing commands that are not acknowledged in time
+type Manager struct {
+	mu        sync.Mutex
+	hub       alarm.Broadcaster
+	publisher Publisher
+	timeout   time.Duration // How long to wait for an ack before publishing again
+	retries   int           // How often a command is published again
+	pending   map[string]*pendingCommand
+}
+
+// NewManager creates an actuator manager. Commands left unfinished by a
+// previous run are marked failed.
+func NewManager(hub alarm.Broadcaster, timeout time.Duration, retries int) *Manager {
+	if n, err := services.ActuatorCommand.FailUnfinished("backend restarted before the command was acknowledged"); err != nil {
+		log.Printf("Error cleaning up unfinished commands: %v", err)
+	} else if n > 0 {
+		log.Printf("Marked %d unfinished actuator commands as failed", n)
+	}
+
+	return &Manager{
+		hub:     hub,
+		timeout: timeout,
+		retries: max(retries, 0),
+		pending: make(map[string]*pendingCommand),
+	}
+}
+
+// SetPublisher sets the MQTT client commands are published with
+func (m *Manager) SetPublisher(publisher Publisher) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.publisher = publisher
+}
+
+// SetTopic returns the MQTT topic commands for an actuator are published to
+func SetTopic(actuatorID string) string {
+	return "actuator/" + actuatorID + "/set"
+}
+
+// Send publishes a command to an actuator and retries it in the background
+// until it is acknowledged. The command_id is added to the command.
+func (m *Manager) Send(actuator *models.Actuator, command map[string]interface{}, by string) (*models.ActuatorCommand, error) {
+	commandID, err := newCommandID()
+	if err != nil {
+		return nil, err
+	}
+
+	body := make(map[string]interface{}, len(command)+1)
+	for k, v := range command {
+		body[k] = v
+	}
+	body["command_id"] = commandID
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode command: %w", err)
+	}
+
+	cmd := &models.ActuatorCommand{
+		CommandID:   commandID,
+		ActuatorID:  actuator.ActuatorID,
+		Payload:     string(payload),
+		Status:      models.CommandPending,
+		RequestedBy: by,
+	}
+	if err := services.ActuatorCommand.Create(cmd); err != nil {
+		return nil, err
+	}
+
+	p := &pendingCommand{command: cmd, qos: actuator.QoS, acked: make(chan struct{})}
+	m.mu.Lock()
+	m.pending[commandID] = p
+	m.mu.Unlock()
+
+	// Publishing waits for the broker, which must not happen on the MQTT
+	// message handler that may have caused this command
+	snapshot := *cmd
+	go m.deliver(p)
+	return &snapshot, nil
+}
+
+// deliver waits for the ack of a command, publishing it again each time
+// the timeout passes without one
+func (m *Manager) deliver(p *pendingCommand) {
+	for attempt := 0; attempt <= m.retries; attempt++ {
+		if attempt > 0 {
+			log.Printf("No ack for command %s to %s, retrying", p.command.CommandID, p.command.ActuatorID)
+		}
+		m.attempt(p)
+
+		select {
+		case <-p.acked:
+			return
+		case <-time.After(m.timeout):
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.pending[p.command.CommandID]; !ok {
+		return
+	}
+	delete(m.pending, p.command.CommandID)
+
+	cmd := p.command
+	cmd.Status = models.CommandTimedOut
+	if cmd.Error == "" {
+		cmd.Error = fmt.Sprintf("not acknowledged after %d attempts", cmd.Attempts)
+	}
+	m.save(cmd)
+	log.Printf("Command %s to %s timed out", cmd.CommandID, cmd.ActuatorID)
+}
+
+// attempt publishes a pending command once
+func (m *Manager) attempt(p *pendingCommand) {
+	m.mu.Lock()
+	if _, ok := m.pending[p.command.CommandID]; !ok {
+		m.mu.Unlock()
+		return
+	}
+	publisher := m.publisher
+	cmd := p.command
+	now := time.Now()
+	cmd.Attempts++
+	cmd.SentAt = &now
+	payload := cmd.Payload
+	topic := SetTopic(cmd.ActuatorID)
+	m.mu.Unlock()
+
+	err := ErrNotConnected
+	if publisher != nil {
+		token := publisher.Publish(topic, p.qos, false, payload)
+		if !token.WaitTimeout(publishTimeout) {
+			err = errors.New("timed out publishing to the broker")
+		} else {
+			err = token.Error()
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.pending[cmd.CommandID]; !ok {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to publish command %s to %s: %v", cmd.CommandID, topic, err)
+		cmd.Error = err.Error()
+	} else {
+		cmd.Status = models.CommandSent
+		cmd.Error = ""
+	}
+	m.save(cmd)
+}
+
+// HandleMessage handles an MQTT message on AckTopic or StateTopic
+func (m *Manager) HandleMessage(topic string, payload []byte) {
+	parts := strings.Split(topic, "/")
+	if len(parts) != 3 || parts[0] != "actuator" {
+		log.Printf("Ignoring message on unexpected actuator topic %s", topic)
+		return
+	}
+	actuatorID := parts[1]
+
+	switch parts[2] {
+	case "ack":
+		var ack Ack
+		if err := json.Unmarshal(payload, &ack); err != nil || ack.CommandID == "" {
+			log.Printf("Ignoring invalid ack from actuator %s: %s", actuatorID, payload)
+			return
+		}
+		m.handleAck(actuatorID, ack)
+		if ack.State != "" {
+			m.setState(actuatorID, ack.State)
+		}
+	case "state":
+		m.setState(actuatorID, parseState(payload))
+	}
+}
+
+// handleAck finishes the command an ack is for. Acks arriving after the
+// command timed out still record the outcome. Acks from another actuator
+// are ignored, so the command keeps being retried.
+func (m *Manager) handleAck(actuatorID string, ack Ack) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var cmd *models.ActuatorCommand
+	p, isPending := m.pending[ack.CommandID]
+	if isPending {
+		cmd = p.command
+	} else {
+		stored, err := services.ActuatorCommand.GetByCommandID(ack.CommandID)
+		if err != nil {
+			log.Printf("Error fetching command: %v", err)
+			return
+		}
+		if stored == nil || stored.Status == models.CommandAcknowledged {
+			return
+		}
+		cmd = stored
+	}
+	if cmd.ActuatorID != actuatorID {
+		log.Printf("Ignoring ack for command %s from %s instead of %s", ack.CommandID, actuatorID, cmd.ActuatorID)
+		return
+	}
+	if isPending {
+		delete(m.pending, ack.CommandID)
+		close(p.acked)
+	}
+
+	now := time.Now()
+	cmd.AcknowledgedAt = &now
+	if ack.Status == "error" {
+		cmd.Status = models.CommandFailed
+		cmd.Error = ack.Error
+		log.Printf("Actuator %s failed command %s: %s", actuatorID, cmd.CommandID, ack.Error)
+	} else {
+		cmd.Status = models.CommandAcknowledged
+		cmd.Error = ""
+	}
+	m.save(cmd)
+}
+
+// setState records the state an actuator reported
+func (m *Manager) setState(actuatorID, state string) {
+	if err := services.Actuator.SetState(actuatorID, state, time.Now()); err != nil {
+		log.Printf("Error saving actuator state: %v", err)
+		return
+	}
+	m.broadcast(Event{Event: EventState, Actuator: actuatorID, State: state})
+}
+
+// save persists and broadcasts a command. Must be called with m.mu held.
+func (m *Manager) save(cmd *models.ActuatorCommand) {
+	if err := services.ActuatorCommand.Update(cmd); err != nil {
+		log.Printf("Error saving command: %v", err)
+	}
+	snapshot := *cmd
+	m.broadcast(Event{Event: EventCommand, Command: &snapshot, Actuator: cmd.ActuatorID})
+}
+
+func (m *Manager) broadcast(event Event) {
+	message, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error marshalling actuator event: %v", err)
+		return
+	}
+	m.hub.BroadcastToTopic(message, Topic)
+}
+
+// parseState reads a state report, either JSON with a state field or the
+// bare state as many devices send it
+func parseState(payload []byte) string {
+	var report struct {
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal(payload, &report); err == nil && report.State != "" {
+		return report.State
+	}
+	return strings.TrimSpace(string(payload))
+}
+
+func newCommandID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate command ID: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
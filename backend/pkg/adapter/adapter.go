@@ -0,0 +1,67 @@
+package adapter
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Adapter translates the topics and payloads of a device ecosystem, like
+// Zigbee2MQTT or Tasmota, into native sensor messages, the ones published on
+// sensor/<id>/<kind>
+type Adapter interface {
+	// Name identifies the adapter in logs and errors
+	Name() string
+	// Subscriptions are the topic filters the adapter's devices publish on
+	Subscriptions() []string
+	// Translate turns a message into native sensor messages. Messages the
+	// adapter has nothing to report for, like bridge logs, give none.
+	Translate(topic string, payload []byte, receivedAt time.Time) ([]Message, error)
+}
+
+// Message is a native sensor message produced by an adapter
+type Message struct {
+	SensorID   string
+	SensorType string // Type unknown sensors are registered with, empty if the adapter can't tell
+	Location   string // Location unknown sensors are registered with, empty if unknown
+	Kind       string
+	Payload    []byte // Payload in the native format of the kind
+}
+
+// Find returns the adapter the topic belongs to, or nil for native topics
+func Find(adapters []Adapter, topic string) Adapter {
+	for _, a := range adapters {
+		for _, filter := range a.Subscriptions() {
+			if MatchTopic(filter, topic) {
+				return a
+			}
+		}
+	}
+	return nil
+}
+
+// MatchTopic reports whether a topic matches an MQTT topic filter with
+// + and # wildcards
+func MatchTopic(filter, topic string) bool {
+	filterParts := strings.Split(filter, "/")
+	topicParts := strings.Split(topic, "/")
+	for i, part := range filterParts {
+		if part == "#" {
+			return true
+		}
+		if i >= len(topicParts) || (part != "+" && part != topicParts[i]) {
+			return false
+		}
+	}
+	return len(filterParts) == len(topicParts)
+}
+
+// message encodes a native payload. Adapters stamp every payload with the
+// time it was received, since the native formats require a timestamp.
+func message(sensorID, sensorType, kind string, receivedAt time.Time, fields map[string]interface{}) Message {
+	fields["sensor_id"] = sensorID
+	fields["timestamp"] = float64(receivedAt.UnixNano()) / 1e9
+	// A map of JSON values can't fail to encode
+	payload, _ := json.Marshal(fields)
+	return Message{SensorID: sensorID, SensorType: sensorType, Kind: kind, Payload: payload}
+}
@@ -0,0 +1,89 @@
+package adapter
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// receivedAt is the time the translated test messages were received
+var receivedAt = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+// translated is a Message with its payload decoded, without the sensor ID
+// and timestamp every payload carries
+type translated struct {
+	SensorID   string
+	SensorType string
+	Kind       string
+	Fields     map[string]interface{}
+}
+
+func decode(t *testing.T, messages []Message) []translated {
+	t.Helper()
+	if len(messages) == 0 {
+		return nil
+	}
+	result := make([]translated, len(messages))
+	for i, msg := range messages {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(msg.Payload, &fields); err != nil {
+			t.Fatalf("payload %s is not JSON: %v", msg.Payload, err)
+		}
+		if fields["sensor_id"] != msg.SensorID {
+			t.Errorf("payload %s has sensor_id %v, want %s", msg.Payload, fields["sensor_id"], msg.SensorID)
+		}
+		if fields["timestamp"] != float64(receivedAt.Unix()) {
+			t.Errorf("payload %s has timestamp %v, want %d", msg.Payload, fields["timestamp"], receivedAt.Unix())
+		}
+		delete(fields, "sensor_id")
+		delete(fields, "timestamp")
+		result[i] = translated{SensorID: msg.SensorID, SensorType: msg.SensorType, Kind: msg.Kind, Fields: fields}
+	}
+	return result
+}
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		filter string
+		topic  string
+		want   bool
+	}{
+		{filter: "tele/+/SENSOR", topic: "tele/plug/SENSOR", want: true},
+		{filter: "tele/+/SENSOR", topic: "tele/plug/STATE", want: false},
+		{filter: "tele/+/SENSOR", topic: "tele/SENSOR", want: false},
+		{filter: "tele/+/SENSOR", topic: "tele/plug/SENSOR/extra", want: false},
+		{filter: "zigbee2mqtt/#", topic: "zigbee2mqtt/front door", want: true},
+		{filter: "zigbee2mqtt/#", topic: "zigbee2mqtt/floor/1/door", want: true},
+		// # also matches the parent level, like brokers do
+		{filter: "zigbee2mqtt/#", topic: "zigbee2mqtt", want: true},
+		{filter: "zigbee2mqtt/#", topic: "tele/plug/SENSOR", want: false},
+		{filter: "sensor/door", topic: "sensor/door", want: true},
+		{filter: "sensor/door", topic: "sensor/window", want: false},
+	}
+	for _, tt := range tests {
+		if got := MatchTopic(tt.filter, tt.topic); got != tt.want {
+			t.Errorf("MatchTopic(%q, %q) = %v, want %v", tt.filter, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	z2m := NewZigbee2MQTT("zigbee2mqtt", "z2m-")
+	tasmota := NewTasmota("tele", "tasmota-")
+	adapters := []Adapter{z2m, tasmota}
+
+	tests := []struct {
+		topic string
+		want  Adapter
+	}{
+		{topic: "zigbee2mqtt/front_door", want: z2m},
+		{topic: "tele/plug/SENSOR", want: tasmota},
+		{topic: "tele/plug/RESULT", want: nil},
+		{topic: "sensor/door-1/alarm", want: nil},
+	}
+	for _, tt := range tests {
+		if got := Find(adapters, tt.topic); got != tt.want {
+			t.Errorf("Find(%q) = %v, want %v", tt.topic, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package adapter
+
+import (
+	"backend/pkg/payloads"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tasmotaMeasurement maps a field of a Tasmota sensor block to a measurement kind
+type tasmotaMeasurement struct {
+	Field string
+	Kind  string
+	Unit  string // Empty for units Tasmota reports itself
+}
+
+var tasmotaMeasurements = []tasmotaMeasurement{
+	{Field: "Temperature", Kind: "temperature"},
+	{Field: "Humidity", Kind: "humidity", Unit: "%"},
+	{Field: "Pressure", Kind: "pressure"},
+	{Field: "Illuminance", Kind: "illuminance", Unit: "lx"},
+	{Field: "CarbonDioxide", Kind: "co2", Unit: "ppm"},
+	{Field: "eCO2", Kind: "co2", Unit: "ppm"},
+}
+
+// Tasmota translates the telemetry Tasmota devices publish on
+// <prefix>/<device>/SENSOR, STATE and LWT. Every device is one sensor. When
+// several sensor blocks report the same measurement, like two temperature
+// probes, only the block first in alphabetical order is used.
+type Tasmota struct {
+	prefix       string
+	sensorPrefix string
+}
+
+// NewTasmota creates the adapter for devices publishing below prefix,
+// usually tele. Sensor IDs are the device topics with sensorPrefix in front.
+func NewTasmota(prefix, sensorPrefix string) *Tasmota {
+	return &Tasmota{prefix: strings.TrimSuffix(prefix, "/"), sensorPrefix: sensorPrefix}
+}
+
+func (t *Tasmota) Name() string {
+	return "tasmota"
+}
+
+func (t *Tasmota) Subscriptions() []string {
+	return []string{t.prefix + "/+/SENSOR", t.prefix + "/+/STATE", t.prefix + "/+/LWT"}
+}
+
+func (t *Tasmota) Translate(topic string, payload []byte, receivedAt time.Time) ([]Message, error) {
+	segments := strings.Split(strings.TrimPrefix(topic, t.prefix+"/"), "/")
+	if len(segments) != 2 {
+		return nil, nil
+	}
+	sensorID := t.sensorPrefix + segments[0]
+
+	switch segments[1] {
+	case "SENSOR":
+		return t.sensor(sensorID, payload, receivedAt)
+	case "STATE":
+		return t.state(sensorID, payload, receivedAt)
+	case "LWT":
+		status := strings.ToLower(strings.TrimSpace(string(payload)))
+		if status != "online" && status != "offline" {
+			return nil, fmt.Errorf("invalid LWT %q", payload)
+		}
+		return []Message{message(sensorID, "", payloads.KindStatus, receivedAt, map[string]interface{}{"status": status})}, nil
+	}
+	return nil, nil
+}
+
+// sensor translates the periodic sensor readings, e.g.
+// {"Time": "...", "AM2301": {"Temperature": 21.3, "Humidity": 45}, "TempUnit": "C"}
+func (t *Tasmota) sensor(sensorID string, payload []byte, receivedAt time.Time) ([]Message, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, fmt.Errorf("invalid SENSOR message: %w", err)
+	}
+
+	units := map[string]string{"temperature": "°C", "pressure": "hPa"}
+	var tempUnit, pressureUnit string
+	if json.Unmarshal(fields["TempUnit"], &tempUnit) == nil && tempUnit != "" {
+		units["temperature"] = "°" + tempUnit
+	}
+	if json.Unmarshal(fields["PressureUnit"], &pressureUnit) == nil && pressureUnit != "" {
+		units["pressure"] = pressureUnit
+	}
+
+	blocks := make([]string, 0, len(fields))
+	for name := range fields {
+		blocks = append(blocks, name)
+	}
+	sort.Strings(blocks)
+
+	var messages []Message
+	seenKinds := make(map[string]bool)
+	for _, name := range blocks {
+		var block map[string]interface{}
+		if json.Unmarshal(fields[name], &block) != nil {
+			// Time, TempUnit and other fields that aren't sensor blocks
+			continue
+		}
+		for _, m := range tasmotaMeasurements {
+			value, ok := block[m.Field].(float64)
+			if !ok || seenKinds[m.Kind] {
+				continue
+			}
+			seenKinds[m.Kind] = true
+			unit := m.Unit
+			if unit == "" {
+				unit = units[m.Kind]
+			}
+			messages = append(messages, message(sensorID, "", m.Kind, receivedAt, map[string]interface{}{"value": value, "unit": unit}))
+		}
+	}
+
+	if len(messages) == 0 {
+		messages = append(messages, message(sensorID, "", payloads.KindHeartbeat, receivedAt, map[string]interface{}{}))
+	}
+	return messages, nil
+}
+
+// state translates the periodic device state into a heartbeat
+func (t *Tasmota) state(sensorID string, payload []byte, receivedAt time.Time) ([]Message, error) {
+	var state struct {
+		UptimeSec *float64 `json:"UptimeSec"`
+		Wifi      struct {
+			Signal *int `json:"Signal"` // dBm
+		} `json:"Wifi"`
+	}
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return nil, fmt.Errorf("invalid STATE message: %w", err)
+	}
+
+	fields := map[string]interface{}{}
+	if state.UptimeSec != nil {
+		fields["uptime"] = *state.UptimeSec
+	}
+	if state.Wifi.Signal != nil {
+		fields["rssi"] = *state.Wifi.Signal
+	}
+	return []Message{message(sensorID, "", payloads.KindHeartbeat, receivedAt, fields)}, nil
+}
@@ -0,0 +1,92 @@
+package adapter
+
+import (
+	"backend/pkg/payloads"
+	"reflect"
+	"testing"
+)
+
+func TestTasmotaTranslate(t *testing.T) {
+	tests := []struct {
+		name    string
+		topic   string
+		payload string
+		want    []translated
+		wantErr bool
+	}{
+		{
+			name:    "sensor readings",
+			topic:   "tele/plug/SENSOR",
+			payload: `{"Time": "2026-10-18T12:00:00", "AM2301": {"Temperature": 21.3, "Humidity": 45}, "TempUnit": "C"}`,
+			want: []translated{
+				{SensorID: "tasmota-plug", Kind: "temperature", Fields: map[string]interface{}{"value": 21.3, "unit": "°C"}},
+				{SensorID: "tasmota-plug", Kind: "humidity", Fields: map[string]interface{}{"value": 45.0, "unit": "%"}},
+			},
+		},
+		{
+			name:    "units reported by the device",
+			topic:   "tele/plug/SENSOR",
+			payload: `{"BME280": {"Temperature": 70.1, "Pressure": 29.9}, "TempUnit": "F", "PressureUnit": "inHg"}`,
+			want: []translated{
+				{SensorID: "tasmota-plug", Kind: "temperature", Fields: map[string]interface{}{"value": 70.1, "unit": "°F"}},
+				{SensorID: "tasmota-plug", Kind: "pressure", Fields: map[string]interface{}{"value": 29.9, "unit": "inHg"}},
+			},
+		},
+		{
+			name:    "first block wins",
+			topic:   "tele/plug/SENSOR",
+			payload: `{"DS18B20": {"Temperature": 19}, "AM2301": {"Temperature": 21}}`,
+			want: []translated{
+				{SensorID: "tasmota-plug", Kind: "temperature", Fields: map[string]interface{}{"value": 21.0, "unit": "°C"}},
+			},
+		},
+		{
+			name:    "no measurements",
+			topic:   "tele/plug/SENSOR",
+			payload: `{"Time": "2026-10-18T12:00:00", "ENERGY": {"Power": 12}}`,
+			want: []translated{
+				{SensorID: "tasmota-plug", Kind: payloads.KindHeartbeat, Fields: map[string]interface{}{}},
+			},
+		},
+		{
+			name:    "state",
+			topic:   "tele/plug/STATE",
+			payload: `{"UptimeSec": 3600, "Wifi": {"Signal": -61}}`,
+			want: []translated{
+				{SensorID: "tasmota-plug", Kind: payloads.KindHeartbeat, Fields: map[string]interface{}{"uptime": 3600.0, "rssi": -61.0}},
+			},
+		},
+		{
+			name:    "online",
+			topic:   "tele/plug/LWT",
+			payload: "Online",
+			want: []translated{
+				{SensorID: "tasmota-plug", Kind: payloads.KindStatus, Fields: map[string]interface{}{"status": "online"}},
+			},
+		},
+		{
+			name:    "offline",
+			topic:   "tele/plug/LWT",
+			payload: "Offline",
+			want: []translated{
+				{SensorID: "tasmota-plug", Kind: payloads.KindStatus, Fields: map[string]interface{}{"status": "offline"}},
+			},
+		},
+		{name: "other message", topic: "tele/plug/RESULT", payload: `{"POWER": "ON"}`},
+		{name: "too many levels", topic: "tele/room/plug/SENSOR", payload: `{}`},
+		{name: "invalid LWT", topic: "tele/plug/LWT", payload: "maybe", wantErr: true},
+		{name: "invalid sensor message", topic: "tele/plug/SENSOR", payload: "not json", wantErr: true},
+		{name: "invalid state", topic: "tele/plug/STATE", payload: "not json", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := NewTasmota("tele/", "tasmota-").Translate(tt.topic, []byte(tt.payload), receivedAt)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Translate error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := decode(t, messages); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Translate = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package adapter
+
+import (
+	"backend/pkg/payloads"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// zigbeeAlarm is a boolean Zigbee2MQTT property that raises an alarm when
+// it changes to Active
+type zigbeeAlarm struct {
+	Property   string
+	Active     bool
+	SensorType string
+	Message    string
+}
+
+// zigbeeAlarms are checked in order, the first one a device reports decides
+// the type it is registered with
+var zigbeeAlarms = []zigbeeAlarm{
+	{Property: "contact", Active: false, SensorType: "door", Message: "Contact opened"},
+	{Property: "occupancy", Active: true, SensorType: "motion", Message: "Motion detected"},
+	{Property: "water_leak", Active: true, SensorType: "water", Message: "Water leak detected"},
+	{Property: "smoke", Active: true, SensorType: "smoke", Message: "Smoke detected"},
+	{Property: "carbon_monoxide", Active: true, SensorType: "co", Message: "Carbon monoxide detected"},
+	{Property: "vibration", Active: true, SensorType: "glass", Message: "Vibration detected"},
+}
+
+// zigbeeMeasurement maps a numeric Zigbee2MQTT property to a measurement kind
+type zigbeeMeasurement struct {
+	Property string
+	Kind     string
+	Unit     string
+}
+
+// zigbeeMeasurements are checked in order, only the first property of a kind is used
+var zigbeeMeasurements = []zigbeeMeasurement{
+	{Property: "temperature", Kind: "temperature", Unit: "°C"},
+	{Property: "humidity", Kind: "humidity", Unit: "%"},
+	{Property: "pressure", Kind: "pressure", Unit: "hPa"},
+	{Property: "illuminance_lux", Kind: "illuminance", Unit: "lx"},
+	{Property: "illuminance", Kind: "illuminance", Unit: "lx"},
+	{Property: "co2", Kind: "co2", Unit: "ppm"},
+}
+
+// Zigbee2MQTT translates the device state Zigbee2MQTT publishes on
+// <base>/<friendly_name> and <base>/<friendly_name>/availability. Zigbee2MQTT
+// publishes the whole state on every change, so alarms, tamper and battery
+// are only reported when they change and measurements when their value does.
+// The state is only kept in memory, so the first state of an alarm property
+// after start-up, usually the retained one, is taken as the baseline and
+// doesn't raise an alarm. Messages without any change count as heartbeats.
+type Zigbee2MQTT struct {
+	baseTopic    string
+	sensorPrefix string
+
+	mu    sync.Mutex
+	state map[string]map[string]interface{} // Last reported properties per sensor
+}
+
+// NewZigbee2MQTT creates the adapter for devices below baseTopic, usually
+// zigbee2mqtt. Sensor IDs are the friendly names with sensorPrefix in front.
+func NewZigbee2MQTT(baseTopic, sensorPrefix string) *Zigbee2MQTT {
+	return &Zigbee2MQTT{
+		baseTopic:    strings.TrimSuffix(baseTopic, "/"),
+		sensorPrefix: sensorPrefix,
+		state:        make(map[string]map[string]interface{}),
+	}
+}
+
+func (z *Zigbee2MQTT) Name() string {
+	return "zigbee2mqtt"
+}
+
+// Subscriptions covers the whole base topic, since friendly names may contain slashes
+func (z *Zigbee2MQTT) Subscriptions() []string {
+	return []string{z.baseTopic + "/#"}
+}
+
+func (z *Zigbee2MQTT) Translate(topic string, payload []byte, receivedAt time.Time) ([]Message, error) {
+	name := strings.TrimPrefix(topic, z.baseTopic+"/")
+	segments := strings.Split(name, "/")
+	switch {
+	case name == topic || segments[0] == "bridge":
+		return nil, nil
+	case segments[len(segments)-1] == "set" || segments[len(segments)-1] == "get":
+		// Commands to devices, ours or someone else's
+		return nil, nil
+	case len(segments) > 2 && (segments[len(segments)-2] == "set" || segments[len(segments)-2] == "get"):
+		return nil, nil
+	}
+
+	if strings.HasSuffix(name, "/availability") {
+		sensorID := z.sensorID(strings.TrimSuffix(name, "/availability"))
+		return z.availability(sensorID, payload, receivedAt)
+	}
+
+	var properties map[string]interface{}
+	if err := json.Unmarshal(payload, &properties); err != nil {
+		return nil, fmt.Errorf("invalid device state: %w", err)
+	}
+	sensorID := z.sensorID(name)
+
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	last := z.state[sensorID]
+	if last == nil {
+		last = make(map[string]interface{})
+		z.state[sensorID] = last
+	}
+	// changed reports whether a property differs from the last message and remembers it
+	changed := func(property string) (interface{}, bool) {
+		value, ok := properties[property]
+		if !ok || value == nil {
+			return nil, false
+		}
+		previous, seen := last[property]
+		last[property] = value
+		return value, !seen || previous != value
+	}
+
+	sensorType := ""
+	var messages []Message
+	for _, a := range zigbeeAlarms {
+		if _, ok := properties[a.Property].(bool); !ok {
+			continue
+		}
+		if sensorType == "" {
+			sensorType = a.SensorType
+		}
+		_, seen := last[a.Property]
+		if value, ok := changed(a.Property); ok && seen && value == a.Active {
+			messages = append(messages, message(sensorID, sensorType, payloads.KindAlarm, receivedAt, map[string]interface{}{"message": a.Message}))
+		}
+	}
+
+	if _, ok := properties["tamper"].(bool); ok {
+		if value, ok := changed("tamper"); ok {
+			messages = append(messages, message(sensorID, sensorType, payloads.KindTamper, receivedAt, map[string]interface{}{"tampered": value}))
+		}
+	}
+	if _, ok := properties["battery"].(float64); ok {
+		if value, ok := changed("battery"); ok {
+			messages = append(messages, message(sensorID, sensorType, payloads.KindBattery, receivedAt, map[string]interface{}{"level": value}))
+		}
+	}
+
+	seenKinds := make(map[string]bool)
+	for _, m := range zigbeeMeasurements {
+		if _, ok := properties[m.Property].(float64); !ok || seenKinds[m.Kind] {
+			continue
+		}
+		seenKinds[m.Kind] = true
+		if value, ok := changed(m.Property); ok {
+			messages = append(messages, message(sensorID, sensorType, m.Kind, receivedAt, map[string]interface{}{"value": value, "unit": m.Unit}))
+		}
+	}
+
+	if len(messages) == 0 {
+		messages = append(messages, message(sensorID, sensorType, payloads.KindHeartbeat, receivedAt, map[string]interface{}{}))
+	}
+	return messages, nil
+}
+
+// availability translates the online/offline state Zigbee2MQTT publishes,
+// either as a bare word or as {"state": "online"}
+func (z *Zigbee2MQTT) availability(sensorID string, payload []byte, receivedAt time.Time) ([]Message, error) {
+	state := strings.TrimSpace(string(payload))
+	var body struct {
+		State string `json:"state"`
+	}
+	if json.Unmarshal(payload, &body) == nil && body.State != "" {
+		state = body.State
+	}
+	if state != "online" && state != "offline" {
+		return nil, fmt.Errorf("invalid availability %q", state)
+	}
+	return []Message{message(sensorID, "", payloads.KindStatus, receivedAt, map[string]interface{}{"status": state})}, nil
+}
+
+func (z *Zigbee2MQTT) sensorID(friendlyName string) string {
+	return z.sensorPrefix + strings.NewReplacer("/", "_", " ", "_").Replace(friendlyName)
+}
@@ -0,0 +1,188 @@
+package adapter
+
+import (
+	"backend/pkg/payloads"
+	"reflect"
+	"testing"
+)
+
+func TestZigbee2MQTTIgnoredTopics(t *testing.T) {
+	z := NewZigbee2MQTT("zigbee2mqtt", "z2m-")
+	topics := []string{
+		"zigbee2mqtt",
+		"zigbee2mqtt/bridge/state",
+		"zigbee2mqtt/bridge/devices",
+		"zigbee2mqtt/front_door/set",
+		"zigbee2mqtt/front_door/get",
+		"zigbee2mqtt/front_door/set/state",
+		"zigbee2mqtt/front_door/get/state",
+		"other/front_door",
+	}
+	for _, topic := range topics {
+		messages, err := z.Translate(topic, []byte(`{"contact": false}`), receivedAt)
+		if err != nil || len(messages) != 0 {
+			t.Errorf("Translate(%q) = %v, %v, want nothing", topic, messages, err)
+		}
+	}
+}
+
+func TestZigbee2MQTTTranslate(t *testing.T) {
+	// Each step is a message on zigbee2mqtt/<device> with the state left by the steps before
+	tests := []struct {
+		name  string
+		steps []struct {
+			device  string
+			payload string
+			want    []translated
+		}
+	}{
+		{
+			name: "contact sensor",
+			steps: []struct {
+				device  string
+				payload string
+				want    []translated
+			}{
+				{
+					// The first state is the baseline, an open door doesn't raise an alarm
+					device:  "front_door",
+					payload: `{"contact": false, "battery": 90, "tamper": false, "linkquality": 120}`,
+					want: []translated{
+						{SensorID: "z2m-front_door", SensorType: "door", Kind: payloads.KindTamper, Fields: map[string]interface{}{"tampered": false}},
+						{SensorID: "z2m-front_door", SensorType: "door", Kind: payloads.KindBattery, Fields: map[string]interface{}{"level": 90.0}},
+					},
+				},
+				{
+					device:  "front_door",
+					payload: `{"contact": true, "battery": 90, "tamper": false}`,
+					want: []translated{
+						{SensorID: "z2m-front_door", SensorType: "door", Kind: payloads.KindHeartbeat, Fields: map[string]interface{}{}},
+					},
+				},
+				{
+					device:  "front_door",
+					payload: `{"contact": false, "battery": 89, "tamper": true}`,
+					want: []translated{
+						{SensorID: "z2m-front_door", SensorType: "door", Kind: payloads.KindAlarm, Fields: map[string]interface{}{"message": "Contact opened"}},
+						{SensorID: "z2m-front_door", SensorType: "door", Kind: payloads.KindTamper, Fields: map[string]interface{}{"tampered": true}},
+						{SensorID: "z2m-front_door", SensorType: "door", Kind: payloads.KindBattery, Fields: map[string]interface{}{"level": 89.0}},
+					},
+				},
+				{
+					device:  "front_door",
+					payload: `{"contact": false, "battery": 89, "tamper": true}`,
+					want: []translated{
+						{SensorID: "z2m-front_door", SensorType: "door", Kind: payloads.KindHeartbeat, Fields: map[string]interface{}{}},
+					},
+				},
+			},
+		},
+		{
+			name: "motion sensor with measurements",
+			steps: []struct {
+				device  string
+				payload string
+				want    []translated
+			}{
+				{
+					device:  "hall/motion",
+					payload: `{"occupancy": false, "illuminance_lux": 12, "illuminance": 300, "temperature": 21.5}`,
+					want: []translated{
+						{SensorID: "z2m-hall_motion", SensorType: "motion", Kind: "temperature", Fields: map[string]interface{}{"value": 21.5, "unit": "°C"}},
+						{SensorID: "z2m-hall_motion", SensorType: "motion", Kind: "illuminance", Fields: map[string]interface{}{"value": 12.0, "unit": "lx"}},
+					},
+				},
+				{
+					device:  "hall/motion",
+					payload: `{"occupancy": true, "illuminance_lux": 12, "illuminance": 310, "temperature": 21.5}`,
+					want: []translated{
+						{SensorID: "z2m-hall_motion", SensorType: "motion", Kind: payloads.KindAlarm, Fields: map[string]interface{}{"message": "Motion detected"}},
+					},
+				},
+			},
+		},
+		{
+			name: "devices are tracked separately",
+			steps: []struct {
+				device  string
+				payload string
+				want    []translated
+			}{
+				{
+					device:  "leak_1",
+					payload: `{"water_leak": false}`,
+					want: []translated{
+						{SensorID: "z2m-leak_1", SensorType: "water", Kind: payloads.KindHeartbeat, Fields: map[string]interface{}{}},
+					},
+				},
+				{
+					device:  "leak 2",
+					payload: `{"water_leak": true}`,
+					want: []translated{
+						{SensorID: "z2m-leak_2", SensorType: "water", Kind: payloads.KindHeartbeat, Fields: map[string]interface{}{}},
+					},
+				},
+				{
+					device:  "leak_1",
+					payload: `{"water_leak": true}`,
+					want: []translated{
+						{SensorID: "z2m-leak_1", SensorType: "water", Kind: payloads.KindAlarm, Fields: map[string]interface{}{"message": "Water leak detected"}},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := NewZigbee2MQTT("zigbee2mqtt/", "z2m-")
+			for i, step := range tt.steps {
+				messages, err := z.Translate("zigbee2mqtt/"+step.device, []byte(step.payload), receivedAt)
+				if err != nil {
+					t.Fatalf("step %d: %v", i, err)
+				}
+				if got := decode(t, messages); !reflect.DeepEqual(got, step.want) {
+					t.Errorf("step %d: Translate(%s) = %+v, want %+v", i, step.payload, got, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestZigbee2MQTTAvailability(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    string // Empty if the payload is invalid
+	}{
+		{payload: "online", want: "online"},
+		{payload: "offline\n", want: "offline"},
+		{payload: `{"state": "online"}`, want: "online"},
+		{payload: `{"state": "offline"}`, want: "offline"},
+		{payload: "unknown"},
+		{payload: `{"state": ""}`},
+	}
+	z := NewZigbee2MQTT("zigbee2mqtt", "z2m-")
+	for _, tt := range tests {
+		messages, err := z.Translate("zigbee2mqtt/front_door/availability", []byte(tt.payload), receivedAt)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("Translate(%q) = %v, want an error", tt.payload, messages)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Translate(%q): %v", tt.payload, err)
+			continue
+		}
+		want := []translated{{SensorID: "z2m-front_door", Kind: payloads.KindStatus, Fields: map[string]interface{}{"status": tt.want}}}
+		if got := decode(t, messages); !reflect.DeepEqual(got, want) {
+			t.Errorf("Translate(%q) = %+v, want %+v", tt.payload, got, want)
+		}
+	}
+}
+
+func TestZigbee2MQTTInvalidState(t *testing.T) {
+	z := NewZigbee2MQTT("zigbee2mqtt", "z2m-")
+	if messages, err := z.Translate("zigbee2mqtt/front_door", []byte("not json"), receivedAt); err == nil {
+		t.Errorf("Translate = %v, want an error", messages)
+	}
+}
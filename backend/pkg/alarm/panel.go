@@ -0,0 +1,541 @@
+package alarm
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// State is the arming state of the alarm panel
+type State string
+
+const (
+	StateDisarmed   State = "disarmed"
+	StateExitDelay  State = "exit_delay"
+	StateArmedHome  State = "armed_home"
+	StateArmedAway  State = "armed_away"
+	StateEntryDelay State = "entry_delay"
+	StateTriggered  State = "triggered"
+)
+
+const (
+	// StateTopic is the WebSocket topic panel state changes are pushed to
+	StateTopic = "alarm-state"
+	// CountdownTopic is the WebSocket topic entry/exit delay ticks are pushed to
+	CountdownTopic = "alarm-countdown"
+	// AlertTopic is the WebSocket topic raised alarms are pushed to
+	AlertTopic = "alerts"
+	// DuressTopic is the restricted WebSocket topic silent duress alarms are pushed to
+	DuressTopic = "alarm-duress"
+)
+
+var (
+	ErrInvalidMode       = errors.New("invalid arming mode")
+	ErrInvalidTransition = errors.New("invalid state transition")
+	ErrArmBlocked        = errors.New("arming blocked by active troubles")
+)
+
+// ArmGuard decides whether a partition may be armed, returning an error
+// wrapping ErrArmBlocked if not. force is set when the user asked to arm anyway.
+type ArmGuard func(partitionID uint, isDefault bool, force bool) error
+
+// AlarmHandler is called with the alarms a panel raises on its own, like an
+// entry delay running out
+type AlarmHandler func(alert Alert)
+
+// StateListener is called with every state change of a panel. It runs with
+// the panel locked, so it must not call back into the panel.
+type StateListener func(change StateChange)
+
+// DuressListener is called when a duress PIN was used, before the action
+// the user was forced to take is carried out
+type DuressListener func(alert DuressAlert)
+
+// Broadcaster publishes messages to WebSocket topics, implemented by websockets.WsHub
+type Broadcaster interface {
+	BroadcastToTopic(message []byte, topic string)
+}
+
+// Status is a snapshot of the panel state
+type Status struct {
+	PartitionID    uint       `json:"partition_id"`
+	State          State      `json:"state"`
+	ArmedMode      State      `json:"armed_mode,omitempty"`
+	TriggeredBy    string     `json:"triggered_by,omitempty"`
+	ChangedBy      string     `json:"changed_by,omitempty"`
+	DelayStartedAt *time.Time `json:"delay_started_at,omitempty"`
+	DelayEndsAt    *time.Time `json:"delay_ends_at,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// StateChange is the event broadcast on StateTopic whenever the panel changes state
+type StateChange struct {
+	Status
+	PreviousState State `json:"previous_state"`
+}
+
+// Countdown is the event broadcast on CountdownTopic every second while an
+// entry or exit delay is running
+type Countdown struct {
+	PartitionID uint      `json:"partition_id"`
+	State       State     `json:"state"`
+	ArmedMode   State     `json:"armed_mode"`
+	SensorID    string    `json:"sensor_id,omitempty"`
+	Remaining   int       `json:"remaining"`
+	EndsAt      time.Time `json:"ends_at"`
+}
+
+// Alert is broadcast on AlertTopic when an alarm is raised by the panel
+// itself, e.g. when an entry delay runs out
+type Alert struct {
+	PartitionID uint   `json:"partition_id"`
+	SensorID    string `json:"sensor_id"`
+	Message     string `json:"message"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+// DuressAlert is broadcast on DuressTopic when a user arms or disarms with
+// their duress PIN
+type DuressAlert struct {
+	PartitionID uint   `json:"partition_id"`
+	User        string `json:"user"`
+	Action      string `json:"action"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+// Panel is the alarm state machine of a single partition. All transitions
+// are persisted before being broadcast.
+type Panel struct {
+	mu          sync.Mutex
+	hub         Broadcaster
+	partitionID uint
+	isDefault   bool // Sensors without a zone belong to the default partition
+	status      Status
+	guard       ArmGuard     // nil if arming is never blocked
+	onAlarm     AlarmHandler // nil if nobody is interested
+	onState     StateListener
+	onDuress    DuressListener
+	// stopDelay cancels the running entry/exit delay countdown, nil if none
+	stopDelay chan struct{}
+}
+
+// NewPanel creates a panel, restoring the last persisted state if there is
+// one. A delay that was running when the backend stopped is resumed, or
+// completed right away if its deadline has already passed.
+func NewPanel(hub Broadcaster, partitionID uint, isDefault bool) (*Panel, error) {
+	p := &Panel{
+		hub:         hub,
+		partitionID: partitionID,
+		isDefault:   isDefault,
+		status: Status{
+			PartitionID: partitionID,
+			State:       StateDisarmed,
+			UpdatedAt:   time.Now(),
+		},
+	}
+
+	saved, err := services.AlarmState.Get(partitionID)
+	if err != nil {
+		return nil, err
+	}
+	if saved != nil {
+		p.status = Status{
+			PartitionID:    partitionID,
+			State:          State(saved.State),
+			ArmedMode:      State(saved.ArmedMode),
+			TriggeredBy:    saved.TriggeredBy,
+			ChangedBy:      saved.ChangedBy,
+			DelayStartedAt: saved.DelayStartedAt,
+			DelayEndsAt:    saved.DelayEndsAt,
+			UpdatedAt:      saved.UpdatedAt,
+		}
+		log.Printf("Restored alarm panel state of partition %d: %s", partitionID, p.status.State)
+	}
+
+	if p.inDelay() && p.status.DelayEndsAt != nil {
+		p.mu.Lock()
+		p.startCountdown()
+		p.mu.Unlock()
+	}
+
+	return p, nil
+}
+
+// Status returns a snapshot of the current panel state
+func (p *Panel) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.status
+}
+
+// Arm starts arming the panel in the given mode. If any sensor has an exit
+// delay configured the panel goes through StateExitDelay first. Arming a
+// triggered panel or one counting down an entry delay is not allowed.
+func (p *Panel) Arm(mode State, by string, force bool) error {
+	if mode != StateArmedHome && mode != StateArmedAway {
+		return ErrInvalidMode
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch p.status.State {
+	case StateTriggered, StateEntryDelay:
+		return fmt.Errorf("%w: disarm the panel first", ErrInvalidTransition)
+	case StateExitDelay:
+		if p.status.ArmedMode == mode {
+			return nil
+		}
+	case mode:
+		return nil
+	}
+
+	if p.guard != nil {
+		if err := p.guard(p.partitionID, p.isDefault, force); err != nil {
+			return err
+		}
+	}
+
+	exitDelay, err := services.Sensor.MaxExitDelay(p.partitionID, p.isDefault)
+	if err != nil {
+		return err
+	}
+
+	p.cancelCountdown()
+	if exitDelay <= 0 {
+		return p.transition(Status{State: mode, ArmedMode: mode, ChangedBy: by})
+	}
+
+	start := time.Now()
+	end := start.Add(time.Duration(exitDelay) * time.Second)
+	if err := p.transition(Status{
+		State:          StateExitDelay,
+		ArmedMode:      mode,
+		ChangedBy:      by,
+		DelayStartedAt: &start,
+		DelayEndsAt:    &end,
+	}); err != nil {
+		return err
+	}
+	p.startCountdown()
+	return nil
+}
+
+// Disarm disarms the panel from any state, cancelling a running delay
+func (p *Panel) Disarm(by string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.status.State == StateDisarmed {
+		return nil
+	}
+
+	p.cancelCountdown()
+	return p.transition(Status{State: StateDisarmed, ChangedBy: by})
+}
+
+// HandleSensorAlarm evaluates an alarm message from a sensor with the given
+// rule against the current state and returns true if the alarm should be
+// raised right away. sensor is nil for sensors that are not registered.
+// Sensors with an entry delay start a countdown instead of raising the
+// alarm immediately.
+func (p *Panel) HandleSensorAlarm(sensorID string, sensor *models.Sensor, rule Rule) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var entryDelay, exitDelay time.Duration
+	if sensor != nil {
+		entryDelay = time.Duration(sensor.EntryDelay) * time.Second
+		exitDelay = time.Duration(sensor.ExitDelay) * time.Second
+	}
+
+	switch p.status.State {
+	case StateTriggered:
+		// Already in alarm, keep reporting sensors that would have triggered it
+		return rule.Triggers(p.status.ArmedMode)
+	case StateExitDelay:
+		// Sensors are ignored until their own exit delay has passed
+		if rule != Rule24h && p.status.DelayStartedAt != nil &&
+			time.Since(*p.status.DelayStartedAt) < exitDelay {
+			return false
+		}
+	case StateEntryDelay:
+		// Further delayed sensors don't restart the countdown, instant ones cut it short
+		if rule != Rule24h && entryDelay > 0 {
+			return false
+		}
+	}
+
+	if !rule.Triggers(p.effectiveState()) {
+		return false
+	}
+
+	if rule != Rule24h && entryDelay > 0 {
+		p.startEntryDelay(sensorID, entryDelay)
+		return false
+	}
+
+	p.trigger(sensorID)
+	return true
+}
+
+// effectiveState is the state alarm rules are evaluated against: the target
+// mode while a delay is running, the current state otherwise
+func (p *Panel) effectiveState() State {
+	if p.inDelay() {
+		return p.status.ArmedMode
+	}
+	return p.status.State
+}
+
+func (p *Panel) inDelay() bool {
+	return p.status.State == StateExitDelay || p.status.State == StateEntryDelay
+}
+
+// startEntryDelay switches to StateEntryDelay. Must be called with p.mu held.
+func (p *Panel) startEntryDelay(sensorID string, delay time.Duration) {
+	p.cancelCountdown()
+
+	start := time.Now()
+	end := start.Add(delay)
+	if err := p.transition(Status{
+		State:          StateEntryDelay,
+		ArmedMode:      p.status.ArmedMode,
+		TriggeredBy:    sensorID,
+		ChangedBy:      sensorID,
+		DelayStartedAt: &start,
+		DelayEndsAt:    &end,
+	}); err != nil {
+		log.Printf("Failed to persist entry delay state: %v", err)
+	}
+	p.startCountdown()
+}
+
+// trigger switches to StateTriggered. Must be called with p.mu held.
+func (p *Panel) trigger(sensorID string) {
+	p.cancelCountdown()
+
+	if err := p.transition(Status{
+		State:       StateTriggered,
+		ArmedMode:   p.status.ArmedMode,
+		TriggeredBy: sensorID,
+		ChangedBy:   sensorID,
+	}); err != nil {
+		log.Printf("Failed to persist triggered state: %v", err)
+	}
+}
+
+// startCountdown starts broadcasting ticks for the delay in p.status and
+// completes it once the deadline passes. Must be called with p.mu held.
+func (p *Panel) startCountdown() {
+	stop := make(chan struct{})
+	p.stopDelay = stop
+
+	tick := Countdown{
+		PartitionID: p.partitionID,
+		State:       p.status.State,
+		ArmedMode:   p.status.ArmedMode,
+		EndsAt:      *p.status.DelayEndsAt,
+	}
+	if p.status.State == StateEntryDelay {
+		tick.SensorID = p.status.TriggeredBy
+	}
+
+	go p.runCountdown(tick, stop)
+}
+
+// cancelCountdown stops the running countdown, if any. Must be called with p.mu held.
+func (p *Panel) cancelCountdown() {
+	if p.stopDelay != nil {
+		close(p.stopDelay)
+		p.stopDelay = nil
+	}
+}
+
+func (p *Panel) runCountdown(tick Countdown, stop chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		remaining := time.Until(tick.EndsAt)
+		if remaining <= 0 {
+			p.completeDelay(stop)
+			return
+		}
+
+		// Round up so keypads show 1 during the last second rather than 0
+		tick.Remaining = int((remaining + time.Second - 1) / time.Second)
+		if message, err := json.Marshal(tick); err == nil {
+			p.hub.BroadcastToTopic(message, CountdownTopic)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// completeDelay finishes the delay owned by stop: an exit delay arms the
+// panel, an entry delay raises the alarm
+func (p *Panel) completeDelay(stop chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The delay was cancelled or replaced while we were waiting for the lock
+	if p.stopDelay != stop {
+		return
+	}
+	p.stopDelay = nil
+
+	switch p.status.State {
+	case StateExitDelay:
+		if err := p.transition(Status{
+			State:     p.status.ArmedMode,
+			ArmedMode: p.status.ArmedMode,
+			ChangedBy: p.status.ChangedBy,
+		}); err != nil {
+			log.Printf("Failed to persist armed state: %v", err)
+		}
+	case StateEntryDelay:
+		p.raise(p.status.TriggeredBy, "entry delay expired")
+	}
+}
+
+// Trigger raises the alarm right away regardless of the armed state, used
+// by automations. by is recorded as what triggered it.
+func (p *Panel) Trigger(by, message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.raise(by, message)
+}
+
+// raise triggers the alarm and reports it on AlertTopic and to the alarm
+// handler. Must be called with p.mu held.
+func (p *Panel) raise(sensorID, message string) {
+	p.trigger(sensorID)
+
+	alert := Alert{
+		PartitionID: p.partitionID,
+		SensorID:    sensorID,
+		Message:     message,
+		Timestamp:   time.Now().Unix(),
+	}
+	data, err := json.Marshal(alert)
+	if err == nil {
+		p.hub.BroadcastToTopic(data, AlertTopic)
+	}
+	if p.onAlarm != nil {
+		p.onAlarm(alert)
+	}
+}
+
+// transition persists and broadcasts a state change. Must be called with p.mu held.
+func (p *Panel) transition(next Status) error {
+	next.PartitionID = p.partitionID
+	next.UpdatedAt = time.Now()
+	if err := services.AlarmState.Save(&models.AlarmState{
+		PartitionID:    next.PartitionID,
+		State:          string(next.State),
+		ArmedMode:      string(next.ArmedMode),
+		TriggeredBy:    next.TriggeredBy,
+		ChangedBy:      next.ChangedBy,
+		DelayStartedAt: next.DelayStartedAt,
+		DelayEndsAt:    next.DelayEndsAt,
+		UpdatedAt:      next.UpdatedAt,
+	}); err != nil {
+		return err
+	}
+
+	change := StateChange{Status: next, PreviousState: p.status.State}
+	p.status = next
+
+	log.Printf("Alarm panel of partition %d %s -> %s (by %s)", p.partitionID, change.PreviousState, next.State, next.ChangedBy)
+	if p.onState != nil {
+		p.onState(change)
+	}
+
+	message, err := json.Marshal(change)
+	if err != nil {
+		log.Printf("Error marshalling state change: %v", err)
+		return nil
+	}
+	p.hub.BroadcastToTopic(message, StateTopic)
+	return nil
+}
+
+// RaiseDuress broadcasts a silent alarm on DuressTopic after a duress PIN
+// was used. The panel state itself is left alone, so to whoever forced the
+// user to enter the code the action looks like it went through normally.
+func (p *Panel) RaiseDuress(user string, action string) {
+	log.Printf("DURESS: %s used a duress PIN to %s partition %d", user, action, p.partitionID)
+
+	alert := DuressAlert{
+		PartitionID: p.partitionID,
+		User:        user,
+		Action:      action,
+		Timestamp:   time.Now().Unix(),
+	}
+	message, err := json.Marshal(alert)
+	if err != nil {
+		log.Printf("Error marshalling duress alert: %v", err)
+		return
+	}
+	p.hub.BroadcastToTopic(message, DuressTopic)
+
+	p.mu.Lock()
+	onDuress := p.onDuress
+	p.mu.Unlock()
+	if onDuress != nil {
+		onDuress(alert)
+	}
+}
+
+// SetArmGuard sets the check that runs before the panel is armed
+func (p *Panel) SetArmGuard(guard ArmGuard) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.guard = guard
+}
+
+// SetAlarmHandler sets the function called when the panel raises an alarm on its own
+func (p *Panel) SetAlarmHandler(handler AlarmHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onAlarm = handler
+}
+
+// SetStateListener sets the function called on every state change
+func (p *Panel) SetStateListener(listener StateListener) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onState = listener
+}
+
+// SetDuressListener sets the function called when a duress PIN was used
+func (p *Panel) SetDuressListener(listener DuressListener) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onDuress = listener
+}
+
+// PartitionID returns the ID of the partition this panel belongs to
+func (p *Panel) PartitionID() uint {
+	return p.partitionID
+}
+
+// Close stops a running countdown without changing the persisted state
+func (p *Panel) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cancelCountdown()
+}
@@ -0,0 +1,83 @@
+package alarm
+
+import "strings"
+
+// Rule decides whether a sensor raises an alarm in a given panel state
+type Rule int
+
+const (
+	// RulePerimeter sensors (doors, windows) trigger in both armed modes
+	RulePerimeter Rule = iota
+	// RuleInterior sensors (motion) only trigger when nobody is home
+	RuleInterior
+	// Rule24h sensors (smoke, water, panic) trigger regardless of the arming state
+	Rule24h
+)
+
+// sensorTypeRules maps a models.Sensor Type to its rule for sensors that are
+// not assigned to a zone. Unknown types are treated as perimeter sensors so
+// they never go unnoticed while armed.
+var sensorTypeRules = map[string]Rule{
+	"door":   RulePerimeter,
+	"window": RulePerimeter,
+	"glass":  RulePerimeter,
+	"motion": RuleInterior,
+	"pir":    RuleInterior,
+	"smoke":  Rule24h,
+	"fire":   Rule24h,
+	"co":     Rule24h,
+	"water":  Rule24h,
+	"panic":  Rule24h,
+}
+
+// Zone types a models.Zone can have
+const (
+	ZonePerimeter = "perimeter"
+	ZoneInterior  = "interior"
+	Zone24hFire   = "24h_fire"
+	Zone24hWater  = "24h_water"
+	ZonePanic     = "panic"
+)
+
+var zoneTypeRules = map[string]Rule{
+	ZonePerimeter: RulePerimeter,
+	ZoneInterior:  RuleInterior,
+	Zone24hFire:   Rule24h,
+	Zone24hWater:  Rule24h,
+	ZonePanic:     Rule24h,
+}
+
+// IsValidZoneType reports whether zoneType is one of the known zone types
+func IsValidZoneType(zoneType string) bool {
+	_, ok := zoneTypeRules[zoneType]
+	return ok
+}
+
+// RuleForZoneType returns the rule that applies to sensors in a zone of the given type
+func RuleForZoneType(zoneType string) Rule {
+	if rule, ok := zoneTypeRules[zoneType]; ok {
+		return rule
+	}
+	return RulePerimeter
+}
+
+// RuleForSensorType returns the rule that applies to the given sensor type
+func RuleForSensorType(sensorType string) Rule {
+	if rule, ok := sensorTypeRules[strings.ToLower(sensorType)]; ok {
+		return rule
+	}
+	return RulePerimeter
+}
+
+// Triggers reports whether a sensor with this rule raises an alarm in the given state
+func (r Rule) Triggers(state State) bool {
+	switch r {
+	case Rule24h:
+		return true
+	case RulePerimeter:
+		return state == StateArmedHome || state == StateArmedAway
+	case RuleInterior:
+		return state == StateArmedAway
+	}
+	return false
+}
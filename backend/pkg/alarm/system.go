@@ -0,0 +1,205 @@
+package alarm
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+)
+
+var ErrUnknownPartition = errors.New("unknown partition")
+
+// System holds the panels of all partitions and routes sensor alarms to the
+// panel of the sensor's zone
+type System struct {
+	mu               sync.RWMutex
+	hub              Broadcaster
+	panels           map[uint]*Panel
+	defaultPartition uint
+	guard            ArmGuard
+	onAlarm          AlarmHandler
+	onState          StateListener
+	onDuress         DuressListener
+}
+
+// NewSystem creates a panel for every partition, making sure the default
+// partition exists
+func NewSystem(hub Broadcaster) (*System, error) {
+	def, err := services.Partition.EnsureDefault()
+	if err != nil {
+		return nil, err
+	}
+
+	partitions, err := services.Partition.List()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &System{
+		hub:              hub,
+		panels:           make(map[uint]*Panel),
+		defaultPartition: def.ID,
+	}
+	for _, partition := range partitions {
+		if err := s.AddPartition(partition.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// AddPartition creates the panel of a newly created partition
+func (s *System) AddPartition(partitionID uint) error {
+	panel, err := NewPanel(s.hub, partitionID, partitionID == s.defaultPartition)
+	if err != nil {
+		return fmt.Errorf("failed to create panel of partition %d: %w", partitionID, err)
+	}
+
+	s.mu.Lock()
+	panel.SetArmGuard(s.guard)
+	panel.SetAlarmHandler(s.onAlarm)
+	panel.SetStateListener(s.onState)
+	panel.SetDuressListener(s.onDuress)
+	s.panels[partitionID] = panel
+	s.mu.Unlock()
+	return nil
+}
+
+// RemovePartition stops and forgets the panel of a deleted partition
+func (s *System) RemovePartition(partitionID uint) {
+	s.mu.Lock()
+	panel, ok := s.panels[partitionID]
+	delete(s.panels, partitionID)
+	s.mu.Unlock()
+
+	if ok {
+		panel.Close()
+	}
+}
+
+// SetArmGuard sets the check that runs before any partition is armed
+func (s *System) SetArmGuard(guard ArmGuard) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.guard = guard
+	for _, panel := range s.panels {
+		panel.SetArmGuard(guard)
+	}
+}
+
+// SetAlarmHandler sets the function called when any panel raises an alarm on its own
+func (s *System) SetAlarmHandler(handler AlarmHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onAlarm = handler
+	for _, panel := range s.panels {
+		panel.SetAlarmHandler(handler)
+	}
+}
+
+// SetStateListener sets the function called on every state change of any panel
+func (s *System) SetStateListener(listener StateListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onState = listener
+	for _, panel := range s.panels {
+		panel.SetStateListener(listener)
+	}
+}
+
+// SetDuressListener sets the function called when a duress PIN was used on any panel
+func (s *System) SetDuressListener(listener DuressListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onDuress = listener
+	for _, panel := range s.panels {
+		panel.SetDuressListener(listener)
+	}
+}
+
+// DefaultPartitionID returns the ID of the partition sensors without a zone belong to
+func (s *System) DefaultPartitionID() uint {
+	return s.defaultPartition
+}
+
+// Panel returns the panel of a partition
+func (s *System) Panel(partitionID uint) (*Panel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	panel, ok := s.panels[partitionID]
+	if !ok {
+		return nil, ErrUnknownPartition
+	}
+	return panel, nil
+}
+
+// Statuses returns the status of every partition ordered by partition ID
+func (s *System) Statuses() []Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make([]Status, 0, len(s.panels))
+	for _, panel := range s.panels {
+		statuses = append(statuses, panel.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].PartitionID < statuses[j].PartitionID
+	})
+	return statuses
+}
+
+// HandleSensorAlarm evaluates an alarm message against the rules of the
+// sensor's zone and the panel of the zone's partition. Sensors without a
+// zone use the default partition and the rule of their sensor type. Returns
+// the partition that evaluated it and true if the alarm should be raised
+// right away.
+func (s *System) HandleSensorAlarm(sensorID string, sensor *models.Sensor) (uint, bool) {
+	panel, zone := s.PanelOf(sensorID, sensor)
+	if panel == nil {
+		return 0, false
+	}
+
+	rule := RulePerimeter
+	if zone != nil {
+		rule = RuleForZoneType(zone.Type)
+	} else if sensor != nil {
+		rule = RuleForSensorType(sensor.Type)
+	}
+
+	return panel.PartitionID(), panel.HandleSensorAlarm(sensorID, sensor, rule)
+}
+
+// PanelOf returns the panel of the partition a sensor belongs to and the
+// sensor's zone. Sensors without a zone, and unregistered ones, belong to
+// the default partition and have a nil zone.
+func (s *System) PanelOf(sensorID string, sensor *models.Sensor) (*Panel, *models.Zone) {
+	partitionID := s.defaultPartition
+	var zone *models.Zone
+	if sensor != nil && sensor.ZoneID != nil {
+		var err error
+		zone, err = services.Zone.Get(*sensor.ZoneID)
+		if err != nil {
+			log.Printf("Failed to look up zone of sensor %s: %v", sensorID, err)
+		} else if zone != nil {
+			partitionID = zone.PartitionID
+		}
+	}
+
+	panel, err := s.Panel(partitionID)
+	if err != nil {
+		log.Printf("Sensor %s belongs to unknown partition %d, using default", sensorID, partitionID)
+		if panel, err = s.Panel(s.defaultPartition); err != nil {
+			return nil, nil
+		}
+	}
+	return panel, zone
+}
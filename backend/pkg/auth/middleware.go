@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type contextKey struct{}
+
+// FromContext returns the claims of the authenticated user, nil for anonymous requests
+func FromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(contextKey{}).(*Claims)
+	return claims
+}
+
+// WithClaims returns a copy of ctx carrying the claims of the authenticated user
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// TokenFromRequest reads a bearer token from the Authorization header, or
+// from the token query parameter since browsers can't set headers on
+// WebSocket connections
+func TokenFromRequest(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	return r.URL.Query().Get("token")
+}
+
+// Authenticate validates the token of a request
+func (m *TokenManager) Authenticate(r *http.Request) (*Claims, error) {
+	token := TokenFromRequest(r)
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+	return m.Verify(token)
+}
+
+// Middleware rejects requests without a valid token, except for the given
+// public paths, and stores the claims of authenticated requests in their context
+func (m *TokenManager) Middleware(next http.Handler, publicPaths ...string) http.Handler {
+	public := make(map[string]bool, len(publicPaths))
+	for _, path := range publicPaths {
+		public[path] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if public[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		claims, err := m.Authenticate(r)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+	})
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PINs are 4 to 8 digits, like on a regular alarm keypad
+const (
+	MinPinLength = 4
+	MaxPinLength = 8
+)
+
+var ErrInvalidPin = errors.New("PIN must be 4 to 8 digits")
+
+// ValidatePin checks that a PIN only has digits and a keypad friendly length
+func ValidatePin(pin string) error {
+	if len(pin) < MinPinLength || len(pin) > MaxPinLength {
+		return ErrInvalidPin
+	}
+	for _, c := range pin {
+		if c < '0' || c > '9' {
+			return ErrInvalidPin
+		}
+	}
+	return nil
+}
+
+// HashPin returns the bcrypt hash of a PIN
+func HashPin(pin string) (string, error) {
+	if err := ValidatePin(pin); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPin reports whether pin matches a hash from HashPin. An empty hash
+// means no PIN is set and never matches.
+func CheckPin(hash, pin string) bool {
+	if hash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pin)) == nil
+}
+
+// Lockout counts failed attempts per key (e.g. the keypad address) and
+// locks the key out for a while once there were too many
+type Lockout struct {
+	mu          sync.Mutex
+	maxAttempts int
+	duration    time.Duration
+	failures    map[string]int
+	lockedUntil map[string]time.Time
+}
+
+// NewLockout creates a lockout that locks a key for duration after
+// maxAttempts failures in a row
+func NewLockout(maxAttempts int, duration time.Duration) *Lockout {
+	return &Lockout{
+		maxAttempts: maxAttempts,
+		duration:    duration,
+		failures:    make(map[string]int),
+		lockedUntil: make(map[string]time.Time),
+	}
+}
+
+// LockedUntil returns when the lockout of key ends, or the zero time if the
+// key is not locked
+func (l *Lockout) LockedUntil(key string) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	until, ok := l.lockedUntil[key]
+	if ok && time.Now().After(until) {
+		delete(l.lockedUntil, key)
+		return time.Time{}
+	}
+	return until
+}
+
+// Fail records a failed attempt and reports whether the key is now locked
+func (l *Lockout) Fail(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.failures[key]++
+	if l.failures[key] < l.maxAttempts {
+		return false
+	}
+	delete(l.failures, key)
+	l.lockedUntil[key] = time.Now().Add(l.duration)
+	return true
+}
+
+// Reset clears the failed attempts of a key after a successful attempt
+func (l *Lockout) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, key)
+}
+
+// RateLimit allows every key a number of attempts within a sliding window
+type RateLimit struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	attempts map[string][]time.Time
+}
+
+// NewRateLimit creates a rate limit of max attempts per key within window
+func NewRateLimit(max int, window time.Duration) *RateLimit {
+	return &RateLimit{max: max, window: window, attempts: make(map[string][]time.Time)}
+}
+
+// Allow records an attempt of key if it is within the limit. Otherwise it
+// returns false and when the next attempt will be allowed.
+func (l *RateLimit) Allow(key string) (bool, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	recent := l.attempts[key][:0]
+	for _, at := range l.attempts[key] {
+		if now.Sub(at) < l.window {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) >= l.max {
+		l.attempts[key] = recent
+		return false, recent[0].Add(l.window)
+	}
+	l.attempts[key] = append(recent, now)
+	return true, time.Time{}
+}
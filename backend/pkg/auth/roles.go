@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"backend/database/models"
+	"errors"
+	"time"
+)
+
+// Role of a user, stored on models.User
+type Role string
+
+const (
+	RoleAdmin     Role = "admin"
+	RoleResident  Role = "resident"
+	RoleGuest     Role = "guest"
+	RoleInstaller Role = "installer"
+)
+
+// Permission is an action a role may be allowed to perform
+type Permission string
+
+const (
+	PermAlarmRead    Permission = "alarm:read"    // See arming state and alerts
+	PermAlarmArm     Permission = "alarm:arm"     // Arm and disarm partitions
+	PermSensorsRead  Permission = "sensors:read"  // See sensors, zones and live sensor messages
+	PermHistoryRead  Permission = "history:read"  // Read stored readings and series
+	PermConfigWrite  Permission = "config:write"  // Change sensors, zones, partitions and extraction rules
+	PermSystemManage Permission = "system:manage" // Inspect and re-process dead letters
+	PermUsersManage  Permission = "users:manage"  // Create, change and delete users
+	PermAlarmDuress  Permission = "alarm:duress"  // Receive silent duress alarms
+	PermAuditRead    Permission = "audit:read"    // Read the audit log, which includes duress events
+)
+
+var rolePermissions = map[Role]map[Permission]bool{
+	RoleAdmin: {
+		PermAlarmRead: true, PermAlarmArm: true, PermSensorsRead: true, PermHistoryRead: true,
+		PermConfigWrite: true, PermSystemManage: true, PermUsersManage: true, PermAlarmDuress: true,
+		PermAuditRead: true,
+	},
+	RoleResident: {
+		PermAlarmRead: true, PermAlarmArm: true, PermSensorsRead: true, PermHistoryRead: true,
+	},
+	RoleGuest: {
+		PermAlarmRead: true, PermAlarmArm: true,
+	},
+	RoleInstaller: {
+		PermAlarmRead: true, PermSensorsRead: true, PermHistoryRead: true,
+		PermConfigWrite: true, PermSystemManage: true,
+	},
+}
+
+var (
+	ErrForbidden     = errors.New("permission denied")
+	ErrOutsideWindow = errors.New("outside of access window")
+)
+
+// IsValidRole reports whether role is one of the known roles
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[Role(role)]
+	return ok
+}
+
+// Can reports whether the role grants a permission
+func (r Role) Can(perm Permission) bool {
+	return rolePermissions[r][perm]
+}
+
+// InAccessWindow reports whether the user may use the system at the given
+// time. Users without a window always may.
+func InAccessWindow(user *models.User, now time.Time) bool {
+	if user.AccessFrom != nil && now.Before(*user.AccessFrom) {
+		return false
+	}
+	if user.AccessUntil != nil && !now.Before(*user.AccessUntil) {
+		return false
+	}
+	return true
+}
+
+// Authorize checks that a user is inside their access window and that
+// their role grants the permission
+func Authorize(user *models.User, perm Permission) error {
+	if !InAccessWindow(user, time.Now()) {
+		return ErrOutsideWindow
+	}
+	if !Role(user.Role).Can(perm) {
+		return ErrForbidden
+	}
+	return nil
+}
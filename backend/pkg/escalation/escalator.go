@@ -0,0 +1,197 @@
+package escalation
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/incident"
+	"backend/pkg/notify"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Escalator walks new incidents through the escalation policy of their
+// partition, notifying one step after another until someone acknowledges
+// the incident. Every step is recorded in the incident's timeline.
+type Escalator struct {
+	mu        sync.Mutex
+	incidents *incident.Manager
+	notifier  *notify.Dispatcher
+	timers    map[uint]*time.Timer // Next step per incident
+}
+
+func NewEscalator(incidents *incident.Manager, notifier *notify.Dispatcher) *Escalator {
+	return &Escalator{
+		incidents: incidents,
+		notifier:  notifier,
+		timers:    make(map[uint]*time.Timer),
+	}
+}
+
+// Start picks up the escalations of incidents that were still open when the
+// backend stopped. Steps that became due meanwhile run right away.
+func (e *Escalator) Start() {
+	incidents, err := services.Incident.ListEscalating()
+	if err != nil {
+		log.Printf("Error fetching escalating incidents: %v", err)
+		return
+	}
+
+	for _, inc := range incidents {
+		policy, err := services.Escalation.Get(*inc.EscalationPolicyID)
+		if err != nil {
+			log.Printf("Error fetching escalation policy: %v", err)
+			continue
+		}
+		if policy == nil || inc.EscalationStep >= len(policy.Steps) {
+			continue
+		}
+
+		from := inc.OpenedAt
+		if inc.EscalatedAt != nil {
+			from = *inc.EscalatedAt
+		}
+		log.Printf("Resuming escalation of incident %d at step %d", inc.ID, inc.EscalationStep+1)
+		e.schedule(inc.ID, policy.ID, inc.EscalationStep, from.Add(stepDelay(policy.Steps[inc.EscalationStep])))
+	}
+}
+
+// HandleIncident is the incident.Listener that starts escalating new
+// incidents and stops once they are acknowledged or closed
+func (e *Escalator) HandleIncident(event incident.Event) {
+	inc := event.Incident
+	switch {
+	case event.Event == incident.EventOpened:
+		// The incident manager is locked while its listener runs
+		go e.begin(inc.ID, inc.PartitionID, inc.OpenedAt)
+	case event.Event == incident.EventState && inc.State != models.IncidentOpen:
+		e.stop(inc.ID, inc.State, event.Entry.Actor)
+	}
+}
+
+// begin attaches the partition's policy to a new incident and schedules the first step
+func (e *Escalator) begin(incidentID, partitionID uint, openedAt time.Time) {
+	policy, err := services.Escalation.ForPartition(partitionID)
+	if err != nil {
+		log.Printf("Error fetching escalation policy: %v", err)
+		return
+	}
+	if policy == nil || len(policy.Steps) == 0 {
+		return
+	}
+
+	if _, err := e.incidents.StartEscalation(incidentID, policy); err != nil {
+		if !errors.Is(err, incident.ErrAlreadyAcknowledged) && !errors.Is(err, incident.ErrClosed) {
+			log.Printf("Error starting escalation of incident %d: %v", incidentID, err)
+		}
+		return
+	}
+	e.schedule(incidentID, policy.ID, 0, openedAt.Add(stepDelay(policy.Steps[0])))
+}
+
+// schedule runs a step of the chain at the given time
+func (e *Escalator) schedule(incidentID, policyID uint, step int, at time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if timer, ok := e.timers[incidentID]; ok {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(max(time.Until(at), 0), func() {
+		e.mu.Lock()
+		current, ok := e.timers[incidentID]
+		if !ok || current != timer {
+			e.mu.Unlock()
+			return
+		}
+		delete(e.timers, incidentID)
+		e.mu.Unlock()
+
+		e.run(incidentID, policyID, step)
+	})
+	e.timers[incidentID] = timer
+}
+
+// stop cancels the next step of an incident that was handled
+func (e *Escalator) stop(incidentID uint, state, by string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if timer, ok := e.timers[incidentID]; ok {
+		timer.Stop()
+		delete(e.timers, incidentID)
+		log.Printf("Escalation of incident %d stopped, %s by %s", incidentID, state, by)
+	}
+}
+
+// run notifies the target of a step and schedules the next one
+func (e *Escalator) run(incidentID, policyID uint, step int) {
+	policy, err := services.Escalation.Get(policyID)
+	if err != nil {
+		log.Printf("Error fetching escalation policy: %v", err)
+		return
+	}
+	// Policies changed while an incident escalates apply from the next step on
+	if policy == nil || step >= len(policy.Steps) {
+		return
+	}
+	target := policy.Steps[step]
+
+	message := fmt.Sprintf("Step %d of %d: notifying %s", step+1, len(policy.Steps), describeTarget(target))
+	inc, err := e.incidents.Escalate(incidentID, step+1, message)
+	if err != nil {
+		if !errors.Is(err, incident.ErrAlreadyAcknowledged) && !errors.Is(err, incident.ErrClosed) {
+			log.Printf("Error escalating incident %d: %v", incidentID, err)
+		}
+		return
+	}
+	log.Printf("Incident %d escalated: %s", incidentID, message)
+
+	event := notify.Event{
+		Type:        models.NotifyEventEscalation,
+		PartitionID: inc.PartitionID,
+		SensorID:    inc.SensorID,
+		Message:     inc.Message,
+		Time:        inc.OpenedAt,
+	}
+	switch target.Target {
+	case models.EscalationTargetUser:
+		var n int
+		if n, err = e.notifier.NotifyUser(*target.UserID, event); err == nil && n == 0 {
+			err = errors.New("no enabled notification preferences")
+		}
+	case models.EscalationTargetContact:
+		err = e.notifier.NotifyContact(target.Name, target.Channel, target.Address, event)
+	default:
+		err = fmt.Errorf("unknown target %s", target.Target)
+	}
+	if err != nil {
+		// The next step still follows, that is what the chain is for
+		note := fmt.Sprintf("Step %d couldn't notify %s: %v", step+1, describeTarget(target), err)
+		log.Printf("Incident %d: %s", incidentID, note)
+		if _, err := e.incidents.AddNote(incidentID, "escalation", note); err != nil {
+			log.Printf("Error adding escalation failure to incident %d: %v", incidentID, err)
+		}
+	}
+
+	if step+1 < len(policy.Steps) {
+		e.schedule(incidentID, policyID, step+1, time.Now().Add(stepDelay(policy.Steps[step+1])))
+	}
+}
+
+func stepDelay(step models.EscalationStep) time.Duration {
+	return time.Duration(step.Delay) * time.Second
+}
+
+func describeTarget(step models.EscalationStep) string {
+	if step.Target == models.EscalationTargetUser {
+		if step.Name != "" {
+			return step.Name
+		}
+		return fmt.Sprintf("user %d", *step.UserID)
+	}
+	return fmt.Sprintf("%s via %s", step.Name, step.Channel)
+}
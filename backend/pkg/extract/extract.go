@@ -0,0 +1,107 @@
+package extract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrNoValue = errors.New("no numeric value at path")
+
+// Rule reads a numeric value and its unit from a JSON payload
+type Rule struct {
+	Metric    string  `json:"metric"`
+	ValuePath string  `json:"value_path"`
+	UnitPath  string  `json:"unit_path,omitempty"`
+	Unit      string  `json:"unit,omitempty"`
+	Scale     float64 `json:"scale,omitempty"`
+	Offset    float64 `json:"offset,omitempty"`
+}
+
+// Value is a metric value extracted from a payload
+type Value struct {
+	Metric string
+	Value  float64
+	Unit   string
+}
+
+// Apply extracts the value described by the rule from a JSON payload
+func (r Rule) Apply(payload []byte) (Value, error) {
+	var doc interface{}
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return Value{}, fmt.Errorf("failed to parse payload: %w", err)
+	}
+
+	raw, ok := lookup(doc, r.ValuePath)
+	if !ok {
+		return Value{}, fmt.Errorf("%w %q", ErrNoValue, r.ValuePath)
+	}
+	value, ok := toFloat(raw)
+	if !ok {
+		return Value{}, fmt.Errorf("%w %q: got %v", ErrNoValue, r.ValuePath, raw)
+	}
+
+	scale := r.Scale
+	if scale == 0 {
+		scale = 1
+	}
+
+	unit := r.Unit
+	if r.UnitPath != "" {
+		if u, ok := lookup(doc, r.UnitPath); ok {
+			if s, ok := u.(string); ok && s != "" {
+				unit = s
+			}
+		}
+	}
+
+	return Value{Metric: r.Metric, Value: value*scale + r.Offset, Unit: unit}, nil
+}
+
+// lookup walks a dotted path such as data.temperature or values.0 through
+// decoded JSON
+func lookup(doc interface{}, path string) (interface{}, bool) {
+	current := doc
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			current = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// toFloat converts JSON numbers, numeric strings and booleans to float64
+func toFloat(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, false
+		}
+		return f, true
+	case bool:
+		if val {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,94 @@
+package extract
+
+import (
+	"backend/database/services"
+	"strings"
+	"sync"
+)
+
+// defaultRules apply to every sensor type unless overridden by a
+// models.ValueExtraction, keyed by message kind
+var defaultRules = map[string]Rule{
+	"temperature": {Metric: "temperature", ValuePath: "value", UnitPath: "unit", Unit: "°C"},
+	"humidity":    {Metric: "humidity", ValuePath: "value", UnitPath: "unit", Unit: "%"},
+	"smoke":       {Metric: "smoke", ValuePath: "value", UnitPath: "unit", Unit: "ppm"},
+	"co":          {Metric: "co", ValuePath: "value", UnitPath: "unit", Unit: "ppm"},
+	"co2":         {Metric: "co2", ValuePath: "value", UnitPath: "unit", Unit: "ppm"},
+	"pressure":    {Metric: "pressure", ValuePath: "value", UnitPath: "unit", Unit: "hPa"},
+	"illuminance": {Metric: "illuminance", ValuePath: "value", UnitPath: "unit", Unit: "lx"},
+	"water":       {Metric: "water", ValuePath: "value", UnitPath: "unit"},
+	"battery":     {Metric: "battery", ValuePath: "level", Unit: "%"},
+}
+
+type ruleKey struct {
+	sensorType string
+	kind       string
+}
+
+// Registry resolves the extraction rule for a sensor type and message kind
+type Registry struct {
+	mu    sync.RWMutex
+	rules map[ruleKey]Rule
+}
+
+// NewRegistry creates a registry holding the configured rules
+func NewRegistry() (*Registry, error) {
+	r := &Registry{}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload replaces the configured rules with the current contents of the database
+func (r *Registry) Reload() error {
+	extractions, err := services.ValueExtraction.List()
+	if err != nil {
+		return err
+	}
+
+	rules := make(map[ruleKey]Rule, len(extractions))
+	for _, e := range extractions {
+		rules[ruleKey{strings.ToLower(e.SensorType), e.Kind}] = Rule{
+			Metric:    e.Metric,
+			ValuePath: e.ValuePath,
+			UnitPath:  e.UnitPath,
+			Unit:      e.Unit,
+			Scale:     e.Scale,
+			Offset:    e.Offset,
+		}
+	}
+
+	r.mu.Lock()
+	r.rules = rules
+	r.mu.Unlock()
+	return nil
+}
+
+// Lookup returns the rule for a message kind from a sensor of the given
+// type. Rules configured for the sensor type win over rules configured for
+// every type, which win over the built-in defaults.
+func (r *Registry) Lookup(sensorType, kind string) (Rule, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if rule, ok := r.rules[ruleKey{strings.ToLower(sensorType), kind}]; ok && sensorType != "" {
+		return rule, true
+	}
+	if rule, ok := r.rules[ruleKey{"", kind}]; ok {
+		return rule, true
+	}
+	rule, ok := defaultRules[kind]
+	return rule, ok
+}
+
+// Extract applies the rule for the sensor type and kind to a payload. ok
+// is false if no rule applies to the message.
+func (r *Registry) Extract(sensorType, kind string, payload []byte) (value Value, ok bool, err error) {
+	rule, ok := r.Lookup(sensorType, kind)
+	if !ok {
+		return Value{}, false, nil
+	}
+	value, err = rule.Apply(payload)
+	return value, true, err
+}
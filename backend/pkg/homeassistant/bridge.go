@@ -0,0 +1,401 @@
+package homeassistant
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/alarm"
+	"backend/pkg/trouble"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Actions Home Assistant sends on the command topic of an alarm panel
+const (
+	ActionArmHome = "ARM_HOME"
+	ActionArmAway = "ARM_AWAY"
+	ActionDisarm  = "DISARM"
+)
+
+// publishTimeout is how long a publish may take before it is given up
+const publishTimeout = 5 * time.Second
+
+var ErrInvalidCommand = errors.New("invalid alarm panel command")
+
+// panelStates maps the state of a panel to the state Home Assistant expects
+var panelStates = map[alarm.State]string{
+	alarm.StateDisarmed:   "disarmed",
+	alarm.StateExitDelay:  "arming",
+	alarm.StateArmedHome:  "armed_home",
+	alarm.StateArmedAway:  "armed_away",
+	alarm.StateEntryDelay: "pending",
+	alarm.StateTriggered:  "triggered",
+}
+
+// invalidIDChars are the characters not allowed in unique IDs and discovery topics
+var invalidIDChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// Publisher publishes MQTT messages, implemented by mqtt.Client
+type Publisher interface {
+	Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token
+	IsConnectionOpen() bool
+}
+
+// Config of the Home Assistant bridge
+type Config struct {
+	DiscoveryPrefix   string        // Topic prefix Home Assistant watches for discovery configs
+	BaseTopic         string        // Prefix of the state and command topics
+	NodeID            string        // Identifies this backend in unique IDs and discovery topics
+	AvailabilityTopic string        // Retained online/offline status of the backend
+	RequireCode       bool          // Whether arming and disarming from Home Assistant needs a PIN
+	SyncInterval      time.Duration // How often the discovery configs are published again, 0 to only publish on changes
+}
+
+// Command is an arm or disarm request from Home Assistant
+type Command struct {
+	PartitionID uint
+	Action      string
+	Code        string
+}
+
+type message struct {
+	topic    string
+	payload  string
+	retained bool
+}
+
+// Bridge makes the alarm panels and sensors show up in Home Assistant
+// through MQTT discovery and mirrors their state to Home Assistant's topics.
+// Arm and disarm commands from Home Assistant are parsed here but carried
+// out by the caller, which decides who is acting.
+type Bridge struct {
+	mu        sync.Mutex
+	system    *alarm.System
+	config    Config
+	publisher Publisher
+	published map[string]bool // Discovery topics of the last sync, so removed entities can be cleared
+	syncs     chan struct{}
+	messages  chan message
+}
+
+func NewBridge(system *alarm.System, config Config) *Bridge {
+	return &Bridge{
+		system:    system,
+		config:    config,
+		published: make(map[string]bool),
+		syncs:     make(chan struct{}, 1),
+		messages:  make(chan message, 256),
+	}
+}
+
+// SetPublisher sets the MQTT client configs and states are published with
+func (b *Bridge) SetPublisher(publisher Publisher) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.publisher = publisher
+}
+
+// Start publishes states as they change and discovery configs whenever a
+// sync is requested or the sync interval passes
+func (b *Bridge) Start() {
+	go b.run()
+}
+
+func (b *Bridge) run() {
+	var tick <-chan time.Time
+	if b.config.SyncInterval > 0 {
+		ticker := time.NewTicker(b.config.SyncInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-b.syncs:
+			b.sync()
+		case <-tick:
+			b.sync()
+		case msg := <-b.messages:
+			b.publish(msg.topic, msg.payload, msg.retained)
+		}
+	}
+}
+
+// Sync requests publishing the discovery configs and current states of all
+// partitions and approved sensors. Call it after connecting and whenever
+// Home Assistant comes back online.
+func (b *Bridge) Sync() {
+	select {
+	case b.syncs <- struct{}{}:
+	default:
+		// A sync is already pending
+	}
+}
+
+// CommandTopic is the topic filter to subscribe to for alarm panel commands
+func (b *Bridge) CommandTopic() string {
+	return b.config.BaseTopic + "/partition/+/set"
+}
+
+// StatusTopic is the topic Home Assistant announces itself on
+func (b *Bridge) StatusTopic() string {
+	return b.config.DiscoveryPrefix + "/status"
+}
+
+// HandleStatus republishes everything when Home Assistant comes online,
+// since it may have lost the entities while it was gone
+func (b *Bridge) HandleStatus(payload []byte) {
+	if strings.TrimSpace(string(payload)) == "online" {
+		b.Sync()
+	}
+}
+
+// ParseCommand reads a command from a message on the command topic. The
+// payload is the JSON rendered by the panel's command template, a bare
+// action is accepted too.
+func (b *Bridge) ParseCommand(topic string, payload []byte) (Command, error) {
+	prefix := b.config.BaseTopic + "/partition/"
+	if !strings.HasPrefix(topic, prefix) || !strings.HasSuffix(topic, "/set") {
+		return Command{}, fmt.Errorf("%w: unexpected topic %s", ErrInvalidCommand, topic)
+	}
+	id, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(topic, prefix), "/set"), 10, 64)
+	if err != nil {
+		return Command{}, fmt.Errorf("%w: invalid partition in topic %s", ErrInvalidCommand, topic)
+	}
+
+	command := Command{PartitionID: uint(id)}
+	var body struct {
+		Action string `json:"action"`
+		Code   string `json:"code"`
+	}
+	if err := json.Unmarshal(payload, &body); err == nil {
+		command.Action, command.Code = body.Action, body.Code
+	} else {
+		command.Action = strings.TrimSpace(string(payload))
+	}
+
+	switch command.Action {
+	case ActionArmHome, ActionArmAway, ActionDisarm:
+		return command, nil
+	}
+	return Command{}, fmt.Errorf("%w: unsupported action %q", ErrInvalidCommand, command.Action)
+}
+
+// ArmMode returns the panel state an arm command asks for
+func (c Command) ArmMode() alarm.State {
+	if c.Action == ActionArmHome {
+		return alarm.StateArmedHome
+	}
+	return alarm.StateArmedAway
+}
+
+// HandleStateChange is the alarm.StateListener that mirrors panel states
+func (b *Bridge) HandleStateChange(change alarm.StateChange) {
+	b.queue(b.partitionTopic(change.PartitionID, "state"), panelStates[change.State], true)
+}
+
+// HandleTrouble is the trouble.Listener that mirrors the tamper, battery
+// and connectivity entities of sensors
+func (b *Bridge) HandleTrouble(event trouble.Event) {
+	if event.Event != trouble.EventRaised && event.Event != trouble.EventCleared {
+		return
+	}
+	raised := event.Event == trouble.EventRaised
+	sensorID := event.Trouble.SensorID
+	switch event.Trouble.Type {
+	case models.TroubleTamper:
+		b.queue(b.sensorTopic(sensorID, entityTamper), onOff(raised), true)
+	case models.TroubleLowBattery:
+		b.queue(b.sensorTopic(sensorID, entityBattery), onOff(raised), true)
+	case models.TroubleSupervision:
+		b.queue(b.sensorTopic(sensorID, entityConnectivity), onOff(!raised), true)
+	}
+}
+
+// HandleReading mirrors a reading of an approved sensor. alarmed is set for
+// live alarm messages. Sensors or metrics Home Assistant doesn't know about
+// yet are announced first.
+func (b *Bridge) HandleReading(sensor *models.Sensor, reading *models.SensorReading, alarmed bool) {
+	if sensor.Status != models.SensorStatusActive {
+		return
+	}
+
+	b.mu.Lock()
+	known := b.published[b.configTopic(componentBinarySensor, sensor.SensorID, entityAlarm)] &&
+		(reading.Metric == "" || b.published[b.configTopic(componentSensor, sensor.SensorID, "metric", reading.Metric)])
+	b.mu.Unlock()
+	if !known {
+		b.Sync()
+	}
+
+	if alarmed {
+		// Not retained, Home Assistant would show an old alarm again after a restart
+		b.queue(b.sensorTopic(sensor.SensorID, entityAlarm), "ON", false)
+	}
+	if reading.Metric != "" {
+		b.queue(b.metricTopic(sensor.SensorID, reading.Metric), formatValue(reading.Value), true)
+	}
+}
+
+// queue hands a state to the publishing goroutine. Listeners call it with
+// other components locked, so it must not block.
+func (b *Bridge) queue(topic, payload string, retained bool) {
+	select {
+	case b.messages <- message{topic, payload, retained}:
+	default:
+		log.Printf("Home Assistant queue full, dropping state for %s", topic)
+	}
+}
+
+// sync publishes the discovery config and current state of every entity and
+// clears the configs of entities that no longer exist
+func (b *Bridge) sync() {
+	partitions, err := services.Partition.List()
+	if err != nil {
+		log.Printf("Error syncing Home Assistant: %v", err)
+		return
+	}
+	sensors, err := services.Sensor.ListActive()
+	if err != nil {
+		log.Printf("Error syncing Home Assistant: %v", err)
+		return
+	}
+	troubles, err := services.Trouble.ListActive()
+	if err != nil {
+		log.Printf("Error syncing Home Assistant: %v", err)
+		return
+	}
+	active := make(map[string]bool, len(troubles))
+	for _, t := range troubles {
+		active[t.SensorID+"/"+t.Type] = true
+	}
+
+	topics := make(map[string]bool)
+	announce := func(component string, config entityConfig, object ...interface{}) {
+		topic := b.configTopic(component, object...)
+		data, err := json.Marshal(config)
+		if err != nil {
+			log.Printf("Error encoding Home Assistant config for %s: %v", topic, err)
+			return
+		}
+		topics[topic] = true
+		b.publish(topic, string(data), true)
+	}
+
+	for i := range partitions {
+		partition := &partitions[i]
+		announce(componentAlarmPanel, b.panelConfig(partition), "partition", partition.ID)
+		if panel, err := b.system.Panel(partition.ID); err == nil {
+			b.publish(b.partitionTopic(partition.ID, "state"), panelStates[panel.Status().State], true)
+		}
+	}
+
+	for i := range sensors {
+		sensor := &sensors[i]
+		for _, entity := range []string{entityAlarm, entityTamper, entityBattery, entityConnectivity} {
+			announce(componentBinarySensor, b.binaryConfig(sensor, entity), sensor.SensorID, entity)
+		}
+		b.publish(b.sensorTopic(sensor.SensorID, entityTamper), onOff(active[sensor.SensorID+"/"+models.TroubleTamper]), true)
+		b.publish(b.sensorTopic(sensor.SensorID, entityBattery), onOff(active[sensor.SensorID+"/"+models.TroubleLowBattery]), true)
+		b.publish(b.sensorTopic(sensor.SensorID, entityConnectivity), onOff(sensor.Online), true)
+
+		readings, err := services.SensorReading.GetLatestMetrics(sensor.SensorID)
+		if err != nil {
+			log.Printf("Error syncing Home Assistant: %v", err)
+			continue
+		}
+		for _, reading := range readings {
+			announce(componentSensor, b.metricConfig(sensor, reading.Metric, reading.Unit), sensor.SensorID, "metric", reading.Metric)
+			b.publish(b.metricTopic(sensor.SensorID, reading.Metric), formatValue(reading.Value), true)
+		}
+	}
+
+	b.mu.Lock()
+	var removed []string
+	for topic := range b.published {
+		if !topics[topic] {
+			removed = append(removed, topic)
+		}
+	}
+	b.published = topics
+	b.mu.Unlock()
+
+	// An empty retained config removes the entity from Home Assistant
+	for _, topic := range removed {
+		b.publish(topic, "", true)
+	}
+}
+
+func (b *Bridge) publish(topic, payload string, retained bool) {
+	b.mu.Lock()
+	publisher := b.publisher
+	b.mu.Unlock()
+	// Everything is published again by the sync after reconnecting
+	if publisher == nil || !publisher.IsConnectionOpen() {
+		return
+	}
+
+	token := publisher.Publish(topic, 1, retained, payload)
+	if !token.WaitTimeout(publishTimeout) {
+		log.Printf("Timed out publishing to %s", topic)
+	} else if token.Error() != nil {
+		log.Printf("Failed to publish to %s: %v", topic, token.Error())
+	}
+}
+
+// configTopic is the discovery topic of an entity
+func (b *Bridge) configTopic(component string, object ...interface{}) string {
+	return b.config.DiscoveryPrefix + "/" + component + "/" + b.config.NodeID + "/" + objectID(object...) + "/config"
+}
+
+func (b *Bridge) partitionTopic(partitionID uint, suffix string) string {
+	return fmt.Sprintf("%s/partition/%d/%s", b.config.BaseTopic, partitionID, suffix)
+}
+
+func (b *Bridge) sensorTopic(sensorID, entity string) string {
+	return b.config.BaseTopic + "/sensor/" + sanitize(sensorID) + "/" + sanitize(entity)
+}
+
+// metricTopic is the state topic of a metric. Metrics get their own level,
+// since a metric like battery would otherwise share the topic of the binary
+// entity of the same name.
+func (b *Bridge) metricTopic(sensorID, metric string) string {
+	return b.config.BaseTopic + "/sensor/" + sanitize(sensorID) + "/metric/" + sanitize(metric)
+}
+
+// uniqueID identifies an entity or device across Home Assistant restarts
+func (b *Bridge) uniqueID(parts ...interface{}) string {
+	return b.config.NodeID + "_" + objectID(parts...)
+}
+
+// objectID joins the parts that identify an entity
+func objectID(parts ...interface{}) string {
+	ids := make([]string, len(parts))
+	for i, part := range parts {
+		ids[i] = sanitize(fmt.Sprint(part))
+	}
+	return strings.Join(ids, "_")
+}
+
+func sanitize(s string) string {
+	return invalidIDChars.ReplaceAllString(s, "_")
+}
+
+func onOff(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
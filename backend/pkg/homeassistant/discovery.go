@@ -0,0 +1,192 @@
+package homeassistant
+
+import (
+	"backend/database/models"
+	"strings"
+)
+
+// Home Assistant entity components we publish discovery configs for
+const (
+	componentAlarmPanel   = "alarm_control_panel"
+	componentBinarySensor = "binary_sensor"
+	componentSensor       = "sensor"
+)
+
+// Entities every approved sensor gets besides its metrics
+const (
+	entityAlarm        = "alarm"
+	entityTamper       = "tamper"
+	entityBattery      = "battery"
+	entityConnectivity = "connectivity"
+)
+
+// alarmOffDelay is how many seconds Home Assistant shows a sensor as
+// alarmed after an alarm message, since sensors don't report the end of one
+const alarmOffDelay = 30
+
+// binaryDeviceClasses maps a models.Sensor Type to the device class of its
+// alarm entity. Unknown types show up as a generic safety sensor.
+var binaryDeviceClasses = map[string]string{
+	"door":   "door",
+	"window": "window",
+	"glass":  "vibration",
+	"motion": "motion",
+	"pir":    "motion",
+	"smoke":  "smoke",
+	"fire":   "smoke",
+	"co":     "carbon_monoxide",
+	"water":  "moisture",
+	"panic":  "safety",
+}
+
+// metricDeviceClasses maps reading metrics to the device class of their
+// sensor entity. Metrics without one are shown as plain numbers.
+var metricDeviceClasses = map[string]string{
+	"temperature": "temperature",
+	"humidity":    "humidity",
+	"pressure":    "pressure",
+	"illuminance": "illuminance",
+	"co":          "carbon_monoxide",
+	"co2":         "carbon_dioxide",
+	"battery":     "battery",
+}
+
+// device groups entities in Home Assistant
+type device struct {
+	Identifiers   []string `json:"identifiers"`
+	Name          string   `json:"name"`
+	Manufacturer  string   `json:"manufacturer,omitempty"`
+	Model         string   `json:"model,omitempty"`
+	SuggestedArea string   `json:"suggested_area,omitempty"`
+	ViaDevice     string   `json:"via_device,omitempty"`
+}
+
+// entityConfig is the discovery payload of an entity. Fields that don't
+// apply to the entity's component are left empty.
+type entityConfig struct {
+	Name              *string `json:"name"` // nil names the entity after its device
+	UniqueID          string  `json:"unique_id"`
+	Device            device  `json:"device"`
+	StateTopic        string  `json:"state_topic"`
+	AvailabilityTopic string  `json:"availability_topic,omitempty"`
+	DeviceClass       string  `json:"device_class,omitempty"`
+	EntityCategory    string  `json:"entity_category,omitempty"`
+
+	// binary_sensor
+	OffDelay int `json:"off_delay,omitempty"`
+
+	// sensor
+	UnitOfMeasurement string `json:"unit_of_measurement,omitempty"`
+	StateClass        string `json:"state_class,omitempty"`
+
+	// alarm_control_panel
+	CommandTopic       string   `json:"command_topic,omitempty"`
+	CommandTemplate    string   `json:"command_template,omitempty"`
+	Code               string   `json:"code,omitempty"`
+	CodeArmRequired    *bool    `json:"code_arm_required,omitempty"`
+	CodeDisarmRequired *bool    `json:"code_disarm_required,omitempty"`
+	SupportedFeatures  []string `json:"supported_features,omitempty"`
+}
+
+// panelDevice is the device the alarm panels of all partitions belong to
+func (b *Bridge) panelDevice() device {
+	return device{
+		Identifiers:  []string{b.config.NodeID},
+		Name:         "Home Security",
+		Manufacturer: "home-sec-proj",
+		Model:        "Alarm panel",
+	}
+}
+
+func (b *Bridge) sensorDevice(sensor *models.Sensor) device {
+	name := sensor.Name
+	if name == "" {
+		name = sensor.SensorID
+	}
+	return device{
+		Identifiers:   []string{b.uniqueID(sensor.SensorID)},
+		Name:          name,
+		Model:         sensor.Type,
+		SuggestedArea: sensor.Location,
+		ViaDevice:     b.config.NodeID,
+	}
+}
+
+// panelConfig describes the alarm panel of a partition. With RequireCode
+// Home Assistant asks for a PIN and sends it along with the action.
+func (b *Bridge) panelConfig(partition *models.Partition) entityConfig {
+	name := partition.Name
+	config := entityConfig{
+		Name:              &name,
+		UniqueID:          b.uniqueID("partition", partition.ID),
+		Device:            b.panelDevice(),
+		StateTopic:        b.partitionTopic(partition.ID, "state"),
+		AvailabilityTopic: b.config.AvailabilityTopic,
+		CommandTopic:      b.partitionTopic(partition.ID, "set"),
+		SupportedFeatures: []string{"arm_home", "arm_away"},
+	}
+	if b.config.RequireCode {
+		config.Code = "REMOTE_CODE"
+		config.CommandTemplate = `{"action": "{{ action }}", "code": "{{ code }}"}`
+	} else {
+		no := false
+		config.CodeArmRequired = &no
+		config.CodeDisarmRequired = &no
+		config.CommandTemplate = `{"action": "{{ action }}"}`
+	}
+	return config
+}
+
+// binaryConfig describes one of the on/off entities of a sensor
+func (b *Bridge) binaryConfig(sensor *models.Sensor, entity string) entityConfig {
+	config := entityConfig{
+		UniqueID:          b.uniqueID(sensor.SensorID, entity),
+		Device:            b.sensorDevice(sensor),
+		StateTopic:        b.sensorTopic(sensor.SensorID, entity),
+		AvailabilityTopic: b.config.AvailabilityTopic,
+	}
+	switch entity {
+	case entityAlarm:
+		// The main entity of the device, named after it
+		config.DeviceClass = binaryDeviceClasses[strings.ToLower(sensor.Type)]
+		if config.DeviceClass == "" {
+			config.DeviceClass = "safety"
+		}
+		config.OffDelay = alarmOffDelay
+	case entityTamper:
+		config.Name = title("Tamper")
+		config.DeviceClass = "tamper"
+		config.EntityCategory = "diagnostic"
+	case entityBattery:
+		config.Name = title("Battery low")
+		config.DeviceClass = "battery"
+		config.EntityCategory = "diagnostic"
+	case entityConnectivity:
+		config.Name = title("Connectivity")
+		config.DeviceClass = "connectivity"
+		config.EntityCategory = "diagnostic"
+	}
+	return config
+}
+
+// metricConfig describes the numeric entity of a metric a sensor reports
+func (b *Bridge) metricConfig(sensor *models.Sensor, metric, unit string) entityConfig {
+	return entityConfig{
+		Name:              title(strings.ReplaceAll(metric, "_", " ")),
+		UniqueID:          b.uniqueID(sensor.SensorID, "metric", metric),
+		Device:            b.sensorDevice(sensor),
+		StateTopic:        b.metricTopic(sensor.SensorID, metric),
+		AvailabilityTopic: b.config.AvailabilityTopic,
+		DeviceClass:       metricDeviceClasses[metric],
+		UnitOfMeasurement: unit,
+		StateClass:        "measurement",
+	}
+}
+
+// title capitalizes the first letter of an entity name
+func title(name string) *string {
+	if name != "" {
+		name = strings.ToUpper(name[:1]) + name[1:]
+	}
+	return &name
+}
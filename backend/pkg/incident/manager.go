@@ -0,0 +1,342 @@
+package incident
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/alarm"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Topic is the WebSocket topic incident changes are pushed to
+const Topic = "incidents"
+
+// Event names broadcast on Topic
+const (
+	EventOpened   = "opened"
+	EventAlarm    = "alarm"
+	EventState    = "state"
+	EventAssigned = "assigned"
+	EventNote     = "note"
+	EventEscalate = "escalate"
+)
+
+var (
+	ErrClosed              = errors.New("incident is already closed")
+	ErrAlreadyAcknowledged = errors.New("incident is already acknowledged")
+)
+
+// Event is broadcast on Topic whenever an incident changes
+type Event struct {
+	Event    string                `json:"event"`
+	Incident *models.Incident      `json:"incident"`
+	Entry    *models.IncidentEvent `json:"entry,omitempty"`
+}
+
+// Listener is called with every incident event. It runs with the manager
+// locked, so it must not call back into the manager.
+type Listener func(event Event)
+
+// Manager opens incidents for raised alarms and moves them through their
+// lifecycle. Alarms of a partition are grouped into its open incident until
+// that is resolved.
+type Manager struct {
+	mu       sync.Mutex
+	hub      alarm.Broadcaster
+	listener Listener
+}
+
+func NewManager(hub alarm.Broadcaster) *Manager {
+	return &Manager{hub: hub}
+}
+
+// SetListener sets the function called with every incident event
+func (m *Manager) SetListener(listener Listener) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listener = listener
+}
+
+// HandleAlert is the alarm.AlarmHandler for alarms raised by the panels
+// themselves, like an entry delay running out
+func (m *Manager) HandleAlert(alert alarm.Alert) {
+	m.RecordAlarm(alert.PartitionID, alert.SensorID, nil, alert.Message)
+}
+
+// RecordAlarm adds a raised alarm to the open incident of the partition,
+// opening a new incident if there is none. readingID links the sensor
+// reading that raised it, if any.
+func (m *Manager) RecordAlarm(partitionID uint, sensorID string, readingID *int, message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	incident, err := services.Incident.GetOpen(partitionID)
+	if err != nil {
+		log.Printf("Error fetching open incident: %v", err)
+		return
+	}
+
+	event := EventAlarm
+	now := time.Now()
+	if incident == nil {
+		incident = &models.Incident{
+			PartitionID: partitionID,
+			State:       models.IncidentOpen,
+			SensorID:    sensorID,
+			Message:     message,
+			OpenedAt:    now,
+		}
+		if err := services.Incident.Create(incident); err != nil {
+			log.Printf("Error opening incident: %v", err)
+			return
+		}
+		event = EventOpened
+		log.Printf("Opened incident %d in partition %d for sensor %s", incident.ID, partitionID, sensorID)
+	}
+
+	incident.AlarmCount++
+	if err := services.Incident.Update(incident); err != nil {
+		log.Printf("Error updating incident: %v", err)
+		return
+	}
+
+	entry := &models.IncidentEvent{
+		IncidentID:      incident.ID,
+		Type:            models.IncidentEventAlarm,
+		Actor:           sensorID,
+		SensorReadingID: readingID,
+		Message:         message,
+		CreatedAt:       now,
+	}
+	m.addEvent(event, incident, entry)
+}
+
+// Acknowledge records that a user is looking into an open incident
+func (m *Manager) Acknowledge(id uint, by string) (*models.Incident, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	incident, err := m.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if incident.State == models.IncidentAcknowledged {
+		return incident, ErrAlreadyAcknowledged
+	}
+
+	now := time.Now()
+	incident.State = models.IncidentAcknowledged
+	incident.AcknowledgedAt = &now
+	incident.AcknowledgedBy = by
+	if incident.Assignee == "" {
+		incident.Assignee = by
+	}
+	return incident, m.changeState(incident, by, "Acknowledged")
+}
+
+// Resolve closes an incident, either as resolved or as a false alarm
+func (m *Manager) Resolve(id uint, by string, falseAlarm bool, note string) (*models.Incident, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	incident, err := m.load(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	incident.State = models.IncidentResolved
+	message := "Resolved"
+	if falseAlarm {
+		incident.State = models.IncidentFalseAlarm
+		message = "Marked as false alarm"
+	}
+	if note != "" {
+		message += ": " + note
+	}
+	incident.ResolvedAt = &now
+	incident.ResolvedBy = by
+	return incident, m.changeState(incident, by, message)
+}
+
+// Assign hands an incident to a user, an empty assignee unassigns it
+func (m *Manager) Assign(id uint, assignee, by string) (*models.Incident, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	incident, err := m.load(id)
+	if err != nil {
+		return nil, err
+	}
+
+	incident.Assignee = assignee
+	if err := services.Incident.Update(incident); err != nil {
+		return nil, err
+	}
+
+	message := "Assigned to " + assignee
+	if assignee == "" {
+		message = "Unassigned"
+	}
+	m.addEvent(EventAssigned, incident, &models.IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       models.IncidentEventAssigned,
+		Actor:      by,
+		Message:    message,
+		CreatedAt:  time.Now(),
+	})
+	return incident, nil
+}
+
+// AddNote adds a note to the timeline of an incident. Closed incidents can
+// still get notes.
+func (m *Manager) AddNote(id uint, by, text string) (*models.IncidentEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	incident, err := services.Incident.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if incident == nil {
+		return nil, services.ErrNotFound
+	}
+
+	entry := &models.IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       models.IncidentEventNote,
+		Actor:      by,
+		Message:    text,
+		CreatedAt:  time.Now(),
+	}
+	if err := services.Incident.AddEvent(entry); err != nil {
+		return nil, err
+	}
+	m.broadcast(Event{Event: EventNote, Incident: incident, Entry: entry})
+	return entry, nil
+}
+
+// StartEscalation attaches an escalation policy to an incident that is
+// still open
+func (m *Manager) StartEscalation(id uint, policy *models.EscalationPolicy) (*models.Incident, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	incident, err := m.loadOpen(id)
+	if err != nil {
+		return incident, err
+	}
+
+	incident.EscalationPolicyID = &policy.ID
+	incident.EscalationStep = 0
+	if err := services.Incident.Update(incident); err != nil {
+		return nil, err
+	}
+	m.addEvent(EventEscalate, incident, &models.IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       models.IncidentEventEscalate,
+		Actor:      "escalation",
+		Message:    fmt.Sprintf("Escalating with policy %s (%d steps) until acknowledged", policy.Name, len(policy.Steps)),
+		CreatedAt:  time.Now(),
+	})
+	return incident, nil
+}
+
+// Escalate records that step, counted from 1, of the escalation chain was
+// done. It fails with ErrAlreadyAcknowledged or ErrClosed once someone
+// handled the incident, which ends the chain.
+func (m *Manager) Escalate(id uint, step int, message string) (*models.Incident, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	incident, err := m.loadOpen(id)
+	if err != nil {
+		return incident, err
+	}
+
+	now := time.Now()
+	incident.EscalationStep = step
+	incident.EscalatedAt = &now
+	if err := services.Incident.Update(incident); err != nil {
+		return nil, err
+	}
+	m.addEvent(EventEscalate, incident, &models.IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       models.IncidentEventEscalate,
+		Actor:      "escalation",
+		Message:    message,
+		CreatedAt:  now,
+	})
+	return incident, nil
+}
+
+// loadOpen fetches an incident nobody acknowledged yet. Must be called with m.mu held.
+func (m *Manager) loadOpen(id uint) (*models.Incident, error) {
+	incident, err := m.load(id)
+	if err != nil {
+		return incident, err
+	}
+	if incident.State != models.IncidentOpen {
+		return incident, ErrAlreadyAcknowledged
+	}
+	return incident, nil
+}
+
+// load fetches an incident that can still be changed. Must be called with m.mu held.
+func (m *Manager) load(id uint) (*models.Incident, error) {
+	incident, err := services.Incident.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if incident == nil {
+		return nil, services.ErrNotFound
+	}
+	if incident.State == models.IncidentResolved || incident.State == models.IncidentFalseAlarm {
+		return incident, fmt.Errorf("%w: %s", ErrClosed, incident.State)
+	}
+	return incident, nil
+}
+
+// changeState persists a state change and adds it to the timeline. Must be
+// called with m.mu held.
+func (m *Manager) changeState(incident *models.Incident, by, message string) error {
+	if err := services.Incident.Update(incident); err != nil {
+		return err
+	}
+	log.Printf("Incident %d is %s (by %s)", incident.ID, incident.State, by)
+
+	m.addEvent(EventState, incident, &models.IncidentEvent{
+		IncidentID: incident.ID,
+		Type:       models.IncidentEventState,
+		Actor:      by,
+		Message:    message,
+		CreatedAt:  time.Now(),
+	})
+	return nil
+}
+
+// addEvent stores a timeline entry and broadcasts it. Must be called with m.mu held.
+func (m *Manager) addEvent(event string, incident *models.Incident, entry *models.IncidentEvent) {
+	if err := services.Incident.AddEvent(entry); err != nil {
+		log.Printf("Error adding incident event: %v", err)
+	}
+	m.broadcast(Event{Event: event, Incident: incident, Entry: entry})
+}
+
+// broadcast pushes an incident event to Topic and the listener. Must be called with m.mu held.
+func (m *Manager) broadcast(event Event) {
+	if m.listener != nil {
+		m.listener(event)
+	}
+
+	message, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error marshalling incident event: %v", err)
+		return
+	}
+	m.hub.BroadcastToTopic(message, Topic)
+}
@@ -0,0 +1,271 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// sendTimeout is how long a single delivery attempt may take
+const sendTimeout = 15 * time.Second
+
+var (
+	ErrInvalidAddress = errors.New("invalid address")
+	ErrPrivateAddress = errors.New("address is not public")
+)
+
+// Message is a rendered notification
+type Message struct {
+	Event     string    `json:"event"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Channel delivers messages to addresses of one kind, e.g. email addresses
+type Channel interface {
+	// Validate checks an address before it is saved in a preference
+	Validate(address string) error
+	Send(address string, msg Message) error
+}
+
+// Webhook POSTs messages as JSON. With a secret, the body is signed with
+// HMAC-SHA256 in the X-Signature header as sha256=<hex>.
+type Webhook struct {
+	Secret       string
+	allowPrivate bool
+	client       *http.Client
+}
+
+// NewWebhook creates the webhook channel. Any user may enter a webhook URL,
+// so unless allowPrivate is set only public addresses are accepted and
+// dialed, keeping users from reaching the hosts on our own network.
+func NewWebhook(secret string, allowPrivate bool) *Webhook {
+	return &Webhook{Secret: secret, allowPrivate: allowPrivate, client: newClient(allowPrivate)}
+}
+
+func (c *Webhook) Validate(address string) error {
+	return validateURL(address, c.allowPrivate)
+}
+
+func (c *Webhook) Send(address string, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, address, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(c.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return do(c.client, req)
+}
+
+// Email sends messages over SMTP with STARTTLS when the server offers it
+type Email struct {
+	Host     string
+	Port     int
+	Username string // Empty to send without authentication
+	Password string
+	From     string
+}
+
+func (c *Email) Validate(address string) error {
+	if _, err := mail.ParseAddress(address); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+	}
+	return nil
+}
+
+func (c *Email) Send(address string, msg Message) error {
+	// Addresses may have a display name, SMTP only takes the bare address
+	to, err := mail.ParseAddress(address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+	}
+	from, err := mail.ParseAddress(c.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", c.From, err)
+	}
+
+	var auth smtp.Auth
+	if c.Username != "" {
+		auth = smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	}
+
+	var body strings.Builder
+	body.WriteString("From: " + from.String() + "\r\n")
+	body.WriteString("To: " + to.String() + "\r\n")
+	body.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", headerValue(msg.Title)) + "\r\n")
+	body.WriteString("Date: " + msg.Timestamp.Format(time.RFC1123Z) + "\r\n")
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n") + "\r\n")
+
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	if err := smtp.SendMail(addr, auth, from.Address, []string{to.Address}, []byte(body.String())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// Ntfy publishes messages to an ntfy server. The address is a topic on the
+// configured server or the full URL of a topic on any server.
+type Ntfy struct {
+	Server       string // e.g. https://ntfy.sh
+	Token        string // Access token for protected topics on Server, optional
+	allowPrivate bool
+	client       *http.Client // For Server, which may well be on our own network
+	urlClient    *http.Client // For topic URLs entered by users
+}
+
+// NewNtfy creates the ntfy channel. Like webhooks, topic URLs on other
+// servers must have a public address unless allowPrivate is set.
+func NewNtfy(server, token string, allowPrivate bool) *Ntfy {
+	return &Ntfy{
+		Server:       strings.TrimRight(server, "/"),
+		Token:        token,
+		allowPrivate: allowPrivate,
+		client:       &http.Client{Timeout: sendTimeout},
+		urlClient:    newClient(allowPrivate),
+	}
+}
+
+func (c *Ntfy) Validate(address string) error {
+	if strings.Contains(address, "://") {
+		return validateURL(address, c.allowPrivate)
+	}
+	if address == "" || strings.ContainsAny(address, "/?# ") {
+		return fmt.Errorf("%w: expected an ntfy topic or topic URL", ErrInvalidAddress)
+	}
+	return nil
+}
+
+func (c *Ntfy) Send(address string, msg Message) error {
+	target, client := address, c.urlClient
+	if !strings.Contains(address, "://") {
+		target, client = c.Server+"/"+address, c.client
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target, strings.NewReader(msg.Body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Title", headerValue(msg.Title))
+	req.Header.Set("Tags", msg.Event)
+	if msg.Event == "alarm" {
+		req.Header.Set("Priority", "urgent")
+	}
+	// The token is only meant for the configured server
+	if c.Token != "" && client == c.client {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return do(client, req)
+}
+
+// do sends a request, treating any status other than 2xx as an error
+func do(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("%s responded %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// validateURL checks a URL entered by a user. Unless allowPrivate is set, the
+// host must only resolve to public addresses. The address may change after
+// validation, so newClient checks it again when connecting.
+func validateURL(address string, allowPrivate bool) error {
+	u, err := url.Parse(address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("%w: expected an http or https URL", ErrInvalidAddress)
+	}
+	if allowPrivate {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: failed to resolve %s", ErrInvalidAddress, u.Hostname())
+	}
+	for _, ip := range ips {
+		if !isPublic(ip.IP) {
+			return fmt.Errorf("%w: %s is on a local network", ErrPrivateAddress, u.Hostname())
+		}
+	}
+	return nil
+}
+
+// newClient creates the client for URLs entered by users. Unless allowPrivate
+// is set, it refuses to connect to anything but public addresses, which also
+// covers redirects and hosts that resolve differently since validation.
+func newClient(allowPrivate bool) *http.Client {
+	if allowPrivate {
+		return &http.Client{Timeout: sendTimeout}
+	}
+
+	dialer := &net.Dialer{
+		Timeout: sendTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy would connect on our behalf, past the check of the dialer
+	transport.Proxy = nil
+	return &http.Client{Timeout: sendTimeout, Transport: transport}
+}
+
+// isPublic reports whether an IP is routable on the internet, i.e. not
+// loopback, private, link-local (like cloud metadata services) or multicast
+func isPublic(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !sharedAddressSpace.Contains(ip)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which isn't covered by IsPrivate
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// headerValue keeps templated titles from breaking out of a header line
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
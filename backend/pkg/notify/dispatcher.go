@@ -0,0 +1,385 @@
+package notify
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/alarm"
+	"backend/pkg/auth"
+	"backend/pkg/trouble"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// queueSize is how many events may wait for the dispatcher before new ones are dropped
+const queueSize = 100
+
+// Events are the events users can subscribe to
+var Events = []string{
+	models.NotifyEventAlarm,
+	models.NotifyEventArmState,
+	models.NotifyEventTrouble,
+	models.NotifyEventRule,
+}
+
+// permissions is what a user needs to be notified about an event, the same
+// as for seeing it in the UI
+var permissions = map[string]auth.Permission{
+	models.NotifyEventAlarm:    auth.PermAlarmRead,
+	models.NotifyEventArmState: auth.PermAlarmRead,
+	models.NotifyEventTrouble:  auth.PermSensorsRead,
+	models.NotifyEventRule:     auth.PermAlarmRead,
+}
+
+// Config of the dispatcher
+type Config struct {
+	MaxAttempts int           // Attempts per delivery before it fails
+	RetryDelay  time.Duration // Delay before the second attempt, doubled for every further one
+	Workers     int           // Deliveries sent at the same time
+}
+
+// Dispatcher turns events into notifications for every user subscribed to
+// them and delivers those over the user's channels. Every delivery is kept
+// in the delivery log and retried until it succeeds or runs out of attempts.
+type Dispatcher struct {
+	mu       sync.Mutex
+	channels map[string]Channel
+	config   Config
+	events   chan Event
+	queue    chan *models.NotificationDelivery
+}
+
+func NewDispatcher(config Config) *Dispatcher {
+	return &Dispatcher{
+		channels: make(map[string]Channel),
+		config: Config{
+			MaxAttempts: max(config.MaxAttempts, 1),
+			RetryDelay:  config.RetryDelay,
+			Workers:     max(config.Workers, 1),
+		},
+		events: make(chan Event, queueSize),
+		queue:  make(chan *models.NotificationDelivery, queueSize),
+	}
+}
+
+// RegisterChannel makes a channel available under a name, one of the
+// models.NotifyChannel* constants
+func (d *Dispatcher) RegisterChannel(name string, channel Channel) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.channels[name] = channel
+}
+
+// Channel returns the channel registered under a name
+func (d *Dispatcher) Channel(name string) (Channel, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	channel, ok := d.channels[name]
+	return channel, ok
+}
+
+// Channels returns the names of the registered channels
+func (d *Dispatcher) Channels() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	names := make([]string, 0, len(d.channels))
+	for name := range d.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start starts delivering. Deliveries still pending from a previous run are
+// picked up again.
+func (d *Dispatcher) Start() {
+	go d.run()
+	for range d.config.Workers {
+		go d.work()
+	}
+
+	pending, err := services.Notification.ListPendingDeliveries()
+	if err != nil {
+		log.Printf("Error fetching pending notifications: %v", err)
+		return
+	}
+	if len(pending) > 0 {
+		log.Printf("Resuming %d pending notifications", len(pending))
+	}
+	for i := range pending {
+		d.enqueue(&pending[i], 0)
+	}
+}
+
+// Notify queues an event without blocking, so it is safe to call from
+// listeners running under another component's lock
+func (d *Dispatcher) Notify(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	select {
+	case d.events <- event:
+	default:
+		log.Printf("Notification queue full, dropping %s event", event.Type)
+	}
+}
+
+// HandleStateChange is the alarm.StateListener that notifies about alarms
+// and about partitions being armed or disarmed
+func (d *Dispatcher) HandleStateChange(change alarm.StateChange) {
+	event := Event{
+		PartitionID: change.PartitionID,
+		State:       string(change.State),
+		By:          change.ChangedBy,
+		Time:        change.UpdatedAt,
+	}
+	switch change.State {
+	case alarm.StateTriggered:
+		if change.PreviousState == alarm.StateTriggered {
+			return
+		}
+		event.Type = models.NotifyEventAlarm
+		event.SensorID = change.TriggeredBy
+	case alarm.StateArmedHome, alarm.StateArmedAway, alarm.StateDisarmed:
+		if change.PreviousState == change.State {
+			return
+		}
+		event.Type = models.NotifyEventArmState
+	default:
+		return
+	}
+	d.Notify(event)
+}
+
+// HandleTrouble is the trouble.Listener that notifies about raised troubles
+func (d *Dispatcher) HandleTrouble(event trouble.Event) {
+	if event.Event != trouble.EventRaised {
+		return
+	}
+	d.Notify(Event{
+		Type:     models.NotifyEventTrouble,
+		SensorID: event.Trouble.SensorID,
+		State:    event.Trouble.Type,
+		Message:  event.Trouble.Message,
+		Time:     event.Trouble.RaisedAt,
+	})
+}
+
+// HandleRule is the rules.Notifier that notifies about notify actions
+func (d *Dispatcher) HandleRule(title, message string) {
+	d.Notify(Event{Type: models.NotifyEventRule, Title: title, Message: message})
+}
+
+// Test sends a test notification to each of the preferences of a user and
+// returns the queued deliveries
+func (d *Dispatcher) Test(user *models.User, prefs []models.NotificationPreference) ([]models.NotificationDelivery, error) {
+	title, body := render(Event{Type: models.NotifyEventTest, Time: time.Now()})
+
+	deliveries := make([]models.NotificationDelivery, 0, len(prefs))
+	for _, pref := range prefs {
+		delivery := newDelivery(user, pref, models.NotifyEventTest, title, body)
+		if err := services.Notification.CreateDelivery(delivery); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+		d.enqueue(delivery, 0)
+	}
+	return deliveries, nil
+}
+
+// NotifyUser sends an event to every enabled preference of a user, whatever
+// events they cover, and returns how many deliveries were queued
+func (d *Dispatcher) NotifyUser(userID uint, event Event) (int, error) {
+	user, err := services.User.Get(userID)
+	if err != nil {
+		return 0, err
+	}
+	if user == nil {
+		return 0, services.ErrNotFound
+	}
+	if err := auth.Authorize(user, auth.PermAlarmRead); err != nil {
+		return 0, fmt.Errorf("user %s may not see alarms: %w", user.Username, err)
+	}
+
+	prefs, err := services.Notification.ListPreferences(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	title, body := render(describe(event))
+	queued := 0
+	for _, pref := range prefs {
+		if !pref.Enabled {
+			continue
+		}
+		delivery := newDelivery(user, pref, event.Type, title, body)
+		if err := services.Notification.CreateDelivery(delivery); err != nil {
+			return queued, err
+		}
+		d.enqueue(delivery, 0)
+		queued++
+	}
+	return queued, nil
+}
+
+// NotifyContact sends an event to an address that doesn't belong to a user,
+// e.g. a neighbour or a monitoring service
+func (d *Dispatcher) NotifyContact(name, channel, address string, event Event) error {
+	title, body := render(describe(event))
+	contact := &models.User{Username: name}
+	delivery := newDelivery(contact, models.NotificationPreference{Channel: channel, Address: address}, event.Type, title, body)
+	if err := services.Notification.CreateDelivery(delivery); err != nil {
+		return err
+	}
+	d.enqueue(delivery, 0)
+	return nil
+}
+
+// Subscribed reports whether a preference covers an event
+func Subscribed(pref models.NotificationPreference, event string) bool {
+	if pref.Events == "" {
+		return true
+	}
+	for _, e := range strings.Split(pref.Events, ",") {
+		if strings.TrimSpace(e) == event {
+			return true
+		}
+	}
+	return false
+}
+
+// run turns queued events into deliveries
+func (d *Dispatcher) run() {
+	for event := range d.events {
+		d.dispatch(event)
+	}
+}
+
+// dispatch creates a delivery for every enabled preference subscribed to the
+// event whose user may see it
+func (d *Dispatcher) dispatch(event Event) {
+	prefs, err := services.Notification.ListEnabledPreferences()
+	if err != nil {
+		log.Printf("Error fetching notification preferences: %v", err)
+		return
+	}
+
+	var subscribed []models.NotificationPreference
+	for _, pref := range prefs {
+		if Subscribed(pref, event.Type) {
+			subscribed = append(subscribed, pref)
+		}
+	}
+	if len(subscribed) == 0 {
+		return
+	}
+
+	title, body := render(describe(event))
+
+	users := make(map[uint]*models.User)
+	for _, pref := range subscribed {
+		user, ok := users[pref.UserID]
+		if !ok {
+			if user, err = services.User.Get(pref.UserID); err != nil {
+				log.Printf("Error fetching user: %v", err)
+				continue
+			}
+			users[pref.UserID] = user
+		}
+		// Roles and access windows can change after a preference was saved
+		if user == nil || auth.Authorize(user, permissions[event.Type]) != nil {
+			continue
+		}
+
+		delivery := newDelivery(user, pref, event.Type, title, body)
+		if err := services.Notification.CreateDelivery(delivery); err != nil {
+			log.Printf("Error creating notification: %v", err)
+			continue
+		}
+		d.enqueue(delivery, 0)
+	}
+}
+
+// describe fills in the partition name of an event
+func describe(event Event) Event {
+	if event.PartitionID == 0 {
+		return event
+	}
+	event.Partition = fmt.Sprintf("partition %d", event.PartitionID)
+	if partition, err := services.Partition.Get(event.PartitionID); err != nil {
+		log.Printf("Error fetching partition: %v", err)
+	} else if partition != nil {
+		event.Partition = partition.Name
+	}
+	return event
+}
+
+// enqueue hands a delivery to the workers after a delay, without blocking
+func (d *Dispatcher) enqueue(delivery *models.NotificationDelivery, delay time.Duration) {
+	if delay <= 0 {
+		go func() { d.queue <- delivery }()
+		return
+	}
+	time.AfterFunc(delay, func() { d.queue <- delivery })
+}
+
+func (d *Dispatcher) work() {
+	for delivery := range d.queue {
+		d.send(delivery)
+	}
+}
+
+// send makes one attempt at a delivery and schedules the next one if it fails
+func (d *Dispatcher) send(delivery *models.NotificationDelivery) {
+	delivery.Attempts++
+
+	channel, ok := d.Channel(delivery.Channel)
+	var err error
+	if !ok {
+		err = fmt.Errorf("channel %s is not configured", delivery.Channel)
+	} else {
+		err = channel.Send(delivery.Address, Message{
+			Event:     delivery.Event,
+			Title:     delivery.Title,
+			Body:      delivery.Body,
+			Timestamp: delivery.CreatedAt,
+		})
+	}
+
+	if err == nil {
+		now := time.Now()
+		delivery.Status = models.DeliverySent
+		delivery.SentAt = &now
+		delivery.Error = ""
+	} else {
+		delivery.Error = err.Error()
+		if !ok || delivery.Attempts >= d.config.MaxAttempts {
+			delivery.Status = models.DeliveryFailed
+			log.Printf("Notification %d to %s via %s failed after %d attempts: %v", delivery.ID, delivery.Username, delivery.Channel, delivery.Attempts, err)
+		} else {
+			delay := d.config.RetryDelay << (delivery.Attempts - 1)
+			log.Printf("Notification %d to %s via %s failed, retrying in %s: %v", delivery.ID, delivery.Username, delivery.Channel, delay, err)
+			defer d.enqueue(delivery, delay)
+		}
+	}
+
+	if err := services.Notification.UpdateDelivery(delivery); err != nil {
+		log.Printf("Error saving notification: %v", err)
+	}
+}
+
+func newDelivery(user *models.User, pref models.NotificationPreference, event, title, body string) *models.NotificationDelivery {
+	return &models.NotificationDelivery{
+		UserID:   user.ID,
+		Username: user.Username,
+		Event:    event,
+		Channel:  pref.Channel,
+		Address:  pref.Address,
+		Title:    title,
+		Body:     body,
+		Status:   models.DeliveryPending,
+	}
+}
@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"text/template"
+	"time"
+)
+
+var ErrInvalidTemplate = errors.New("invalid template")
+
+// Event is something users can be notified about. Its fields are available
+// in templates, e.g. {{.SensorID}} or {{.Time.Format "15:04"}}.
+type Event struct {
+	Type        string    // One of the models.NotifyEvent* constants
+	PartitionID uint      // Zero for events without a partition
+	Partition   string    // Partition name, filled in by the dispatcher
+	SensorID    string    // Sensor that triggered the alarm or is in trouble
+	State       string    // Arm state for arm_state, trouble type for trouble
+	By          string    // Who armed or disarmed
+	Title       string    // Rule name for rule
+	Message     string    // Alarm, trouble or rule message
+	Time        time.Time // When it happened
+}
+
+// Template is the title and body of a notification
+type Template struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// Defaults are the built-in templates per event
+var Defaults = map[string]Template{
+	models.NotifyEventAlarm: {
+		Title: "ALARM in {{.Partition}}",
+		Body:  "Alarm triggered by {{.SensorID}} at {{.Time.Format \"15:04:05\"}}.{{if .Message}} {{.Message}}{{end}}",
+	},
+	models.NotifyEventArmState: {
+		Title: "{{.Partition}} {{.State}}",
+		Body:  "{{.Partition}} is {{.State}}{{if .By}} by {{.By}}{{end}} since {{.Time.Format \"15:04:05\"}}.",
+	},
+	models.NotifyEventTrouble: {
+		Title: "Trouble on {{.SensorID}}",
+		Body:  "{{.State}} on sensor {{.SensorID}}: {{.Message}}",
+	},
+	models.NotifyEventRule: {
+		Title: "{{.Title}}",
+		Body:  "{{.Message}}",
+	},
+	models.NotifyEventEscalation: {
+		Title: "Unacknowledged alarm in {{.Partition}}",
+		Body:  "Alarm triggered by {{.SensorID}} at {{.Time.Format \"15:04:05\"}} has not been acknowledged yet.{{if .Message}} {{.Message}}{{end}}",
+	},
+	models.NotifyEventTest: {
+		Title: "Test notification",
+		Body:  "Notifications reach you here.",
+	},
+}
+
+// CheckTemplate parses a title and body and renders them with a sample event
+func CheckTemplate(title, body string) error {
+	sample := Event{
+		Type: models.NotifyEventAlarm, PartitionID: 1, Partition: "House", SensorID: "door1",
+		State: "triggered", By: "admin", Title: "Rule", Message: "Door opened", Time: time.Now(),
+	}
+	if _, err := execute(title, sample); err != nil {
+		return fmt.Errorf("%w: title: %v", ErrInvalidTemplate, err)
+	}
+	if _, err := execute(body, sample); err != nil {
+		return fmt.Errorf("%w: body: %v", ErrInvalidTemplate, err)
+	}
+	return nil
+}
+
+// render returns the title and body of an event, using the override stored
+// for the event if there is a working one
+func render(event Event) (string, string) {
+	def := Defaults[event.Type]
+	override, err := services.Notification.GetTemplate(event.Type)
+	if err != nil {
+		log.Printf("Error fetching notification template: %v", err)
+	}
+	if override != nil {
+		title, titleErr := execute(override.Title, event)
+		body, bodyErr := execute(override.Body, event)
+		if titleErr == nil && bodyErr == nil {
+			return title, body
+		}
+		log.Printf("Template of %s notifications failed, using the default: %v", event.Type, errors.Join(titleErr, bodyErr))
+	}
+
+	title, err := execute(def.Title, event)
+	if err != nil {
+		log.Printf("Error rendering %s notification title: %v", event.Type, err)
+	}
+	body, err := execute(def.Body, event)
+	if err != nil {
+		log.Printf("Error rendering %s notification body: %v", event.Type, err)
+	}
+	return title, body
+}
+
+func execute(text string, event Event) (string, error) {
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, event); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,139 @@
+package payloads
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Payload is a decoded and validated MQTT message from a sensor
+type Payload interface {
+	// Kind is the message kind, the last segment of the topic
+	Kind() string
+	// Sensor is the sensor_id the payload claims to come from, empty if not sent
+	Sensor() string
+	// Time is the time the sensor created the message
+	Time() time.Time
+	// Validate checks the payload against its schema
+	Validate() error
+}
+
+// Decoder turns a raw payload into a validated Payload
+type Decoder func(data []byte) (Payload, error)
+
+var ErrUnknownKind = errors.New("no decoder registered for message kind")
+
+// FieldError describes a single schema violation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every schema violation found in a payload
+type ValidationError struct {
+	Kind   string       `json:"kind"`
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return fmt.Sprintf("invalid %s payload: %s", e.Kind, strings.Join(msgs, "; "))
+}
+
+// Add records a violation of field
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// Err returns e if any violations were recorded, nil otherwise
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+var (
+	mu       sync.RWMutex
+	decoders = map[string]Decoder{}
+)
+
+// Register adds or replaces the decoder of a message kind
+func Register(kind string, decoder Decoder) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	decoders[kind] = decoder
+}
+
+// Kinds returns the message kinds that have a decoder registered
+func Kinds() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	kinds := make([]string, 0, len(decoders))
+	for kind := range decoders {
+		kinds = append(kinds, kind)
+	}
+	return kinds
+}
+
+// Decode decodes and validates data with the decoder registered for kind
+func Decode(kind string, data []byte) (Payload, error) {
+	mu.RLock()
+	decoder, ok := decoders[kind]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKind, kind)
+	}
+	return decoder(data)
+}
+
+// JSONDecoder returns a Decoder that unmarshals JSON into a new T and validates it
+func JSONDecoder[T any, P interface {
+	*T
+	Payload
+}](kind string) Decoder {
+	return func(data []byte) (Payload, error) {
+		return decodeJSON(kind, data, P(new(T)))
+	}
+}
+
+// decodeJSON unmarshals data into p and validates it
+func decodeJSON(kind string, data []byte, p Payload) (Payload, error) {
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, jsonError(kind, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// jsonError turns a JSON decoding error into a ValidationError
+func jsonError(kind string, err error) error {
+	verr := &ValidationError{Kind: kind}
+
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			verr.Add("payload", "expected a JSON object, got "+typeErr.Value)
+		} else {
+			verr.Add(typeErr.Field, fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value))
+		}
+	case errors.As(err, &syntaxErr):
+		verr.Add("payload", fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset))
+	default:
+		verr.Add("payload", err.Error())
+	}
+	return verr
+}
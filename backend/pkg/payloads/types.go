@@ -0,0 +1,194 @@
+package payloads
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// Message kinds with a built-in decoder
+const (
+	KindAlarm     = "alarm"
+	KindStatus    = "status"
+	KindHeartbeat = "heartbeat"
+	KindBattery   = "battery"
+	KindTamper    = "tamper"
+)
+
+// measurementKinds are the kinds decoded as a Measurement
+var measurementKinds = []string{"temperature", "humidity", "smoke", "co", "co2", "pressure", "illuminance", "water"}
+
+func init() {
+	Register(KindAlarm, JSONDecoder[Alarm](KindAlarm))
+	Register(KindStatus, StatusDecoder)
+	Register(KindHeartbeat, JSONDecoder[Heartbeat](KindHeartbeat))
+	Register(KindBattery, JSONDecoder[Battery](KindBattery))
+	Register(KindTamper, JSONDecoder[Tamper](KindTamper))
+
+	for _, kind := range measurementKinds {
+		Register(kind, MeasurementDecoder(kind))
+	}
+}
+
+// Base holds the fields every sensor message carries
+type Base struct {
+	Timestamp *float64 `json:"timestamp"` // Unix seconds, may be fractional
+	SensorID  string   `json:"sensor_id"`
+}
+
+func (b *Base) Sensor() string {
+	return b.SensorID
+}
+
+func (b *Base) Time() time.Time {
+	if b.Timestamp == nil {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(*b.Timestamp)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+func (b *Base) validate(verr *ValidationError) {
+	if b.Timestamp == nil {
+		verr.Add("timestamp", "is required")
+	} else if *b.Timestamp <= 0 || math.IsInf(*b.Timestamp, 0) || math.IsNaN(*b.Timestamp) {
+		verr.Add("timestamp", "must be a positive unix timestamp")
+	}
+}
+
+// Alarm is sent on sensor/<id>/alarm when a sensor detects an intrusion or hazard
+type Alarm struct {
+	Base
+	Message  string `json:"message"`
+	Severity int    `json:"severity"` // 1 (lowest) to 5
+}
+
+func (a *Alarm) Kind() string { return KindAlarm }
+
+func (a *Alarm) Validate() error {
+	verr := &ValidationError{Kind: KindAlarm}
+	a.validate(verr)
+	if a.Severity != 0 && (a.Severity < 1 || a.Severity > 5) {
+		verr.Add("severity", "must be between 1 and 5")
+	}
+	return verr.Err()
+}
+
+// Status is sent on sensor/<id>/status when a sensor reports its state
+type Status struct {
+	Base
+	Status string `json:"status"`
+}
+
+var validStatuses = map[string]bool{"active": true, "inactive": true, "online": true, "offline": true, "error": true}
+
+func (s *Status) Kind() string { return KindStatus }
+
+func (s *Status) Validate() error {
+	verr := &ValidationError{Kind: KindStatus}
+	s.validate(verr)
+	if s.Status == "" {
+		verr.Add("status", "is required")
+	} else if !validStatuses[s.Status] {
+		verr.Add("status", "must be one of active, inactive, online, offline, error")
+	}
+	return verr.Err()
+}
+
+// StatusDecoder decodes status messages. Besides the JSON form it accepts a
+// bare status word like "offline", which is what devices usually set as
+// their MQTT Last Will.
+func StatusDecoder(data []byte) (Payload, error) {
+	word := strings.Trim(strings.TrimSpace(string(data)), `"`)
+	if validStatuses[word] {
+		return &Status{Status: word}, nil
+	}
+	return decodeJSON(KindStatus, data, &Status{})
+}
+
+// Heartbeat is sent periodically by sensors to show they are alive
+type Heartbeat struct {
+	Base
+	Uptime *float64 `json:"uptime,omitempty"` // Seconds since boot
+	RSSI   *int     `json:"rssi,omitempty"`   // Signal strength in dBm
+}
+
+func (h *Heartbeat) Kind() string { return KindHeartbeat }
+
+func (h *Heartbeat) Validate() error {
+	verr := &ValidationError{Kind: KindHeartbeat}
+	h.validate(verr)
+	if h.Uptime != nil && *h.Uptime < 0 {
+		verr.Add("uptime", "must not be negative")
+	}
+	return verr.Err()
+}
+
+// Battery is sent when a sensor reports its battery level
+type Battery struct {
+	Base
+	Level   *float64 `json:"level"`             // Percent, 0-100
+	Voltage *float64 `json:"voltage,omitempty"` // Volts
+}
+
+func (b *Battery) Kind() string { return KindBattery }
+
+func (b *Battery) Validate() error {
+	verr := &ValidationError{Kind: KindBattery}
+	b.validate(verr)
+	if b.Level == nil {
+		verr.Add("level", "is required")
+	} else if *b.Level < 0 || *b.Level > 100 {
+		verr.Add("level", "must be between 0 and 100")
+	}
+	if b.Voltage != nil && *b.Voltage < 0 {
+		verr.Add("voltage", "must not be negative")
+	}
+	return verr.Err()
+}
+
+// Tamper is sent when a sensor's tamper switch changes state
+type Tamper struct {
+	Base
+	Tampered *bool `json:"tampered"` // true when the enclosure is open
+}
+
+func (t *Tamper) Kind() string { return KindTamper }
+
+func (t *Tamper) Validate() error {
+	verr := &ValidationError{Kind: KindTamper}
+	t.validate(verr)
+	if t.Tampered == nil {
+		verr.Add("tampered", "is required")
+	}
+	return verr.Err()
+}
+
+// Measurement is sent by sensors reporting a numeric value, e.g. on
+// sensor/<id>/temperature
+type Measurement struct {
+	Base
+	Value *float64 `json:"value"`
+	Unit  string   `json:"unit,omitempty"`
+	kind  string
+}
+
+func (m *Measurement) Kind() string { return m.kind }
+
+func (m *Measurement) Validate() error {
+	verr := &ValidationError{Kind: m.kind}
+	m.validate(verr)
+	if m.Value == nil {
+		verr.Add("value", "is required")
+	} else if math.IsNaN(*m.Value) || math.IsInf(*m.Value, 0) {
+		verr.Add("value", "must be a finite number")
+	}
+	return verr.Err()
+}
+
+// MeasurementDecoder returns a Decoder for a measurement kind such as temperature
+func MeasurementDecoder(kind string) Decoder {
+	return func(data []byte) (Payload, error) {
+		return decodeJSON(kind, data, &Measurement{kind: kind})
+	}
+}
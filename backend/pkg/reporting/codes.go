@@ -0,0 +1,77 @@
+package reporting
+
+import (
+	"backend/database/models"
+	"backend/pkg/sia"
+	"fmt"
+	"strings"
+)
+
+// code is an event in both code sets. Contact ID events are reported as
+// new events (qualifier 1) or restores (qualifier 3) of the same code.
+type code struct {
+	SIA     string
+	CID     int
+	Restore bool
+	Text    string
+}
+
+// Alarm codes by sensor type, sensors of other types raise burglary alarms
+var alarmCodes = map[string]code{
+	"smoke": {SIA: "FA", CID: 111, Text: "Smoke alarm"},
+	"fire":  {SIA: "FA", CID: 110, Text: "Fire alarm"},
+	"heat":  {SIA: "FA", CID: 114, Text: "Heat alarm"},
+	"water": {SIA: "WA", CID: 154, Text: "Water leak"},
+	"leak":  {SIA: "WA", CID: 154, Text: "Water leak"},
+	"flood": {SIA: "WA", CID: 154, Text: "Water leak"},
+	"gas":   {SIA: "GA", CID: 151, Text: "Gas detected"},
+	"co":    {SIA: "GA", CID: 162, Text: "Carbon monoxide detected"},
+	"panic": {SIA: "PA", CID: 120, Text: "Panic alarm"},
+}
+
+var burglaryCode = code{SIA: "BA", CID: 130, Text: "Burglary alarm"}
+
+// Trouble codes by trouble type, the restore codes are used when a trouble clears
+var (
+	troubleCodes = map[string]code{
+		models.TroubleTamper:      {SIA: "TA", CID: 383, Text: "Sensor tamper"},
+		models.TroubleLowBattery:  {SIA: "XT", CID: 384, Text: "Sensor low battery"},
+		models.TroubleSupervision: {SIA: "UT", CID: 381, Text: "Sensor supervision loss"},
+	}
+	troubleRestoreCodes = map[string]code{
+		models.TroubleTamper:      {SIA: "TR", CID: 383, Restore: true, Text: "Sensor tamper restored"},
+		models.TroubleLowBattery:  {SIA: "XR", CID: 384, Restore: true, Text: "Sensor battery restored"},
+		models.TroubleSupervision: {SIA: "UR", CID: 381, Restore: true, Text: "Sensor supervision restored"},
+	}
+)
+
+// Opening, closing and other codes that carry a user number instead of a zone
+var (
+	openingCode        = code{SIA: "OP", CID: 401, Text: "Disarmed"}
+	closingCode        = code{SIA: "CL", CID: 401, Restore: true, Text: "Armed away"}
+	partialClosingCode = code{SIA: "CG", CID: 441, Restore: true, Text: "Armed home"}
+	cancelCode         = code{SIA: "BC", CID: 406, Text: "Alarm cancelled"}
+	testCode           = code{SIA: "RX", CID: 601, Text: "Manual test"}
+	duressCode         = code{SIA: "HA", CID: 121, Text: "Duress"}
+)
+
+func alarmCode(sensorType string) code {
+	if c, ok := alarmCodes[strings.ToLower(sensorType)]; ok {
+		return c
+	}
+	return burglaryCode
+}
+
+// data formats the message data of an event in partition (area) with a zone
+// or user number, as SIA DCS or Contact ID
+func (c code) data(token string, partition uint, number int) (string, string) {
+	if token == sia.TokenCID {
+		qualifier := 1
+		if c.Restore {
+			qualifier = 3
+		}
+		event := fmt.Sprintf("%d%03d", qualifier, c.CID)
+		return fmt.Sprintf("%s %02d %03d", event, partition%100, number%1000), event
+	}
+	return fmt.Sprintf("Nri%d/%s%03d", partition%100, c.SIA, number%1000), c.SIA
+}
@@ -0,0 +1,352 @@
+package reporting
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/alarm"
+	"backend/pkg/sia"
+	"backend/pkg/trouble"
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// queueSize is how many events may wait to be recorded before new ones are dropped
+const queueSize = 100
+
+// maxRetryDelay caps the backoff while the receiver is unreachable
+const maxRetryDelay = 5 * time.Minute
+
+var (
+	ErrNAK = errors.New("receiver rejected the message")
+	ErrDUH = errors.New("receiver didn't understand the message")
+)
+
+// Config of the reporter
+type Config struct {
+	Address    string // host:port of the receiver
+	Protocol   string // tcp or udp
+	Token      string // sia.TokenSIA or sia.TokenCID
+	Account    string // Account number in hex, 3-16 digits
+	Receiver   string // Receiver number in hex, optional
+	Prefix     string // Account prefix in hex
+	Key        []byte // AES key, nil to send unencrypted
+	AckTimeout time.Duration
+	RetryDelay time.Duration // Delay after the first failed attempt, doubled for every further one
+	Heartbeat  time.Duration // How often a NULL message tests the link while idle, 0 to disable
+	Expire     time.Duration // Reports not acknowledged for this long are given up on, 0 to never
+}
+
+// event is an alarm system event waiting to be turned into a report
+type event struct {
+	code        code
+	alarm       bool // The code depends on the type of the sensor
+	partitionID uint
+	sensorID    string // Zone number source, for alarms and troubles
+	user        string // User number source, for openings and closings
+}
+
+// Reporter reports alarms, troubles, openings and closings to an alarm
+// receiving centre over SIA DC-09. Reports are stored first and sent in
+// order, one at a time, so nothing is lost while the receiver is unreachable.
+type Reporter struct {
+	config   Config
+	events   chan event
+	wake     chan struct{}
+	mu       sync.Mutex
+	sequence int
+}
+
+func NewReporter(config Config) (*Reporter, error) {
+	if config.Token != sia.TokenSIA && config.Token != sia.TokenCID {
+		return nil, fmt.Errorf("unknown SIA format %q, expected %s or %s", config.Token, sia.TokenSIA, sia.TokenCID)
+	}
+	if config.Protocol != "tcp" && config.Protocol != "udp" {
+		return nil, fmt.Errorf("unknown SIA protocol %q, expected tcp or udp", config.Protocol)
+	}
+	config.Account = strings.ToUpper(config.Account)
+	config.Receiver = strings.ToUpper(config.Receiver)
+	config.Prefix = strings.ToUpper(config.Prefix)
+	if config.Prefix == "" {
+		config.Prefix = "0"
+	}
+	if len(config.Account) < 3 || len(config.Account) > 16 || strings.Trim(config.Account, "0123456789ABCDEF") != "" {
+		return nil, fmt.Errorf("SIA account must be 3-16 hex digits, got %q", config.Account)
+	}
+
+	sequence, err := services.AlarmReport.LastSequence()
+	if err != nil {
+		return nil, err
+	}
+	return &Reporter{
+		config:   config,
+		events:   make(chan event, queueSize),
+		wake:     make(chan struct{}, 1),
+		sequence: sequence,
+	}, nil
+}
+
+// Start starts recording and sending reports, including those left pending by a previous run
+func (r *Reporter) Start() {
+	go r.record()
+	go r.send()
+}
+
+// HandleAlert is the alarm.AlarmHandler that reports raised alarms
+func (r *Reporter) HandleAlert(alert alarm.Alert) {
+	r.queue(event{code: burglaryCode, alarm: true, partitionID: alert.PartitionID, sensorID: alert.SensorID})
+}
+
+// HandleDuress is the alarm.DuressListener that reports duress as a hold-up
+// alarm. It runs before the forced action, so the station gets the duress
+// report ahead of the opening or closing that follows it.
+func (r *Reporter) HandleDuress(alert alarm.DuressAlert) {
+	r.queue(event{code: duressCode, partitionID: alert.PartitionID, user: alert.User})
+}
+
+// HandleStateChange is the alarm.StateListener that reports openings,
+// closings and cancelled alarms
+func (r *Reporter) HandleStateChange(change alarm.StateChange) {
+	if change.State == change.PreviousState {
+		return
+	}
+	e := event{partitionID: change.PartitionID, user: change.ChangedBy}
+	switch change.State {
+	case alarm.StateDisarmed:
+		if change.PreviousState == alarm.StateTriggered {
+			r.queue(event{code: cancelCode, partitionID: change.PartitionID, user: change.ChangedBy})
+		}
+		e.code = openingCode
+	case alarm.StateArmedAway:
+		e.code = closingCode
+	case alarm.StateArmedHome:
+		e.code = partialClosingCode
+	default:
+		return
+	}
+	r.queue(e)
+}
+
+// HandleTrouble is the trouble.Listener that reports raised and cleared troubles
+func (r *Reporter) HandleTrouble(te trouble.Event) {
+	codes := troubleCodes
+	switch te.Event {
+	case trouble.EventRaised:
+	case trouble.EventCleared:
+		codes = troubleRestoreCodes
+	default:
+		return
+	}
+	c, ok := codes[te.Trouble.Type]
+	if !ok {
+		return
+	}
+	r.queue(event{code: c, sensorID: te.Trouble.SensorID})
+}
+
+// Test queues a manual test report
+func (r *Reporter) Test(by string) {
+	r.queue(event{code: testCode, user: by})
+}
+
+// queue hands an event to the recorder without blocking, so it is safe to
+// call from listeners running under another component's lock
+func (r *Reporter) queue(e event) {
+	select {
+	case r.events <- e:
+	default:
+		log.Printf("SIA report queue full, dropping %s", e.code.Text)
+	}
+}
+
+// record stores queued events as pending reports
+func (r *Reporter) record() {
+	for e := range r.events {
+		c, number, description := r.resolve(e)
+		data, code := c.data(r.config.Token, e.partitionID, number)
+		report := &models.AlarmReport{
+			Token:       r.config.Token,
+			Sequence:    r.nextSequence(),
+			Account:     r.config.Account,
+			Code:        code,
+			Description: description,
+			PartitionID: e.partitionID,
+			Zone:        number,
+			Data:        data,
+			Status:      models.ReportPending,
+		}
+		if err := services.AlarmReport.Create(report); err != nil {
+			log.Printf("Error recording SIA report: %v", err)
+			continue
+		}
+
+		select {
+		case r.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// resolve returns the code of an event and its zone or user number, and describes it
+func (r *Reporter) resolve(e event) (code, int, string) {
+	c := e.code
+	if e.sensorID != "" {
+		// Sensors get their ID as zone number
+		sensor, err := services.Sensor.GetBySensorID(e.sensorID)
+		if err != nil {
+			log.Printf("Error fetching sensor: %v", err)
+		}
+		if sensor == nil {
+			return c, 0, c.Text + " on " + e.sensorID
+		}
+		if e.alarm {
+			c = alarmCode(sensor.Type)
+		}
+		return c, int(sensor.ID), fmt.Sprintf("%s on %s (zone %d)", c.Text, e.sensorID, sensor.ID%1000)
+	}
+	if e.user != "" {
+		// Users get their ID as user number, rules and the system 0
+		user, err := services.User.GetByUsername(e.user)
+		if err != nil {
+			log.Printf("Error fetching user: %v", err)
+		}
+		if user == nil {
+			return c, 0, c.Text + " by " + e.user
+		}
+		return c, int(user.ID), fmt.Sprintf("%s by %s (user %d)", c.Text, e.user, user.ID%1000)
+	}
+	return c, 0, c.Text
+}
+
+// send delivers pending reports in order, waiting for each to be
+// acknowledged before sending the next
+func (r *Reporter) send() {
+	failures := 0
+	var heartbeat <-chan time.Time
+	if r.config.Heartbeat > 0 {
+		ticker := time.NewTicker(r.config.Heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		report, err := services.AlarmReport.NextPending()
+		if err != nil {
+			log.Printf("Error fetching SIA report: %v", err)
+		}
+		if report == nil {
+			select {
+			case <-r.wake:
+			case <-heartbeat:
+				if err := r.exchange(sia.Message{Token: sia.TokenNull, Sequence: r.nextSequence(), Time: time.Now()}); err != nil {
+					log.Printf("SIA link test to %s failed: %v", r.config.Address, err)
+				}
+			case <-time.After(time.Minute):
+				// Recheck in case the database was unreachable
+			}
+			continue
+		}
+
+		if r.config.Expire > 0 && time.Since(report.CreatedAt) > r.config.Expire {
+			report.Status = models.ReportExpired
+			log.Printf("SIA report %d (%s) expired after %d attempts", report.ID, report.Description, report.Attempts)
+			r.save(report)
+			continue
+		}
+
+		now := time.Now()
+		report.Attempts++
+		report.SentAt = &now
+		err = r.exchange(sia.Message{Token: report.Token, Sequence: report.Sequence, Data: report.Data, Time: now})
+		if err == nil {
+			report.Status = models.ReportAcknowledged
+			report.AcknowledgedAt = &now
+			report.Error = ""
+			r.save(report)
+			failures = 0
+			continue
+		}
+
+		report.Error = err.Error()
+		r.save(report)
+		delay := min(r.config.RetryDelay<<min(failures, 10), maxRetryDelay)
+		failures++
+		log.Printf("SIA report %d to %s failed, retrying in %s: %v", report.ID, r.config.Address, delay, err)
+		time.Sleep(delay)
+	}
+}
+
+// exchange sends a message and waits for the receiver's reply
+func (r *Reporter) exchange(msg sia.Message) error {
+	msg.Encrypted = r.config.Key != nil
+	msg.Receiver = r.config.Receiver
+	msg.Prefix = r.config.Prefix
+	msg.Account = r.config.Account
+	frame, err := sia.Encode(msg, r.config.Key)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout(r.config.Protocol, r.config.Address, r.config.AckTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(r.config.AckTimeout))
+
+	if _, err := conn.Write(frame); err != nil {
+		return fmt.Errorf("failed to send: %w", err)
+	}
+
+	var reply []byte
+	if r.config.Protocol == "udp" {
+		buf := make([]byte, 1024)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return fmt.Errorf("no reply: %w", err)
+		}
+		reply = buf[:n]
+	} else {
+		// Frames end with a carriage return
+		reader := bufio.NewReader(conn)
+		if reply, err = reader.ReadBytes('\r'); err != nil {
+			return fmt.Errorf("no reply: %w", err)
+		}
+	}
+
+	ack, err := sia.Decode(reply, r.config.Key)
+	if err != nil {
+		return fmt.Errorf("bad reply: %w", err)
+	}
+	switch ack.Token {
+	case sia.TokenACK:
+		if ack.Sequence != msg.Sequence || (ack.Account != "" && ack.Account != msg.Account) {
+			return fmt.Errorf("ACK for sequence %04d #%s, expected %04d #%s", ack.Sequence, ack.Account, msg.Sequence, msg.Account)
+		}
+		return nil
+	case sia.TokenNAK:
+		// Usually our clock is off, the receiver sends its own time along
+		return fmt.Errorf("%w, receiver time is %s", ErrNAK, ack.Time.Format(time.RFC3339))
+	case sia.TokenDUH:
+		return ErrDUH
+	}
+	return fmt.Errorf("unexpected reply %s", ack.Token)
+}
+
+func (r *Reporter) nextSequence() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sequence = r.sequence%9999 + 1
+	return r.sequence
+}
+
+func (r *Reporter) save(report *models.AlarmReport) {
+	if err := services.AlarmReport.Update(report); err != nil {
+		log.Printf("Error saving SIA report: %v", err)
+	}
+}
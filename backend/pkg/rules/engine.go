@@ -0,0 +1,259 @@
+package rules
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/alarm"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// NotifyTopic is the WebSocket topic notify actions are pushed to until a
+// different Notifier is set
+const NotifyTopic = "notifications"
+
+// BroadcastPrefix is the prefix of the WebSocket topics broadcast actions may
+// use. The other topics carry the alarm state, alerts and duress events
+// clients trust, which rules must not be able to fake.
+const BroadcastPrefix = "rules/"
+
+// publishTimeout is how long an MQTT publish may take before it is logged as failed
+const publishTimeout = 5 * time.Second
+
+// Event is a stored sensor message the rules are evaluated against
+type Event struct {
+	Reading *models.SensorReading
+	Sensor  *models.Sensor
+	Kind    string // Message kind, the last topic segment
+}
+
+// Publisher publishes MQTT messages, implemented by mqtt.Client
+type Publisher interface {
+	Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token
+}
+
+// Notifier delivers the message of a notify action
+type Notifier func(title, message string)
+
+// Notification is broadcast on NotifyTopic by the default Notifier
+type Notification struct {
+	Title     string `json:"title"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Engine evaluates the enabled rules against incoming sensor messages and
+// executes the actions of the ones that match
+type Engine struct {
+	mu        sync.Mutex
+	hub       alarm.Broadcaster
+	system    *alarm.System
+	publisher Publisher
+	notify    Notifier
+	rules     []*models.Rule
+}
+
+// NewEngine creates an engine holding the enabled rules
+func NewEngine(hub alarm.Broadcaster, system *alarm.System) (*Engine, error) {
+	e := &Engine{hub: hub, system: system}
+	e.notify = e.BroadcastNotification
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Reload replaces the rules with the enabled rules in the database
+func (e *Engine) Reload() error {
+	enabled, err := services.Rule.ListEnabled()
+	if err != nil {
+		return err
+	}
+
+	rules := make([]*models.Rule, len(enabled))
+	for i := range enabled {
+		rules[i] = &enabled[i]
+	}
+
+	e.mu.Lock()
+	e.rules = rules
+	e.mu.Unlock()
+	return nil
+}
+
+// SetPublisher sets the MQTT client mqtt_publish actions use
+func (e *Engine) SetPublisher(publisher Publisher) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.publisher = publisher
+}
+
+// SetNotifier replaces where notify actions are delivered
+func (e *Engine) SetNotifier(notify Notifier) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.notify = notify
+}
+
+// Evaluate runs every rule matching the event
+func (e *Engine) Evaluate(event Event) {
+	now := time.Now()
+
+	// The partition is only looked up if a rule needs it
+	var panel *alarm.Panel
+	var zone *models.Zone
+	resolved := false
+	partition := func() (*alarm.Panel, *models.Zone) {
+		if !resolved {
+			panel, zone = e.system.PanelOf(event.Reading.SensorID, event.Sensor)
+			resolved = true
+		}
+		return panel, zone
+	}
+
+	for _, rule := range e.due(event, now, partition) {
+		log.Printf("Rule %d (%s) fired for sensor %s", rule.ID, rule.Name, event.Reading.SensorID)
+		if err := services.Rule.MarkFired(rule.ID, now); err != nil {
+			log.Printf("Error recording rule firing: %v", err)
+		}
+
+		target, _ := partition()
+		if rule.PartitionID != nil {
+			p, err := e.system.Panel(*rule.PartitionID)
+			if err != nil {
+				log.Printf("Rule %d acts on unknown partition %d", rule.ID, *rule.PartitionID)
+				continue
+			}
+			target = p
+		}
+		e.execute(rule, event, target)
+	}
+}
+
+// due returns copies of the rules that match the event and are not cooling
+// down, marking them as fired
+func (e *Engine) due(event Event, now time.Time, partition func() (*alarm.Panel, *models.Zone)) []models.Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var due []models.Rule
+	for _, rule := range e.rules {
+		if rule.LastFiredAt != nil && now.Sub(*rule.LastFiredAt) < time.Duration(rule.Cooldown)*time.Second {
+			continue
+		}
+		if !matches(rule, event, now, partition) {
+			continue
+		}
+		rule.LastFiredAt = &now
+		due = append(due, *rule)
+	}
+	return due
+}
+
+// matches reports whether every condition of the rule holds for the event
+func matches(rule *models.Rule, event Event, now time.Time, partition func() (*alarm.Panel, *models.Zone)) bool {
+	reading := event.Reading
+	if rule.SensorID != "" && rule.SensorID != reading.SensorID {
+		return false
+	}
+	if rule.Kind != "" && rule.Kind != event.Kind {
+		return false
+	}
+	if rule.SensorType != "" && (event.Sensor == nil || !strings.EqualFold(rule.SensorType, event.Sensor.Type)) {
+		return false
+	}
+	if rule.Operator != "" {
+		compare, ok := operators[rule.Operator]
+		if !ok || reading.Metric == "" || !compare(reading.Value, rule.Threshold) {
+			return false
+		}
+	}
+	if !inWindow(rule.TimeFrom, rule.TimeUntil, now) {
+		return false
+	}
+
+	if rule.ZoneID != nil || rule.ArmState != "" {
+		panel, zone := partition()
+		if rule.ZoneID != nil && (zone == nil || zone.ID != *rule.ZoneID) {
+			return false
+		}
+		if rule.ArmState != "" && (panel == nil || string(panel.Status().State) != rule.ArmState) {
+			return false
+		}
+	}
+	return true
+}
+
+// execute runs the action of a rule. Actions on the alarm panel are
+// recorded as changed by the rule.
+func (e *Engine) execute(rule models.Rule, event Event, panel *alarm.Panel) {
+	payload := render(rule.Payload, event)
+	by := "rule:" + rule.Name
+
+	switch rule.Action {
+	case models.RuleActionBroadcast:
+		// Rules saved before broadcasts were limited may still use other topics
+		if !strings.HasPrefix(rule.Topic, BroadcastPrefix) {
+			log.Printf("Rule %d can't broadcast to %s, only to topics below %s", rule.ID, rule.Topic, BroadcastPrefix)
+			return
+		}
+		e.hub.BroadcastToTopic([]byte(payload), rule.Topic)
+
+	case models.RuleActionMQTTPublish:
+		e.mu.Lock()
+		publisher := e.publisher
+		e.mu.Unlock()
+		if publisher == nil {
+			log.Printf("Rule %d can't publish to %s, MQTT is not connected", rule.ID, rule.Topic)
+			return
+		}
+		// Don't block the message handler waiting for the broker
+		token := publisher.Publish(rule.Topic, rule.QoS, false, payload)
+		go func() {
+			if !token.WaitTimeout(publishTimeout) {
+				log.Printf("Rule %d timed out publishing to %s", rule.ID, rule.Topic)
+			} else if err := token.Error(); err != nil {
+				log.Printf("Rule %d failed to publish to %s: %v", rule.ID, rule.Topic, err)
+			}
+		}()
+
+	case models.RuleActionNotify:
+		e.mu.Lock()
+		notify := e.notify
+		e.mu.Unlock()
+		notify(rule.Name, payload)
+
+	case models.RuleActionArm, models.RuleActionDisarm, models.RuleActionAlarm:
+		if panel == nil {
+			log.Printf("Rule %d has no partition to act on", rule.ID)
+			return
+		}
+		var err error
+		switch rule.Action {
+		case models.RuleActionArm:
+			err = panel.Arm(alarm.State(rule.ArmMode), by, false)
+		case models.RuleActionDisarm:
+			err = panel.Disarm(by)
+		case models.RuleActionAlarm:
+			panel.Trigger(event.Reading.SensorID, "rule "+rule.Name+": "+payload)
+		}
+		if err != nil {
+			log.Printf("Rule %d failed to %s partition %d: %v", rule.ID, rule.Action, panel.PartitionID(), err)
+		}
+	}
+}
+
+// BroadcastNotification is the default Notifier, pushing the message to NotifyTopic
+func (e *Engine) BroadcastNotification(title, message string) {
+	data, err := json.Marshal(Notification{Title: title, Message: message, Timestamp: time.Now().Unix()})
+	if err != nil {
+		log.Printf("Error marshalling notification: %v", err)
+		return
+	}
+	e.hub.BroadcastToTopic(data, NotifyTopic)
+}
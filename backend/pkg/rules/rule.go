@@ -0,0 +1,147 @@
+package rules
+
+import (
+	"backend/database/models"
+	"backend/pkg/alarm"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("invalid rule")
+
+var operators = map[string]func(value, threshold float64) bool{
+	"gt":  func(v, t float64) bool { return v > t },
+	"gte": func(v, t float64) bool { return v >= t },
+	"lt":  func(v, t float64) bool { return v < t },
+	"lte": func(v, t float64) bool { return v <= t },
+	"eq":  func(v, t float64) bool { return v == t },
+	"ne":  func(v, t float64) bool { return v != t },
+}
+
+var armStates = map[string]bool{
+	string(alarm.StateDisarmed):   true,
+	string(alarm.StateExitDelay):  true,
+	string(alarm.StateArmedHome):  true,
+	string(alarm.StateArmedAway):  true,
+	string(alarm.StateEntryDelay): true,
+	string(alarm.StateTriggered):  true,
+}
+
+// Validate checks that a rule can be evaluated and its action executed,
+// returning an error wrapping ErrInvalidRule if not
+func Validate(rule *models.Rule) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidRule, fmt.Sprintf(format, args...))
+	}
+
+	if rule.Name == "" {
+		return invalid("name is required")
+	}
+	if _, ok := operators[rule.Operator]; rule.Operator != "" && !ok {
+		return invalid("unknown operator %q, expected gt, gte, lt, lte, eq or ne", rule.Operator)
+	}
+	if (rule.TimeFrom == "") != (rule.TimeUntil == "") {
+		return invalid("time_from and time_until must be set together")
+	}
+	for _, t := range []string{rule.TimeFrom, rule.TimeUntil} {
+		if _, err := parseClock(t); t != "" && err != nil {
+			return invalid("%v", err)
+		}
+	}
+	if rule.ArmState != "" && !armStates[rule.ArmState] {
+		return invalid("unknown arm_state %q", rule.ArmState)
+	}
+	if rule.Cooldown < 0 {
+		return invalid("cooldown can't be negative")
+	}
+
+	switch rule.Action {
+	case models.RuleActionBroadcast, models.RuleActionMQTTPublish:
+		if rule.Topic == "" {
+			return invalid("topic is required for %s", rule.Action)
+		}
+		if strings.ContainsAny(rule.Topic, "+#") {
+			return invalid("topic can't contain wildcards")
+		}
+		if rule.QoS > 2 {
+			return invalid("qos must be 0, 1 or 2")
+		}
+		if rule.Action == models.RuleActionBroadcast && (!strings.HasPrefix(rule.Topic, BroadcastPrefix) || rule.Topic == BroadcastPrefix) {
+			return invalid("broadcast topic must start with %s", BroadcastPrefix)
+		}
+	case models.RuleActionArm:
+		if rule.ArmMode != string(alarm.StateArmedHome) && rule.ArmMode != string(alarm.StateArmedAway) {
+			return invalid("arm_mode must be armed_home or armed_away")
+		}
+	case models.RuleActionNotify, models.RuleActionDisarm, models.RuleActionAlarm:
+	default:
+		return invalid("unknown action %q", rule.Action)
+	}
+	return nil
+}
+
+// NeedsArmPermission reports whether a rule needs the permission to arm and
+// disarm. That is the case for rules that arm, disarm or trigger a partition,
+// and for rules publishing to MQTT, since a message on an actuator or Home
+// Assistant command topic can unlock doors, silence sirens or disarm.
+func NeedsArmPermission(rule *models.Rule) bool {
+	switch rule.Action {
+	case models.RuleActionArm, models.RuleActionDisarm, models.RuleActionAlarm, models.RuleActionMQTTPublish:
+		return true
+	}
+	return false
+}
+
+// parseClock parses HH:MM into minutes after midnight
+func parseClock(s string) (int, error) {
+	hours, minutes, ok := strings.Cut(s, ":")
+	h, herr := strconv.Atoi(hours)
+	m, merr := strconv.Atoi(minutes)
+	if !ok || herr != nil || merr != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return h*60 + m, nil
+}
+
+// inWindow reports whether t falls in the daily window from-until. Windows
+// where until is before from wrap past midnight.
+func inWindow(from, until string, t time.Time) bool {
+	if from == "" {
+		return true
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(until)
+	if err != nil {
+		return false
+	}
+
+	now := t.Hour()*60 + t.Minute()
+	if start <= end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// render fills in the placeholders of a payload template
+func render(template string, event Event) string {
+	if template == "" {
+		return event.Reading.Message
+	}
+	value := ""
+	if event.Reading.Metric != "" {
+		value = strconv.FormatFloat(event.Reading.Value, 'f', -1, 64)
+	}
+	return strings.NewReplacer(
+		"{sensor_id}", event.Reading.SensorID,
+		"{kind}", event.Kind,
+		"{value}", value,
+		"{unit}", event.Reading.Unit,
+		"{message}", event.Reading.Message,
+	).Replace(template)
+}
@@ -0,0 +1,239 @@
+package sia
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message IDs (tokens) of SIA DC-09
+const (
+	TokenSIA  = "SIA-DCS" // SIA DC-03 event codes, e.g. BA for burglary alarm
+	TokenCID  = "ADM-CID" // Contact ID event codes, e.g. 1130
+	TokenNull = "NULL"    // Link test without an event
+	TokenACK  = "ACK"
+	TokenNAK  = "NAK"
+	TokenDUH  = "DUH" // The receiver understood the frame but not the message
+)
+
+var (
+	ErrInvalidFrame = errors.New("invalid SIA DC-09 frame")
+	ErrCRC          = errors.New("CRC mismatch")
+	ErrNoKey        = errors.New("message is encrypted but no key is configured")
+)
+
+// Message is a SIA DC-09 message, without the framing
+type Message struct {
+	Token     string
+	Encrypted bool
+	Sequence  int    // 1-9999, the reply to a message carries its sequence number
+	Receiver  string // Receiver number in hex, optional
+	Prefix    string // Account prefix in hex
+	Account   string // Account number in hex
+	Data      string // Event data after the account, e.g. "Nri1/BA015" or "1130 01 015"
+	Time      time.Time
+}
+
+var headerPattern = regexp.MustCompile(`^"(\*?)([A-Z-]+)"(\d{4})(?:R([0-9A-F]{1,6}))?(?:L([0-9A-F]{1,6}))?(?:#([0-9A-F]{3,16}))?\[`)
+
+// Encode builds the frame of a message. Messages with Encrypted set are
+// encrypted with key, AES-128, 192 or 256 depending on its length.
+func Encode(msg Message, key []byte) ([]byte, error) {
+	var body strings.Builder
+	body.WriteString(`"`)
+	if msg.Encrypted {
+		body.WriteString("*")
+	}
+	body.WriteString(msg.Token + `"`)
+	body.WriteString(fmt.Sprintf("%04d", msg.Sequence))
+	if msg.Receiver != "" {
+		body.WriteString("R" + msg.Receiver)
+	}
+	body.WriteString("L" + msg.Prefix)
+	if msg.Account != "" {
+		body.WriteString("#" + msg.Account)
+	}
+
+	timestamp := formatTimestamp(msg.Time)
+	if msg.Encrypted {
+		// The padding in front of the | replaces the account
+		encrypted, err := encrypt(key, "|"+msg.Data+"]"+timestamp)
+		if err != nil {
+			return nil, err
+		}
+		body.WriteString("[" + encrypted)
+	} else {
+		data := ""
+		if msg.Data != "" {
+			data = "#" + msg.Account + "|" + msg.Data
+		}
+		body.WriteString("[" + data + "]" + timestamp)
+	}
+
+	content := body.String()
+	if len(content) > 0xFFF {
+		return nil, fmt.Errorf("%w: message too long", ErrInvalidFrame)
+	}
+	return []byte(fmt.Sprintf("\n%04X0%03X%s\r", crc16([]byte(content)), len(content), content)), nil
+}
+
+// Decode parses a frame, checking its CRC and length and decrypting it
+// with key if it is encrypted
+func Decode(frame []byte, key []byte) (Message, error) {
+	var msg Message
+	s := strings.Trim(string(frame), "\r\n\x00")
+	if len(s) < 8 {
+		return msg, fmt.Errorf("%w: too short", ErrInvalidFrame)
+	}
+
+	crc, err := strconv.ParseUint(s[:4], 16, 16)
+	if err != nil {
+		return msg, fmt.Errorf("%w: bad CRC field", ErrInvalidFrame)
+	}
+	length, err := strconv.ParseUint(s[4:8], 16, 16)
+	if err != nil {
+		return msg, fmt.Errorf("%w: bad length field", ErrInvalidFrame)
+	}
+	content := s[8:]
+	if int(length) != len(content) {
+		return msg, fmt.Errorf("%w: length is %d, header says %d", ErrInvalidFrame, len(content), length)
+	}
+	if uint16(crc) != crc16([]byte(content)) {
+		return msg, ErrCRC
+	}
+
+	header := headerPattern.FindStringSubmatch(content)
+	if header == nil {
+		return msg, fmt.Errorf("%w: bad header", ErrInvalidFrame)
+	}
+	msg.Encrypted = header[1] == "*"
+	msg.Token = header[2]
+	msg.Sequence, _ = strconv.Atoi(header[3])
+	msg.Receiver = header[4]
+	msg.Prefix = header[5]
+	msg.Account = header[6]
+
+	rest := content[len(header[0]):]
+	if msg.Encrypted {
+		if len(key) == 0 {
+			return msg, ErrNoKey
+		}
+		decrypted, err := decrypt(key, rest)
+		if err != nil {
+			return msg, err
+		}
+		// Everything before the first | is padding
+		pipe := strings.IndexByte(decrypted, '|')
+		if pipe < 0 {
+			return msg, fmt.Errorf("%w: bad decrypted content", ErrInvalidFrame)
+		}
+		rest = decrypted[pipe+1:]
+	}
+
+	end := strings.LastIndexByte(rest, ']')
+	if end < 0 {
+		return msg, fmt.Errorf("%w: unterminated data", ErrInvalidFrame)
+	}
+	data, extra := rest[:end], rest[end+1:]
+	// Unencrypted data repeats the account: #acct|data
+	if pipe := strings.IndexByte(data, '|'); pipe >= 0 && !msg.Encrypted {
+		data = data[pipe+1:]
+	}
+	msg.Data = data
+
+	if i := strings.IndexByte(extra, '_'); i >= 0 {
+		msg.Time, _ = parseTimestamp(extra[i:])
+	}
+	return msg, nil
+}
+
+// formatTimestamp writes a DC-09 timestamp, _HH:MM:SS,MM-DD-YYYY in UTC.
+// time.Format can't be used, it reads ",0" as fractional seconds.
+func formatTimestamp(t time.Time) string {
+	t = t.UTC()
+	return fmt.Sprintf("_%02d:%02d:%02d,%02d-%02d-%04d", t.Hour(), t.Minute(), t.Second(), t.Month(), t.Day(), t.Year())
+}
+
+func parseTimestamp(s string) (time.Time, error) {
+	var hour, minute, second, month, day, year int
+	if _, err := fmt.Sscanf(s, "_%2d:%2d:%2d,%2d-%2d-%4d", &hour, &minute, &second, &month, &day, &year); err != nil {
+		return time.Time{}, fmt.Errorf("%w: bad timestamp %q", ErrInvalidFrame, s)
+	}
+	return time.Date(year, time.Month(month), day, hour, minute, second, 0, time.UTC), nil
+}
+
+// ParseKey reads an AES key given in hex, returning nil for an empty string
+func ParseKey(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	key, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	}
+	return nil, fmt.Errorf("invalid key: must be 16, 24 or 32 bytes, got %d", len(key))
+}
+
+// encrypt pads plain with random characters to the AES block size and
+// encrypts it with CBC and a zero IV, as DC-09 specifies
+func encrypt(key []byte, plain string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", fmt.Errorf("invalid key: %w", err)
+	}
+
+	padding := aes.BlockSize - len(plain)%aes.BlockSize
+	pad := make([]byte, padding)
+	if _, err := rand.Read(pad); err != nil {
+		return "", fmt.Errorf("failed to generate padding: %w", err)
+	}
+	// Padding must not contain the characters that delimit the data
+	const padChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	for i := range pad {
+		pad[i] = padChars[int(pad[i])%len(padChars)]
+	}
+
+	data := append(pad, plain...)
+	cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(data, data)
+	return strings.ToUpper(hex.EncodeToString(data)), nil
+}
+
+func decrypt(key []byte, encrypted string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", fmt.Errorf("invalid key: %w", err)
+	}
+	data, err := hex.DecodeString(encrypted)
+	if err != nil || len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return "", fmt.Errorf("%w: bad encrypted content", ErrInvalidFrame)
+	}
+	cipher.NewCBCDecrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(data, data)
+	return string(data), nil
+}
+
+// crc16 is the CRC-16/ARC checksum DC-09 frames carry
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b)
+		for range 8 {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,185 @@
+package sia
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCRC16(t *testing.T) {
+	tests := []struct {
+		data string
+		want uint16
+	}{
+		{data: "", want: 0x0000},
+		// Check value of CRC-16/ARC
+		{data: "123456789", want: 0xBB3D},
+		{data: `"ACK"0042R1L0#1234[]_12:00:05,10-18-2026`, want: 0x43E9},
+	}
+	for _, tt := range tests {
+		if got := crc16([]byte(tt.data)); got != tt.want {
+			t.Errorf("crc16(%q) = %04X, want %04X", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	key128, err := ParseKey("000102030405060708090A0B0C0D0E0F")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key256, err := ParseKey(strings.Repeat("AB", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 10, 18, 12, 0, 5, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		msg  Message
+		key  []byte
+	}{
+		{
+			name: "SIA event",
+			msg:  Message{Token: TokenSIA, Sequence: 1, Receiver: "1", Prefix: "0", Account: "1234", Data: "Nri1/BA015", Time: at},
+		},
+		{
+			name: "Contact ID event",
+			msg:  Message{Token: TokenCID, Sequence: 9999, Prefix: "A1", Account: "ABCDEF", Data: "1130 01 015", Time: at},
+		},
+		{
+			name: "link test",
+			msg:  Message{Token: TokenNull, Sequence: 17, Prefix: "0", Account: "1234", Time: at},
+		},
+		{
+			name: "encrypted with AES-128",
+			msg:  Message{Token: TokenSIA, Encrypted: true, Sequence: 2, Receiver: "1", Prefix: "0", Account: "1234", Data: "Nri1/BA015", Time: at},
+			key:  key128,
+		},
+		{
+			name: "encrypted with AES-256",
+			msg:  Message{Token: TokenCID, Encrypted: true, Sequence: 3, Prefix: "0", Account: "1234", Data: "1130 01 015", Time: at},
+			key:  key256,
+		},
+		{
+			name: "encrypted link test",
+			msg:  Message{Token: TokenNull, Encrypted: true, Sequence: 4, Prefix: "0", Account: "1234", Time: at},
+			key:  key128,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := Encode(tt.msg, tt.key)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if frame[0] != '\n' || frame[len(frame)-1] != '\r' {
+				t.Errorf("frame %q is not enclosed in LF and CR", frame)
+			}
+			if tt.msg.Encrypted && strings.Contains(string(frame), tt.msg.Data+"]") {
+				t.Errorf("frame %q contains the plain data", frame)
+			}
+
+			got, err := Decode(frame, tt.key)
+			if err != nil {
+				t.Fatalf("Decode(%q): %v", frame, err)
+			}
+			if !got.Time.Equal(tt.msg.Time) {
+				t.Errorf("Time = %v, want %v", got.Time, tt.msg.Time)
+			}
+			got.Time = tt.msg.Time
+			if got != tt.msg {
+				t.Errorf("Decode(%q) = %+v, want %+v", frame, got, tt.msg)
+			}
+		})
+	}
+}
+
+func TestDecodeACK(t *testing.T) {
+	frame := []byte("\n43E90028\"ACK\"0042R1L0#1234[]_12:00:05,10-18-2026\r")
+	want := Message{
+		Token:    TokenACK,
+		Sequence: 42,
+		Receiver: "1",
+		Prefix:   "0",
+		Account:  "1234",
+		Time:     time.Date(2026, 10, 18, 12, 0, 5, 0, time.UTC),
+	}
+
+	got, err := Decode(frame, nil)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got != want {
+		t.Errorf("Decode = %+v, want %+v", got, want)
+	}
+
+	encoded, err := Encode(want, nil)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if string(encoded) != string(frame) {
+		t.Errorf("Encode = %q, want %q", encoded, frame)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	key, err := ParseKey("000102030405060708090A0B0C0D0E0F")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := Encode(Message{Token: TokenSIA, Encrypted: true, Sequence: 1, Prefix: "0", Account: "1234", Data: "Nri1/BA015"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const ack = "\n43E90028\"ACK\"0042R1L0#1234[]_12:00:05,10-18-2026\r"
+
+	tests := []struct {
+		name  string
+		frame string
+		key   []byte
+		want  error
+	}{
+		{name: "too short", frame: "\n43E9\r", want: ErrInvalidFrame},
+		{name: "bad CRC field", frame: strings.Replace(ack, "43E9", "XXXX", 1), want: ErrInvalidFrame},
+		{name: "length too long", frame: strings.Replace(ack, "0028", "0029", 1), want: ErrInvalidFrame},
+		{name: "length too short", frame: strings.Replace(ack, "0028", "0027", 1), want: ErrInvalidFrame},
+		{name: "CRC mismatch", frame: strings.Replace(ack, "43E9", "43EA", 1), want: ErrCRC},
+		{name: "corrupted content", frame: strings.Replace(ack, "#1234", "#1235", 1), want: ErrCRC},
+		{name: "encrypted without key", frame: string(encrypted), want: ErrNoKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.frame), tt.key)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Decode(%q) error = %v, want %v", tt.frame, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantLen int
+		wantErr bool
+	}{
+		{key: "", wantLen: 0},
+		{key: strings.Repeat("00", 16), wantLen: 16},
+		{key: strings.Repeat("00", 24), wantLen: 24},
+		{key: strings.Repeat("00", 32), wantLen: 32},
+		{key: strings.Repeat("00", 15), wantErr: true},
+		{key: "not hex", wantErr: true},
+	}
+	for _, tt := range tests {
+		key, err := ParseKey(tt.key)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseKey(%q) error = %v, want error %v", tt.key, err, tt.wantErr)
+			continue
+		}
+		if len(key) != tt.wantLen {
+			t.Errorf("ParseKey(%q) gave %d bytes, want %d", tt.key, len(key), tt.wantLen)
+		}
+	}
+}
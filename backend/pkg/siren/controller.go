@@ -0,0 +1,229 @@
+package siren
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/actuator"
+	"backend/pkg/alarm"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// Topic is the WebSocket topic siren status changes are pushed to
+const Topic = "sirens"
+
+// Siren states of a partition
+const (
+	StateIdle     = "idle"     // Quiet, sounds on the next alarm
+	StateSounding = "sounding" // Sounding until the cutoff, a silence or a disarm
+	StateCutOff   = "cut_off"  // Silenced by the cutoff, sounds again on the next alarm
+	StateSilenced = "silenced" // Silenced by a user, stays quiet until the partition is disarmed
+)
+
+var ErrNotSounding = errors.New("sirens are not sounding")
+
+// Status of the sirens of a partition
+type Status struct {
+	PartitionID uint       `json:"partition_id"`
+	State       string     `json:"state"`
+	Soundings   int        `json:"soundings"` // Times the sirens sounded since the alarm was triggered
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CutoffAt    *time.Time `json:"cutoff_at,omitempty"`
+	SilencedBy  string     `json:"silenced_by,omitempty"`
+}
+
+// Config of the siren controller
+type Config struct {
+	Cutoff       time.Duration // Sirens are silenced after sounding this long
+	Pattern      string        // Sound pattern sent to the sirens, e.g. continuous, pulse or temporal3
+	Strobe       bool          // Whether sirens with a strobe light flash it
+	MaxSoundings int           // How often sirens sound again after a cutoff per alarm, 0 for no limit
+}
+
+type partitionSirens struct {
+	status     Status
+	generation int // Bumped on every change so a stale cutoff timer does nothing
+}
+
+// Controller sounds the siren actuators of a partition when its alarm is
+// triggered and silences them after the cutoff, when a user silences them or
+// when the partition is disarmed. Silencing doesn't touch the incident.
+type Controller struct {
+	mu               sync.Mutex
+	hub              alarm.Broadcaster
+	actuators        *actuator.Manager
+	defaultPartition uint
+	config           Config
+	partitions       map[uint]*partitionSirens
+}
+
+func NewController(hub alarm.Broadcaster, actuators *actuator.Manager, defaultPartition uint, config Config) *Controller {
+	return &Controller{
+		hub:              hub,
+		actuators:        actuators,
+		defaultPartition: defaultPartition,
+		config:           config,
+		partitions:       make(map[uint]*partitionSirens),
+	}
+}
+
+// HandleStateChange is the alarm.StateListener that drives the sirens
+func (c *Controller) HandleStateChange(change alarm.StateChange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ps := c.partition(change.PartitionID)
+	switch change.State {
+	case alarm.StateTriggered:
+		switch ps.status.State {
+		case StateSounding, StateSilenced:
+			return
+		}
+		c.soundAgain(ps, change.TriggeredBy)
+
+	case alarm.StateDisarmed:
+		if ps.status.State == StateSounding {
+			c.command(ps.status.PartitionID, map[string]interface{}{"state": "off"}, change.ChangedBy)
+		}
+		if ps.status.State != StateIdle {
+			ps.generation++
+			ps.status = Status{PartitionID: change.PartitionID, State: StateIdle}
+			c.broadcast(ps.status)
+		}
+	}
+}
+
+// HandleAlert sounds the sirens again for a sensor tripping in a partition
+// that is already triggered, once the cutoff silenced them. The panel stays
+// triggered then, so there is no state change to react to.
+func (c *Controller) HandleAlert(alert alarm.Alert) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ps := c.partition(alert.PartitionID)
+	if ps.status.State != StateCutOff {
+		return
+	}
+	c.soundAgain(ps, alert.SensorID)
+}
+
+// soundAgain sounds the sirens unless they already sounded MaxSoundings
+// times for this alarm. Must be called with c.mu held.
+func (c *Controller) soundAgain(ps *partitionSirens, triggeredBy string) {
+	if c.config.MaxSoundings > 0 && ps.status.Soundings >= c.config.MaxSoundings {
+		log.Printf("Sirens of partition %d already sounded %d times for this alarm", ps.status.PartitionID, ps.status.Soundings)
+		return
+	}
+	c.sound(ps, triggeredBy)
+}
+
+// Silence turns off the sirens of a partition until it is disarmed. The
+// alarm stays triggered and the incident open.
+func (c *Controller) Silence(partitionID uint, by string) (Status, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ps := c.partition(partitionID)
+	if ps.status.State != StateSounding {
+		return ps.status, ErrNotSounding
+	}
+
+	c.command(partitionID, map[string]interface{}{"state": "off"}, by)
+	ps.generation++
+	ps.status.State = StateSilenced
+	ps.status.CutoffAt = nil
+	ps.status.SilencedBy = by
+	log.Printf("Sirens of partition %d silenced by %s", partitionID, by)
+	c.broadcast(ps.status)
+	return ps.status, nil
+}
+
+// Status returns the siren status of a partition
+func (c *Controller) Status(partitionID uint) Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.partition(partitionID).status
+}
+
+// sound turns on the sirens and schedules the cutoff. Must be called with c.mu held.
+func (c *Controller) sound(ps *partitionSirens, triggeredBy string) {
+	partitionID := ps.status.PartitionID
+	// Sirens switch themselves off after the cutoff too, in case the backend goes away
+	c.command(partitionID, map[string]interface{}{
+		"state":    "on",
+		"pattern":  c.config.Pattern,
+		"strobe":   c.config.Strobe,
+		"duration": int(c.config.Cutoff.Seconds()),
+	}, triggeredBy)
+
+	now := time.Now()
+	cutoff := now.Add(c.config.Cutoff)
+	ps.generation++
+	ps.status.State = StateSounding
+	ps.status.Soundings++
+	ps.status.StartedAt = &now
+	ps.status.CutoffAt = &cutoff
+	ps.status.SilencedBy = ""
+	log.Printf("Sirens of partition %d sounding until %s", partitionID, cutoff.Format(time.TimeOnly))
+	c.broadcast(ps.status)
+
+	generation := ps.generation
+	time.AfterFunc(c.config.Cutoff, func() { c.cutOff(partitionID, generation) })
+}
+
+// cutOff silences sirens that are still sounding when the cutoff passes
+func (c *Controller) cutOff(partitionID uint, generation int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ps := c.partition(partitionID)
+	if ps.generation != generation || ps.status.State != StateSounding {
+		return
+	}
+
+	c.command(partitionID, map[string]interface{}{"state": "off"}, "siren cutoff")
+	ps.generation++
+	ps.status.State = StateCutOff
+	ps.status.CutoffAt = nil
+	log.Printf("Sirens of partition %d cut off after %s", partitionID, c.config.Cutoff)
+	c.broadcast(ps.status)
+}
+
+// command sends a command to every siren of a partition. Must be called with c.mu held.
+func (c *Controller) command(partitionID uint, command map[string]interface{}, by string) {
+	sirens, err := services.Actuator.ListInPartition(models.ActuatorTypeSiren, partitionID, partitionID == c.defaultPartition)
+	if err != nil {
+		log.Printf("Error fetching sirens: %v", err)
+		return
+	}
+	if len(sirens) == 0 {
+		log.Printf("No sirens in partition %d", partitionID)
+	}
+	for i := range sirens {
+		if _, err := c.actuators.Send(&sirens[i], command, by); err != nil {
+			log.Printf("Error sending command to siren %s: %v", sirens[i].ActuatorID, err)
+		}
+	}
+}
+
+// partition returns the siren state of a partition. Must be called with c.mu held.
+func (c *Controller) partition(partitionID uint) *partitionSirens {
+	ps, ok := c.partitions[partitionID]
+	if !ok {
+		ps = &partitionSirens{status: Status{PartitionID: partitionID, State: StateIdle}}
+		c.partitions[partitionID] = ps
+	}
+	return ps
+}
+
+func (c *Controller) broadcast(status Status) {
+	message, err := json.Marshal(status)
+	if err != nil {
+		log.Printf("Error marshalling siren status: %v", err)
+		return
+	}
+	c.hub.BroadcastToTopic(message, Topic)
+}
@@ -0,0 +1,101 @@
+package supervision
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/trouble"
+	"log"
+	"time"
+)
+
+// Supervisor tracks when each sensor was last heard from and raises a
+// supervision trouble for sensors that stay silent for longer than the
+// window. Sensors only become supervised after their first message.
+type Supervisor struct {
+	troubles  *trouble.Manager
+	window    time.Duration
+	startedAt time.Time
+}
+
+// NewSupervisor creates a supervisor that marks sensors offline after window
+func NewSupervisor(troubles *trouble.Manager, window time.Duration) *Supervisor {
+	return &Supervisor{troubles: troubles, window: window, startedAt: time.Now()}
+}
+
+// Start checks for silent sensors in the background
+func (s *Supervisor) Start() {
+	// Check often enough that a sensor is flagged at most a third of the window late
+	interval := max(s.window/3, time.Second)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.check()
+		}
+	}()
+	log.Printf("Supervising sensors, offline after %s of silence", s.window)
+}
+
+// Seen records a message from a sensor, bringing it back online if it was offline
+func (s *Supervisor) Seen(sensor *models.Sensor, at time.Time) {
+	// Sensors that were never seen start out offline too, their first
+	// message may have been an offline status that raised a trouble
+	wasOffline := !sensor.Online
+	firstSeen := sensor.LastSeenAt == nil
+	if err := services.Sensor.MarkSeen(sensor.SensorID, at); err != nil {
+		log.Printf("Error recording sensor heartbeat: %v", err)
+		return
+	}
+	sensor.LastSeenAt = &at
+	sensor.Online = true
+
+	if wasOffline {
+		if !firstSeen {
+			log.Printf("Sensor %s is back online", sensor.SensorID)
+		}
+		s.troubles.Clear(sensor.SensorID, models.TroubleSupervision)
+	}
+}
+
+// Offline marks a sensor offline right away, used when it announces that
+// it is going offline instead of waiting for the window to pass
+func (s *Supervisor) Offline(sensor *models.Sensor) {
+	if sensor.LastSeenAt != nil && !sensor.Online {
+		return
+	}
+	if err := services.Sensor.SetOffline(sensor.SensorID); err != nil {
+		log.Printf("Error marking sensor offline: %v", err)
+		return
+	}
+	sensor.Online = false
+
+	if sensor.Status == models.SensorStatusActive {
+		log.Printf("Supervision failure: sensor %s reported offline", sensor.SensorID)
+		s.troubles.Raise(sensor.SensorID, models.TroubleSupervision, "Sensor reported offline")
+	}
+}
+
+// check marks every sensor that was silent for longer than the window offline
+func (s *Supervisor) check() {
+	now := time.Now()
+	// Give sensors a full window to report in after the backend was down
+	if now.Sub(s.startedAt) < s.window {
+		return
+	}
+
+	sensors, err := services.Sensor.ListStale(now.Add(-s.window))
+	if err != nil {
+		log.Printf("Error checking sensor supervision: %v", err)
+		return
+	}
+
+	for _, sensor := range sensors {
+		if err := services.Sensor.SetOffline(sensor.SensorID); err != nil {
+			log.Printf("Error marking sensor offline: %v", err)
+			continue
+		}
+		lastSeen := sensor.LastSeenAt.Format(time.RFC3339)
+		log.Printf("Supervision failure: sensor %s not seen since %s", sensor.SensorID, lastSeen)
+		s.troubles.Raise(sensor.SensorID, models.TroubleSupervision, "Not seen since "+lastSeen)
+	}
+}
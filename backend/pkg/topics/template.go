@@ -0,0 +1,160 @@
+package topics
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Fields a template can extract from a topic
+const (
+	FieldSensorID = "sensor_id"
+	FieldKind     = "kind"
+	FieldLocation = "location" // Location unknown sensors are registered with
+	FieldType     = "type"     // Type unknown sensors are registered with
+)
+
+var knownFields = map[string]bool{FieldSensorID: true, FieldKind: true, FieldLocation: true, FieldType: true}
+
+var ErrInvalidTemplate = errors.New("invalid topic template")
+
+// Template is a topic pattern like home/{location}/{sensor_id}/{kind}. A
+// {field} segment extracts that segment of the topic, + matches any segment
+// and a trailing # any number of them.
+type Template struct {
+	raw      string
+	segments []string
+}
+
+// Parse checks a template. Every field may appear once.
+func Parse(template string) (*Template, error) {
+	if template == "" {
+		return nil, fmt.Errorf("%w: empty template", ErrInvalidTemplate)
+	}
+	segments := strings.Split(template, "/")
+	seen := make(map[string]bool)
+	for i, segment := range segments {
+		if segment == "#" && i != len(segments)-1 {
+			return nil, fmt.Errorf("%w: # must be the last segment of %s", ErrInvalidTemplate, template)
+		}
+		name, isField := field(segment)
+		if !isField {
+			if strings.ContainsAny(segment, "{}#+") && segment != "+" && segment != "#" {
+				return nil, fmt.Errorf("%w: invalid segment %q in %s", ErrInvalidTemplate, segment, template)
+			}
+			continue
+		}
+		if !knownFields[name] {
+			return nil, fmt.Errorf("%w: unknown field {%s} in %s", ErrInvalidTemplate, name, template)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: field {%s} appears twice in %s", ErrInvalidTemplate, name, template)
+		}
+		seen[name] = true
+	}
+	return &Template{raw: template, segments: segments}, nil
+}
+
+func (t *Template) String() string {
+	return t.raw
+}
+
+// Has reports whether the template extracts a field
+func (t *Template) Has(name string) bool {
+	for _, segment := range t.segments {
+		if n, ok := field(segment); ok && n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter is the MQTT topic filter to subscribe to for the template
+func (t *Template) Filter() string {
+	filter := make([]string, len(t.segments))
+	for i, segment := range t.segments {
+		if _, ok := field(segment); ok {
+			segment = "+"
+		}
+		filter[i] = segment
+	}
+	return strings.Join(filter, "/")
+}
+
+// Match extracts the fields of a topic, ok is false if the topic doesn't
+// match the template
+func (t *Template) Match(topic string) (fields map[string]string, ok bool) {
+	parts := strings.Split(topic, "/")
+	fields = make(map[string]string)
+	for i, segment := range t.segments {
+		if segment == "#" {
+			return fields, true
+		}
+		if i >= len(parts) {
+			return nil, false
+		}
+		if name, isField := field(segment); isField {
+			if parts[i] == "" {
+				return nil, false
+			}
+			fields[name] = parts[i]
+		} else if segment != "+" && segment != parts[i] {
+			return nil, false
+		}
+	}
+	if len(parts) != len(t.segments) {
+		return nil, false
+	}
+	return fields, true
+}
+
+func field(segment string) (string, bool) {
+	if len(segment) > 2 && strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+// Subscription is a topic template sensors publish on and the QoS to subscribe with
+type Subscription struct {
+	Template *Template
+	QoS      byte
+}
+
+// ParseSubscriptions reads a comma separated list of templates, each with an
+// optional @qos suffix, e.g. "sensor/{sensor_id}/{kind}@1,home/{location}/{sensor_id}/{kind}@0".
+// Templates without a QoS use defaultQoS. Every template must extract the
+// sensor ID and the message kind.
+func ParseSubscriptions(spec string, defaultQoS byte) ([]Subscription, error) {
+	var subscriptions []Subscription
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		qos := defaultQoS
+		if at := strings.LastIndex(entry, "@"); at >= 0 {
+			n, err := strconv.Atoi(entry[at+1:])
+			if err != nil || n < 0 || n > 2 {
+				return nil, fmt.Errorf("%w: QoS of %s must be 0, 1 or 2", ErrInvalidTemplate, entry)
+			}
+			qos = byte(n)
+			entry = entry[:at]
+		}
+
+		template, err := Parse(entry)
+		if err != nil {
+			return nil, err
+		}
+		if !template.Has(FieldSensorID) || !template.Has(FieldKind) {
+			return nil, fmt.Errorf("%w: %s must contain {%s} and {%s}", ErrInvalidTemplate, entry, FieldSensorID, FieldKind)
+		}
+		subscriptions = append(subscriptions, Subscription{Template: template, QoS: qos})
+	}
+	if len(subscriptions) == 0 {
+		return nil, fmt.Errorf("%w: no subscriptions configured", ErrInvalidTemplate)
+	}
+	return subscriptions, nil
+}
@@ -0,0 +1,153 @@
+package topics
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  bool
+	}{
+		{template: "sensor/{sensor_id}/{kind}"},
+		{template: "home/{location}/{type}/{sensor_id}/{kind}"},
+		{template: "sensor/+/{sensor_id}/{kind}"},
+		{template: "sensor/{sensor_id}/{kind}/#"},
+		{template: "", wantErr: true},
+		{template: "sensor/{sensor_id}/{sensor_id}", wantErr: true},
+		{template: "sensor/{name}/{kind}", wantErr: true},
+		{template: "sensor/#/{kind}", wantErr: true},
+		{template: "sensor/{sensor_id}x/{kind}", wantErr: true},
+		{template: "sensor/a+b/{kind}", wantErr: true},
+		{template: "sensor/{}/{kind}", wantErr: true},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.template)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, want error %v", tt.template, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidTemplate) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidTemplate", tt.template, err)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{template: "sensor/{sensor_id}/{kind}", want: "sensor/+/+"},
+		{template: "home/{location}/+/{sensor_id}/{kind}", want: "home/+/+/+/+"},
+		{template: "sensor/{sensor_id}/{kind}/#", want: "sensor/+/+/#"},
+	}
+	for _, tt := range tests {
+		template, err := Parse(tt.template)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.template, err)
+		}
+		if got := template.Filter(); got != tt.want {
+			t.Errorf("Filter of %q = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		template string
+		topic    string
+		want     map[string]string // nil if the topic must not match
+	}{
+		{
+			template: "sensor/{sensor_id}/{kind}",
+			topic:    "sensor/door-1/alarm",
+			want:     map[string]string{FieldSensorID: "door-1", FieldKind: "alarm"},
+		},
+		{
+			template: "home/{location}/{sensor_id}/{kind}",
+			topic:    "home/kitchen/smoke-1/alarm",
+			want:     map[string]string{FieldLocation: "kitchen", FieldSensorID: "smoke-1", FieldKind: "alarm"},
+		},
+		{
+			template: "sensor/+/{sensor_id}/{kind}",
+			topic:    "sensor/anything/door-1/alarm",
+			want:     map[string]string{FieldSensorID: "door-1", FieldKind: "alarm"},
+		},
+		{
+			template: "sensor/{sensor_id}/{kind}/#",
+			topic:    "sensor/door-1/alarm/extra/levels",
+			want:     map[string]string{FieldSensorID: "door-1", FieldKind: "alarm"},
+		},
+		{template: "sensor/{sensor_id}/{kind}", topic: "sensor/door-1"},
+		{template: "sensor/{sensor_id}/{kind}", topic: "sensor/door-1/alarm/extra"},
+		{template: "sensor/{sensor_id}/{kind}", topic: "sensor//alarm"},
+		{template: "sensor/{sensor_id}/{kind}", topic: "other/door-1/alarm"},
+		{template: "sensor/{sensor_id}/{kind}/#", topic: "sensor/door-1"},
+	}
+	for _, tt := range tests {
+		template, err := Parse(tt.template)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.template, err)
+		}
+		got, ok := template.Match(tt.topic)
+		if ok != (tt.want != nil) {
+			t.Errorf("%q matching %q = %v, want %v", tt.template, tt.topic, ok, tt.want != nil)
+			continue
+		}
+		if ok && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q matching %q = %v, want %v", tt.template, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestParseSubscriptions(t *testing.T) {
+	type subscription struct {
+		template string
+		qos      byte
+	}
+	tests := []struct {
+		spec    string
+		want    []subscription
+		wantErr bool
+	}{
+		{
+			spec: "sensor/{sensor_id}/{kind}",
+			want: []subscription{{"sensor/{sensor_id}/{kind}", 1}},
+		},
+		{
+			spec: "sensor/{sensor_id}/{kind}@2, home/{location}/{sensor_id}/{kind}@0 ,",
+			want: []subscription{{"sensor/{sensor_id}/{kind}", 2}, {"home/{location}/{sensor_id}/{kind}", 0}},
+		},
+		{spec: "", wantErr: true},
+		{spec: " , ", wantErr: true},
+		{spec: "sensor/{sensor_id}/{kind}@3", wantErr: true},
+		{spec: "sensor/{sensor_id}/{kind}@x", wantErr: true},
+		{spec: "sensor/{sensor_id}/{kind}@", wantErr: true},
+		{spec: "sensor/{sensor_id}", wantErr: true},
+		{spec: "sensor/{kind}", wantErr: true},
+		{spec: "sensor/{sensor_id}/{sensor_id}/{kind}", wantErr: true},
+	}
+	for _, tt := range tests {
+		subscriptions, err := ParseSubscriptions(tt.spec, 1)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSubscriptions(%q) error = %v, want error %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, ErrInvalidTemplate) {
+				t.Errorf("ParseSubscriptions(%q) error = %v, want ErrInvalidTemplate", tt.spec, err)
+			}
+			continue
+		}
+
+		got := make([]subscription, len(subscriptions))
+		for i, s := range subscriptions {
+			got[i] = subscription{s.Template.String(), s.QoS}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSubscriptions(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
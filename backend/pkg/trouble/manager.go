@@ -0,0 +1,205 @@
+package trouble
+
+import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/alarm"
+	"backend/pkg/payloads"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Topic is the WebSocket topic trouble events are pushed to
+const Topic = "troubles"
+
+// Event types
+const (
+	EventRaised       = "raised"
+	EventCleared      = "cleared"
+	EventAcknowledged = "acknowledged"
+)
+
+// Arm policies while troubles are active
+const (
+	PolicyForce = "force" // Arming needs force
+	PolicyBlock = "block" // Arming is refused until the troubles are cleared
+)
+
+var ErrAlreadyAcknowledged = errors.New("trouble is already acknowledged")
+
+// Event is broadcast on Topic whenever a trouble changes
+type Event struct {
+	Event   string          `json:"event"`
+	Trouble *models.Trouble `json:"trouble"`
+}
+
+// Listener is called with every trouble event. It runs with the manager
+// locked, so it must not call back into the manager.
+type Listener func(event Event)
+
+// Manager raises and clears troubles from incoming sensor messages and
+// supervision, and decides whether a partition may be armed
+type Manager struct {
+	mu         sync.Mutex
+	hub        alarm.Broadcaster
+	lowBattery float64 // Battery level in percent below which a sensor is in trouble
+	policy     string
+	listener   Listener
+}
+
+// NewManager creates a trouble manager. Batteries below lowBattery percent
+// are a trouble, policy is PolicyForce or PolicyBlock.
+func NewManager(hub alarm.Broadcaster, lowBattery float64, policy string) *Manager {
+	if policy != PolicyBlock {
+		policy = PolicyForce
+	}
+	return &Manager{hub: hub, lowBattery: lowBattery, policy: policy}
+}
+
+// SetListener sets the function called with every trouble event
+func (m *Manager) SetListener(listener Listener) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listener = listener
+}
+
+// HandlePayload raises or clears the troubles a sensor message reports
+func (m *Manager) HandlePayload(sensor *models.Sensor, payload payloads.Payload) {
+	switch p := payload.(type) {
+	case *payloads.Tamper:
+		if *p.Tampered {
+			m.Raise(sensor.SensorID, models.TroubleTamper, "Tamper switch open")
+		} else {
+			m.Clear(sensor.SensorID, models.TroubleTamper)
+		}
+	case *payloads.Battery:
+		if *p.Level < m.lowBattery {
+			m.Raise(sensor.SensorID, models.TroubleLowBattery, fmt.Sprintf("Battery at %.0f%%", *p.Level))
+		} else {
+			m.Clear(sensor.SensorID, models.TroubleLowBattery)
+		}
+	}
+}
+
+// Raise records a trouble unless the sensor already has an active one of the same type
+func (m *Manager) Raise(sensorID, troubleType, message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, err := services.Trouble.GetActive(sensorID, troubleType)
+	if err != nil {
+		log.Printf("Error checking troubles: %v", err)
+		return
+	}
+	if existing != nil {
+		return
+	}
+
+	trouble := &models.Trouble{
+		SensorID: sensorID,
+		Type:     troubleType,
+		Message:  message,
+		RaisedAt: time.Now(),
+	}
+	if err := services.Trouble.Create(trouble); err != nil {
+		log.Printf("Error raising trouble: %v", err)
+		return
+	}
+
+	log.Printf("Trouble raised: %s on sensor %s (%s)", troubleType, sensorID, message)
+	m.broadcast(EventRaised, trouble)
+}
+
+// Clear ends the active trouble of a type on a sensor, if there is one
+func (m *Manager) Clear(sensorID, troubleType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	trouble, err := services.Trouble.GetActive(sensorID, troubleType)
+	if err != nil {
+		log.Printf("Error checking troubles: %v", err)
+		return
+	}
+	if trouble == nil {
+		return
+	}
+
+	now := time.Now()
+	if err := services.Trouble.Clear(trouble, now); err != nil {
+		log.Printf("Error clearing trouble: %v", err)
+		return
+	}
+	trouble.ClearedAt = &now
+
+	log.Printf("Trouble cleared: %s on sensor %s", troubleType, sensorID)
+	m.broadcast(EventCleared, trouble)
+}
+
+// Acknowledge marks a trouble as seen by a user. It stays active until the
+// condition is gone.
+func (m *Manager) Acknowledge(id uint, by string) (*models.Trouble, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	trouble, err := services.Trouble.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if trouble == nil {
+		return nil, services.ErrNotFound
+	}
+	if trouble.AcknowledgedAt != nil {
+		return trouble, ErrAlreadyAcknowledged
+	}
+
+	now := time.Now()
+	if err := services.Trouble.Acknowledge(trouble, by, now); err != nil {
+		return nil, err
+	}
+	trouble.AcknowledgedAt = &now
+	trouble.AcknowledgedBy = by
+
+	m.broadcast(EventAcknowledged, trouble)
+	return trouble, nil
+}
+
+// ArmGuard is the alarm.ArmGuard that keeps partitions with active troubles
+// from being armed, unless forced under PolicyForce
+func (m *Manager) ArmGuard(partitionID uint, isDefault bool, force bool) error {
+	troubles, err := services.Trouble.ListActiveInPartition(partitionID, isDefault)
+	if err != nil {
+		return err
+	}
+	if len(troubles) == 0 || (force && m.policy == PolicyForce) {
+		return nil
+	}
+
+	descriptions := make([]string, 0, len(troubles))
+	for _, t := range troubles {
+		descriptions = append(descriptions, t.SensorID+" "+t.Type)
+	}
+	hint := "arm with force to override"
+	if m.policy == PolicyBlock {
+		hint = "clear them first"
+	}
+	return fmt.Errorf("%w: %s, %s", alarm.ErrArmBlocked, strings.Join(descriptions, ", "), hint)
+}
+
+// broadcast pushes a trouble event to Topic and the listener. Must be called with m.mu held.
+func (m *Manager) broadcast(event string, trouble *models.Trouble) {
+	if m.listener != nil {
+		m.listener(Event{Event: event, Trouble: trouble})
+	}
+
+	message, err := json.Marshal(Event{Event: event, Trouble: trouble})
+	if err != nil {
+		log.Printf("Error marshalling trouble event: %v", err)
+		return
+	}
+	m.hub.BroadcastToTopic(message, Topic)
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -51,6 +52,28 @@ func GetEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+// GetEnvInt gets environment variable as an integer with default
+func GetEnvInt(key string, defaultValue int) int {
+	LoadEnv()
+	if value := os.Getenv(key); value != "" {
+		if intVal, err := strconv.Atoi(value); err == nil {
+			return intVal
+		}
+	}
+	return defaultValue
+}
+
+// GetEnvDuration gets environment variable as a time.Duration (e.g. "30s", "24h") with default
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	LoadEnv()
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
 // IsDebugEnabled checks if debug logging is enabled
 func IsDebugEnabled() bool {
 	return GetEnvBool("DEBUG", false)
@@ -1,6 +1,8 @@
 package websockets
 
 import (
+	"backend/database/models"
+	"backend/pkg/auth"
 	"log"
 
 	"github.com/gorilla/websocket"
@@ -18,15 +20,23 @@ type Client struct {
 
 	// Map of topics to clients subscribed to them
 	subscribedTopics map[string]bool
+
+	// Claims of the user the connection was authenticated as
+	claims *auth.Claims
+
+	// User behind the connection, its role decides the allowed topics
+	user *models.User
 }
 
 // NewClient creates a new client with the given connection
-func (h *WsHub) NewClient(conn *websocket.Conn) *Client {
+func (h *WsHub) NewClient(conn *websocket.Conn, claims *auth.Claims, user *models.User) *Client {
 	return &Client{
-		hub:        h,
-		conn:       conn,
-		send:       make(chan []byte, 256), // Buffered channel for sending messages
+		hub:              h,
+		conn:             conn,
+		send:             make(chan []byte, 256), // Buffered channel for sending messages
 		subscribedTopics: make(map[string]bool),
+		claims:           claims,
+		user:             user,
 	}
 }
 
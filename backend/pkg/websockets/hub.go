@@ -1,8 +1,12 @@
 package websockets
 
 import (
+	"backend/pkg/auth"
 	"encoding/json"
 	"log"
+	"strings"
+	"sync"
+	"time"
 )
 
 type WsHub struct {
@@ -16,6 +20,16 @@ type WsHub struct {
 	broadcast chan []byte
 	// Topic subscriptions
 	topicSubscriptions map[string]map[*Client]bool
+	// Permissions required to subscribe to topics, keyed by topic prefix
+	topicPermissions map[string]auth.Permission
+	// Guards topicSubscriptions and topicPermissions
+	mu sync.RWMutex
+}
+
+// ErrorMessage is sent to a client when one of its requests is refused
+type ErrorMessage struct {
+	Error  string   `json:"error"`
+	Topics []string `json:"topics,omitempty"`
 }
 
 func NewWsHub() *WsHub {
@@ -25,7 +39,31 @@ func NewWsHub() *WsHub {
 		clients:            make(map[*Client]bool),
 		broadcast:          make(chan []byte, 256), // Buffered channel for broadcasting messages
 		topicSubscriptions: make(map[string]map[*Client]bool),
+		topicPermissions:   make(map[string]auth.Permission),
+	}
+}
+
+// RestrictTopic requires a permission to subscribe to every topic starting
+// with prefix. Topics without a matching rule are open to any signed in user.
+func (h *WsHub) RestrictTopic(prefix string, perm auth.Permission) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.topicPermissions[prefix] = perm
+}
+
+// canSubscribe checks the client against the longest matching topic rule
+func (h *WsHub) canSubscribe(client *Client, topic string) bool {
+	var perm auth.Permission
+	longest := -1
+	for prefix, p := range h.topicPermissions {
+		if strings.HasPrefix(topic, prefix) && len(prefix) > longest {
+			perm, longest = p, len(prefix)
+		}
+	}
+	if longest < 0 {
+		return auth.InAccessWindow(client.user, time.Now())
 	}
+	return auth.Authorize(client.user, perm) == nil
 }
 
 func (h *WsHub) BroadcastMessage(message []byte) {
@@ -33,24 +71,48 @@ func (h *WsHub) BroadcastMessage(message []byte) {
 }
 
 func (h *WsHub) BroadcastToTopic(message []byte, topic string) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	now := time.Now()
 	if clients, ok := h.topicSubscriptions[topic]; ok {
 		for client := range clients {
+			// Guests keep their connection after the window ends, but get nothing more
+			if !auth.InAccessWindow(client.user, now) {
+				continue
+			}
 			client.SendMessage(message)
 		}
 	}
 }
 
+// SubscribeClientToTopics subscribes the client to the topics its role may
+// see and reports the refused ones back to it
 func (h *WsHub) SubscribeClientToTopics(client *Client, topics []string) {
+	h.mu.Lock()
+	var denied []string
 	for _, topic := range topics {
+		if !h.canSubscribe(client, topic) {
+			denied = append(denied, topic)
+			continue
+		}
 		if _, exists := h.topicSubscriptions[topic]; !exists {
 			h.topicSubscriptions[topic] = make(map[*Client]bool)
 		}
 		h.topicSubscriptions[topic][client] = true
 		client.subscribedTopics[topic] = true
 	}
+	h.mu.Unlock()
+
+	if len(denied) > 0 {
+		log.Printf("Refused subscription of %s to %v\n", client.user.Username, denied)
+		message, _ := json.Marshal(ErrorMessage{Error: "Not allowed to subscribe", Topics: denied})
+		client.SendMessage(message)
+	}
 }
 
 func (h *WsHub) UnsubscribeClientFromTopics(client *Client, topics []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	for _, topic := range topics {
 		if clients, exists := h.topicSubscriptions[topic]; exists {
 			delete(clients, client)
@@ -85,6 +147,7 @@ func (h *WsHub) Run() {
 			h.clients[client] = true
 		case client := <-h.unregister:
 			if _, ok := h.clients[client]; ok {
+				h.removeSubscriptions(client)
 				delete(h.clients, client)
 				close(client.send) // Close the send channel to stop writing
 			}
@@ -99,3 +162,18 @@ func (h *WsHub) Run() {
 		}
 	}
 }
+
+// removeSubscriptions drops a disconnected client from all of its topics
+func (h *WsHub) removeSubscriptions(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for topic := range client.subscribedTopics {
+		if clients, exists := h.topicSubscriptions[topic]; exists {
+			delete(clients, client)
+			if len(clients) == 0 {
+				delete(h.topicSubscriptions, topic)
+			}
+		}
+		delete(client.subscribedTopics, topic)
+	}
+}
@@ -1,6 +1,9 @@
 package websockets
 
 import (
+	"backend/database/models"
+	"backend/database/services"
+	"backend/pkg/auth"
 	"backend/pkg/utils"
 	"log"
 	"net/http"
@@ -17,7 +20,32 @@ var upgrader = websocket.Upgrader{
 var hub = NewWsHub()
 var mutex = &sync.Mutex{}
 
-func handler(w http.ResponseWriter, r *http.Request) {
+func handler(tokens *auth.TokenManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Check the session token before upgrading, browsers pass it as ?token=
+		claims, err := tokens.Authenticate(r)
+		if err != nil {
+			log.Println("Rejected WebSocket connection:", err)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := services.User.Get(claims.UserID)
+		if err != nil || user == nil {
+			log.Println("Rejected WebSocket connection, unknown user:", err)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !auth.InAccessWindow(user, time.Now()) {
+			http.Error(w, "Access is not allowed at this time", http.StatusForbidden)
+			return
+		}
+
+		serve(w, r, claims, user)
+	}
+}
+
+func serve(w http.ResponseWriter, r *http.Request, claims *auth.Claims, user *models.User) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("Error upgrading connection:", err)
@@ -26,7 +54,7 @@ func handler(w http.ResponseWriter, r *http.Request) {
 	defer conn.Close()
 
 	mutex.Lock()
-	client := hub.NewClient(conn)
+	client := hub.NewClient(conn, claims, user)
 	go client.WriteMessages() // Start the write goroutine
 	hub.register <- client    // Register with hub instead of direct map access
 	defer func() {
@@ -52,13 +80,13 @@ func handler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func StartWebsocketServer() *WsHub {
+func StartWebsocketServer(tokens *auth.TokenManager) *WsHub {
 	port := utils.GetEnv("PORT", "8080")
 
 	go hub.Run() // Start the hub to handle broadcasting messages
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/ws", handler)
+	mux.HandleFunc("/ws", handler(tokens))
 
 	go func() {
 		log.Println("WebSocket server running on port", port)